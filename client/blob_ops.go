@@ -0,0 +1,232 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// blobChunkSize is the size of the chunks the blob content is uploaded in.
+const blobChunkSize = 1 << 20
+
+var (
+	errBlobUploadIncomplete = errors.New("the server has not received the whole content")
+	errBlobChunkCorrupted   = errors.New("blob chunk checksum mismatch")
+)
+
+// UploadBlob uploads the content to the server in chunks and returns the reference to it.
+// If the upload is interrupted, it's resumed from the offset the server has received,
+// so the content must be seekable. The content already uploaded is not sent again.
+func (c *Client) UploadBlob(content io.ReadSeeker) (models.BinaryData, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, content)
+	if err != nil {
+		return models.BinaryData{}, fmt.Errorf("uploadBlob: could not read the content: %w", err)
+	}
+	ref := models.BinaryData{Size: size, Hash: hex.EncodeToString(h.Sum(nil))}
+
+	for i := 0; ; i++ {
+		var offset int64
+		upload, err := c.pbClient.GetBlobUpload(c.ctx, &pb.GetBlobUploadRequest{Sha256: ref.Hash}, c.auth)
+		switch {
+		case err == nil && upload.Complete:
+			return ref, nil
+		case err == nil:
+			offset = upload.Received
+		case status.Code(err) == codes.NotFound:
+			// the upload is not started yet
+		default:
+			if !isRetryableBlobError(err) || i+1 >= c.maxNumberOfRetries {
+				return models.BinaryData{}, blobOpError("uploadBlob", err)
+			}
+			c.blobRetrySleep("uploadBlob", err, i)
+			continue
+		}
+
+		err = c.uploadBlobFrom(ref, content, offset)
+		if err == nil {
+			return ref, nil
+		}
+		if !isRetryableBlobError(err) || i+1 >= c.maxNumberOfRetries {
+			return models.BinaryData{}, blobOpError("uploadBlob", err)
+		}
+		c.blobRetrySleep("uploadBlob", err, i)
+	}
+}
+
+// uploadBlobFrom sends the content to the server starting at the offset provided.
+func (c *Client) uploadBlobFrom(ref models.BinaryData, content io.ReadSeeker, offset int64) error {
+	if _, err := content.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("could not seek the content: %w", err)
+	}
+	stream, err := c.pbClient.UploadBlob(c.ctx, c.auth)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, blobChunkSize)
+	for first := true; first || offset < ref.Size; first = false {
+		n, err := io.ReadFull(content, buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("could not read the content: %w", err)
+		}
+		if n == 0 && !first {
+			break // the content is shorter than it was when hashed, the server rejects it
+		}
+		if err := stream.Send(&pb.BlobChunk{
+			Sha256: ref.Hash,
+			Size:   ref.Size,
+			Offset: offset,
+			Data:   buf[:n],
+			Crc32C: models.BlobChunkChecksum(buf[:n]),
+		}); err != nil {
+			if errors.Is(err, io.EOF) {
+				break // the server has closed the stream, the reason is returned by CloseAndRecv
+			}
+			return err
+		}
+		offset += int64(n)
+	}
+	upload, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	if !upload.Complete {
+		return errBlobUploadIncomplete
+	}
+	return nil
+}
+
+// DownloadBlob downloads the content of the blob item from the server and writes it to w.
+// If the download is interrupted, it's resumed from the last byte written.
+// The hash of the whole content is verified at the end.
+func (c *Client) DownloadBlob(itemID uuid.UUID, w io.Writer) error {
+	entry, err := c.repo.GetItemByID(itemID)
+	if err != nil {
+		return fmt.Errorf("downloadBlob: %w", err)
+	}
+	ref, ok := entry.Item.Payload.(models.BinaryData)
+	if !ok {
+		return fmt.Errorf("downloadBlob: item %s is not a blob", itemID)
+	}
+
+	h := sha256.New()
+	dst := &countingWriter{w: io.MultiWriter(w, h)}
+	for i := 0; ; i++ {
+		err := c.downloadBlobFrom(ref, dst)
+		if err == nil {
+			break
+		}
+		if !isRetryableBlobError(err) || i+1 >= c.maxNumberOfRetries {
+			return blobOpError("downloadBlob", err)
+		}
+		c.blobRetrySleep("downloadBlob", err, i)
+	}
+	if hex.EncodeToString(h.Sum(nil)) != ref.Hash {
+		return fmt.Errorf("downloadBlob: the content doesn't match its hash")
+	}
+
+	return nil
+}
+
+// downloadBlobFrom receives the content from the server starting at the number of bytes already written.
+func (c *Client) downloadBlobFrom(ref models.BinaryData, dst *countingWriter) error {
+	stream, err := c.pbClient.DownloadBlob(c.ctx, &pb.DownloadBlobRequest{
+		Sha256: ref.Hash,
+		Offset: dst.n,
+	}, c.auth)
+	if err != nil {
+		return err
+	}
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if chunk.Offset != dst.n {
+			return fmt.Errorf("unexpected chunk offset %d, %d bytes received", chunk.Offset, dst.n)
+		}
+		if models.BlobChunkChecksum(chunk.Data) != chunk.Crc32C {
+			return errBlobChunkCorrupted
+		}
+		if _, err := dst.Write(chunk.Data); err != nil {
+			return fmt.Errorf("could not write the content: %w", err)
+		}
+	}
+	if dst.n != ref.Size {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// isRetryableBlobError reports whether the interrupted blob transfer may be resumed.
+func isRetryableBlobError(err error) bool {
+	if errors.Is(err, errBlobUploadIncomplete) || errors.Is(err, errBlobChunkCorrupted) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Internal, codes.DeadlineExceeded, codes.Aborted, codes.DataLoss:
+		return true
+	case codes.FailedPrecondition:
+		return ErrorReason(err) == pb.ErrorReason_BLOB_OFFSET_MISMATCH
+	}
+	return false
+}
+
+// blobRetrySleep logs the error of the blob transfer attempt and waits before the next one.
+func (c *Client) blobRetrySleep(op string, err error, attempt int) {
+	timeToWait := time.Millisecond * time.Duration(math.Pow(retrySleepBase, float64(attempt)))
+	log.Printf("client: %s: %s, resuming in %v", op, err, timeToWait)
+	time.Sleep(timeToWait)
+}
+
+// blobOpError logs the error of the blob transfer and converts it into a readable error.
+func blobOpError(op string, err error) error {
+	se, ok := status.FromError(err)
+	if !ok {
+		errMsg := fmt.Sprintf("%s: %s", op, err)
+		log.Println(errMsg)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	var errMsg string
+	switch se.Code() {
+	case codes.Internal:
+		errMsg = fmt.Sprintf("%s: internal server error: %s", op, se.Message())
+	case codes.NotFound:
+		errMsg = fmt.Sprintf("%s: blob content is not found: %s", op, se.Message())
+	case codes.DataLoss:
+		errMsg = fmt.Sprintf("%s: blob content is corrupted: %s", op, se.Message())
+	case codes.Unauthenticated:
+		errMsg = fmt.Sprintf("%s: could not authenticate the user: %s", op, se.Message())
+	default:
+		errMsg = fmt.Sprintf("%s: %s", op, se.Message())
+	}
+	log.Println(errMsg)
+
+	return errors.New(errMsg)
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
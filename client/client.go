@@ -6,13 +6,13 @@ import (
 	"errors"
 	"log"
 	"math"
-	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/vanamelnik/gophkeeper/client/repo"
 	"github.com/vanamelnik/gophkeeper/models"
 	pb "github.com/vanamelnik/gophkeeper/proto"
-	"github.com/vanamelnik/gophkeeper/server/users"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -25,6 +25,8 @@ const (
 
 var (
 	ErrReloginNeeded = errors.New("relogin needed, session stopped")
+
+	errFullSyncNeeded = errors.New("the server can't tell the changes since the local data version")
 )
 
 type (
@@ -41,9 +43,13 @@ type (
 
 		// repo is local items repository
 		repo *repo.Repo
+		// auth attaches the access token to the calls that require authentication.
+		auth grpc.CallOption
 
 		eventCh chan models.Event
 		closeCh chan struct{}
+		// watchCh receives the notices from the Watch stream.
+		watchCh chan watchNotice
 
 		maxNumberOfRetries int
 
@@ -77,8 +83,10 @@ func New(
 		syncInterval:       syncInterval,
 		sendInterval:       sendInterval,
 		repo:               storage,
+		auth:               grpc.PerRPCCredentials(NewTokenCredentials(storage)),
 		eventCh:            make(chan models.Event, 1),
 		closeCh:            make(chan struct{}),
+		watchCh:            make(chan watchNotice),
 		maxNumberOfRetries: maxRetries,
 		conflictResolveFn:  conflictResolveFn,
 		eventsPool:         make([]models.Event, 0),
@@ -93,6 +101,7 @@ func New(
 		return nil, ErrReloginNeeded
 	}
 	go c.worker()
+	go c.watcher(c.closeCh)
 	log.Println("client started")
 	return &c, nil
 }
@@ -114,17 +123,16 @@ func (c *Client) PublishEvent(event models.Event) {
 // If the server presponses "update the data", GetUpdates function invoked.
 func (c *Client) WhatsNew() error {
 	request := &pb.WhatsNewRequest{
-		Token:       &pb.AccessToken{AccessToken: string(c.repo.GetAccessToken())},
 		DataVersion: c.repo.GetDataVersion(),
 	}
 	for i := 0; i < c.maxNumberOfRetries; i++ {
-		_, err := c.pbClient.WhatsNew(c.ctx, request)
+		_, err := c.pbClient.WhatsNew(c.ctx, request, c.auth)
 		if err == nil { // Server responses "all is up to date"
 			return nil
 		}
 
 		st, _ := status.FromError(err)
-		if st.Code() == codes.PermissionDenied { // Server responses "update the data"
+		if ErrorReason(err) == pb.ErrorReason_DATA_VERSION_STALE { // Server responses "update the data"
 			// get updates from the server
 			dataVersion, items, err := c.GetUpdates()
 			if err != nil {
@@ -136,7 +144,7 @@ func (c *Client) WhatsNew() error {
 		}
 
 		if st.Code() == codes.Unauthenticated {
-			if strings.Contains(err.Error(), users.ErrAccessTokenExpired.Error()) {
+			if ErrorReason(err) == pb.ErrorReason_TOKEN_EXPIRED {
 				log.Printf("client: WhatsNew: %s; trying to renew the token pair", err)
 				if err := c.RenewTokens(); err != nil { // try to renew the tokens
 					log.Printf("client: WhatsNew: %s; relogin needed", err)
@@ -158,51 +166,91 @@ func (c *Client) WhatsNew() error {
 	return ErrReloginNeeded
 }
 
-// GetUpdates fetches updates from the server.
+// GetUpdates fetches updates from the server. Only the items changed since the local data version
+// are requested; if the server can't tell them, all the updates are downloaded by the version map.
 // Multiple connection attempts are made. In case of failure, the error "relogin nedded" returns.
-// Function panics if it could not marshall version map.
 func (c *Client) GetUpdates() (uint64, []models.Item, error) {
+	dataVersion, items, err := c.getChangesSince()
+	if errors.Is(err, errFullSyncNeeded) {
+		log.Printf("client: GetUpdates: %s, downloading all the updates", err)
+		return c.downloadUserData()
+	}
+	return dataVersion, items, err
+}
+
+// getChangesSince fetches the items changed since the local data version.
+// If the server's change log doesn't cover it, errFullSyncNeeded returns.
+func (c *Client) getChangesSince() (uint64, []models.Item, error) {
+	request := &pb.GetChangesSinceRequest{
+		DataVersion: c.repo.GetDataVersion(),
+	}
+	for i := 0; i < c.maxNumberOfRetries; i++ {
+		userData, err := c.pbClient.GetChangesSince(c.ctx, request, c.auth)
+		if err == nil {
+			return userData.DataVersion, pbToItems(userData.Items), nil
+		}
+
+		st, _ := status.FromError(err)
+		if ErrorReason(err) == pb.ErrorReason_CHANGE_LOG_COMPACTED || st.Code() == codes.Unimplemented {
+			return 0, nil, errFullSyncNeeded
+		}
+		if st.Code() == codes.Unauthenticated {
+			if ErrorReason(err) == pb.ErrorReason_TOKEN_EXPIRED {
+				log.Printf("client: getChangesSince: %s; trying to renew the token pair", err)
+				if err := c.RenewTokens(); err != nil { // try to renew the tokens
+					log.Printf("client: getChangesSince: %s; relogin needed", err)
+					break // could not renew the tokens - end the session!
+				}
+				log.Println("client: getChangesSince: tokens are refreshed, trying again")
+				continue // tokens are renewed, try again
+			}
+			log.Printf("client: getChangesSince: %s; relogin needed", err)
+			break // unauthenticated and problem isn't with expired access token - end the session!
+		}
+		if st.Code() == codes.Internal {
+			timeToWait := time.Millisecond * time.Duration(math.Pow(retrySleepBase, float64(i)))
+			log.Printf("client: getChangesSince: internal server error, try again in %v", timeToWait)
+			time.Sleep(timeToWait)
+			continue // internal server error - try again
+		}
+	}
+	return 0, nil, ErrReloginNeeded
+}
+
+// downloadUserData fetches all the updates from the server by the version map of the local items.
+// Function panics if it could not marshall version map.
+func (c *Client) downloadUserData() (uint64, []models.Item, error) {
 	versionMap := c.repo.BuildItemVersionMap()
 	versions, err := json.Marshal(versionMap)
 	if err != nil {
-		log.Fatalf("client: GetUpdates: could not marshall the map: %s", err)
+		log.Fatalf("client: downloadUserData: could not marshall the map: %s", err)
 	}
 	request := &pb.DownloadUserDataRequest{
-		Token:      &pb.AccessToken{AccessToken: string(c.repo.GetAccessToken())},
 		VersionMap: string(versions),
 	}
 	for i := 0; i < c.maxNumberOfRetries; i++ {
-		userData, err := c.pbClient.DownloadUserData(c.ctx, request)
+		userData, err := c.pbClient.DownloadUserData(c.ctx, request, c.auth)
 		if err == nil {
-			items := make([]models.Item, 0, len(userData.Items))
-			for _, pbItem := range userData.Items {
-				item, err := models.PbToItem(pbItem)
-				if err != nil {
-					log.Fatal(err)
-				}
-				items = append(items, item)
-			}
-
-			return userData.DataVersion, items, nil
+			return userData.DataVersion, pbToItems(userData.Items), nil
 		}
 
 		st, _ := status.FromError(err)
 		if st.Code() == codes.Unauthenticated {
-			if strings.Contains(err.Error(), users.ErrAccessTokenExpired.Error()) {
-				log.Printf("client: GetUpdates: %s; trying to renew the token pair", err)
+			if ErrorReason(err) == pb.ErrorReason_TOKEN_EXPIRED {
+				log.Printf("client: downloadUserData: %s; trying to renew the token pair", err)
 				if err := c.RenewTokens(); err != nil { // try to renew the tokens
-					log.Printf("client: GetUpdates: %s; relogin needed", err)
+					log.Printf("client: downloadUserData: %s; relogin needed", err)
 					break // could not renew the tokens - end the session!
 				}
-				log.Println("client: GetUpdates: tokens are refreshed, trying again")
+				log.Println("client: downloadUserData: tokens are refreshed, trying again")
 				continue // tokens are renewed, try again
 			}
-			log.Printf("client: GetUpdates: %s; relogin needed", err)
+			log.Printf("client: downloadUserData: %s; relogin needed", err)
 			break // unauthenticated and problem isn't with expired access token - end the session!
 		}
 		if st.Code() == codes.Internal {
 			timeToWait := time.Millisecond * time.Duration(math.Pow(retrySleepBase, float64(i)))
-			log.Printf("client: GetUpdates: internal server error, try again in %v", timeToWait)
+			log.Printf("client: downloadUserData: internal server error, try again in %v", timeToWait)
 			time.Sleep(timeToWait)
 			continue // internal server error - try again
 		}
@@ -210,30 +258,110 @@ func (c *Client) GetUpdates() (uint64, []models.Item, error) {
 	return 0, nil, ErrReloginNeeded
 }
 
+// processEventResults updates the local items according to the outcome of the events sent.
+// If the item is changed on the server since the version sent, the conflict is resolved
+// by the user. Other events that are not applied are dropped, their items stay pending.
+func (c *Client) processEventResults(sentDataVersion uint64, sent []models.Event, resp *pb.PublishLocalChangesResponse) {
+	for i, r := range resp.Results {
+		if i >= len(sent) {
+			break
+		}
+		if r.Applied {
+			c.repo.ConfirmItem(sent[i].Item, r.ItemVersion)
+			c.rebaseQueuedEvents(sent[i].Item.ID, r.ItemVersion)
+			continue
+		}
+		if r.Reason == pb.ErrorReason_ITEM_VERSION_CONFLICT && r.ConflictingItem != nil {
+			serverItem, err := models.PbToItem(r.ConflictingItem)
+			if err == nil {
+				if err := c.repo.MergeItem(serverItem); err != nil {
+					c.processConflictResolving(serverItem, err)
+				}
+				continue
+			}
+		}
+		log.Printf("client: sendEvents: event %s of item %s is not applied: %s",
+			sent[i].Operation, r.ItemId, r.Error)
+	}
+	// if there were no other changes on the server, the local data is up to date
+	if resp.DataVersion == sentDataVersion+1 {
+		c.repo.StoreDataVersion(resp.DataVersion)
+	}
+}
+
+// queueEvent adds the event to the events pool. The pool keeps only the latest event of each item,
+// an item created and then updated before sending is sent as created. An item created and then
+// deleted before sending never reaches the server and is removed from local repository.
+// Contract: must be called from the worker goroutine.
+func (c *Client) queueEvent(event models.Event) {
+	for i, e := range c.eventsPool {
+		if e.Item.ID == event.Item.ID {
+			if e.Operation == models.OpCreate {
+				if event.Operation == models.OpDelete {
+					c.eventsPool = append(c.eventsPool[:i], c.eventsPool[i+1:]...)
+					c.repo.RemoveItem(event.Item.ID)
+					return
+				}
+				event.Operation = models.OpCreate
+			}
+			c.eventsPool[i] = event
+			return
+		}
+	}
+	c.eventsPool = append(c.eventsPool, event)
+}
+
+// rebaseQueuedEvents sets the version of the queued events of the item to the version
+// the server assigned to the previous change of the item.
+func (c *Client) rebaseQueuedEvents(itemID uuid.UUID, version uint64) {
+	for i, e := range c.eventsPool {
+		if e.Item.ID == itemID {
+			c.eventsPool[i].Item.Version = version
+		}
+	}
+}
+
+// pbToItems converts the items received from the server. Function panics if an item is malformed.
+func pbToItems(pbItems []*pb.Item) []models.Item {
+	items := make([]models.Item, 0, len(pbItems))
+	for _, pbItem := range pbItems {
+		item, err := models.PbToItem(pbItem)
+		if err != nil {
+			log.Fatal(err)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
 // sendEvents sends all accumulated events to the server.
 func (c *Client) sendEvents() error {
 	if len(c.eventsPool) == 0 {
 		return nil
 	}
-	events := make([]*pb.Event, 0, len(c.eventsPool))
-	for _, e := range c.eventsPool {
+	sent := c.eventsPool
+	events := make([]*pb.Event, 0, len(sent))
+	for _, e := range sent {
 		events = append(events, &pb.Event{
 			Operation: pb.Event_Operation(pb.Event_Operation_value[string(e.Operation)]),
 			Item:      models.ItemToPb(e.Item),
 		})
 	}
 	for i := 0; i < c.maxNumberOfRetries; i++ {
-		_, err := c.pbClient.PublishLocalChanges(c.ctx, &pb.PublishLocalChangesRequest{
-			Token:       &pb.AccessToken{AccessToken: string(c.repo.GetAccessToken())},
-			DataVersion: c.repo.GetDataVersion(),
+		dataVersion := c.repo.GetDataVersion()
+		resp, err := c.pbClient.PublishLocalChanges(c.ctx, &pb.PublishLocalChangesRequest{
+			DataVersion: dataVersion,
 			Events:      events,
-		})
+		}, c.auth)
 		if err == nil {
+			// the events queued while processing the results are sent next time
+			c.eventsPool = make([]models.Event, 0)
+			c.processEventResults(dataVersion, sent, resp)
 			return nil
 		}
 		st, _ := status.FromError(err)
 		if st.Code() == codes.Unauthenticated {
-			if strings.Contains(err.Error(), users.ErrAccessTokenExpired.Error()) {
+			if ErrorReason(err) == pb.ErrorReason_TOKEN_EXPIRED {
 				log.Printf("client: sendEvents: %s; trying to renew the token pair", err)
 				if err := c.RenewTokens(); err != nil { // try to renew the tokens
 					log.Printf("client: sendEvents: %s; relogin needed", err)
@@ -255,12 +383,15 @@ func (c *Client) sendEvents() error {
 	return ErrReloginNeeded
 }
 
-// worker periodically fetches updates from the server and sends local updates to the server.
+// worker fetches updates from the server when the Watch stream reports a new data version
+// (or periodically if the stream is broken) and sends local updates to the server.
 func (c *Client) worker() {
 	whatsNew := time.NewTicker(c.syncInterval)
 	defer whatsNew.Stop()
 	timeToSend := time.NewTicker(c.sendInterval)
 	defer timeToSend.Stop()
+	// watching is true while the Watch stream is healthy, so there is no need to poll the server.
+	watching := false
 
 clientLoop:
 	for {
@@ -268,20 +399,32 @@ clientLoop:
 		case <-c.closeCh:
 			break clientLoop
 		case <-whatsNew.C:
+			if watching {
+				continue
+			}
+			if err := c.WhatsNew(); err != nil {
+				log.Println("client: user relogin needed, session stopped")
+				c.Close() // Relogin needed
+				continue
+			}
+		case n := <-c.watchCh:
+			watching = n.connected
+			if !watching || n.dataVersion == c.repo.GetDataVersion() {
+				continue
+			}
 			if err := c.WhatsNew(); err != nil {
 				log.Println("client: user relogin needed, session stopped")
 				c.Close() // Relogin needed
 				continue
 			}
 		case event := <-c.eventCh:
-			c.eventsPool = append(c.eventsPool, event)
+			c.queueEvent(event)
 		case <-timeToSend.C:
 			if err := c.sendEvents(); err != nil {
 				log.Println("client: user relogin needed, session stopped")
 				c.Close()
 				continue
 			}
-			c.eventsPool = c.eventsPool[:0] // if all events are successfully sent, clear the events pool
 		}
 	}
 	if c.eventCh != nil {
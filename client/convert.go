@@ -59,7 +59,7 @@ func BlobToItem(b Blob) (models.Item, error) {
 		Version:   0,
 		CreatedAt: &now,
 		DeletedAt: nil,
-		Payload:   models.BinaryData{Binary: b.Data},
+		Payload:   models.BinaryData{Size: b.Size, Hash: b.Hash},
 		Meta:      models.JSONMetadata(meta),
 	}, nil
 
@@ -81,3 +81,49 @@ func TextToItem(t Text) (models.Item, error) {
 		Meta:      models.JSONMetadata(meta),
 	}, nil
 }
+
+// OTPToItem converts local OTP struct to the models.Item object.
+func OTPToItem(o OTP) (models.Item, error) {
+	now := time.Now()
+	meta, err := json.Marshal(o)
+	if err != nil {
+		return models.Item{}, fmt.Errorf("could not encode metadata: %w", err)
+	}
+	return models.Item{
+		ID:        o.ID,
+		Version:   0,
+		CreatedAt: &now,
+		DeletedAt: nil,
+		Payload: models.OTPData{
+			Secret:    o.Secret,
+			Algorithm: o.Algorithm,
+			Digits:    o.Digits,
+			Period:    o.Period,
+			Issuer:    o.Issuer,
+			Account:   o.Account,
+		},
+		Meta: models.JSONMetadata(meta),
+	}, nil
+}
+
+// SSHKeyToItem converts local SSHKey struct to the models.Item object.
+func SSHKeyToItem(k SSHKey) (models.Item, error) {
+	now := time.Now()
+	meta, err := json.Marshal(k)
+	if err != nil {
+		return models.Item{}, fmt.Errorf("could not encode metadata: %w", err)
+	}
+	return models.Item{
+		ID:        k.ID,
+		Version:   0,
+		CreatedAt: &now,
+		DeletedAt: nil,
+		Payload: models.SSHKeyData{
+			PrivateKey: k.PrivateKey,
+			PublicKey:  k.PublicKey,
+			Comment:    k.Comment,
+			Passphrase: k.Passphrase,
+		},
+		Meta: models.JSONMetadata(meta),
+	}, nil
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+
+	"github.com/vanamelnik/gophkeeper/client/repo"
+	"google.golang.org/grpc/credentials"
+)
+
+// TokenCredentials attaches the current access token from the repository to every call
+// as "authorization: Bearer <token>" metadata. It can be passed to grpc.Dial with
+// grpc.WithPerRPCCredentials, the Client attaches it to its own calls by itself.
+type TokenCredentials struct {
+	repo *repo.Repo
+}
+
+var _ credentials.PerRPCCredentials = TokenCredentials{}
+
+// NewTokenCredentials creates the credentials reading the access token from the repository provided.
+func NewTokenCredentials(r *repo.Repo) TokenCredentials {
+	return TokenCredentials{repo: r}
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials interface.
+// If the user is not logged in, no metadata is attached.
+func (t TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token := t.repo.GetAccessToken()
+	if token == "" {
+		return nil, nil
+	}
+	return map[string]string{"authorization": "Bearer " + string(token)}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials interface.
+// The server doesn't support TLS yet, so the token is allowed over an insecure connection.
+func (t TokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vanamelnik/gophkeeper/proto"
+)
+
+// errorDomain is the domain of google.rpc.ErrorInfo details of the server errors.
+const errorDomain = "gophkeeper"
+
+// ErrorReason returns the reason of the server error from google.rpc.ErrorInfo detail of the gRPC status.
+// If the error has no such detail, ERROR_REASON_UNSPECIFIED returns.
+func ErrorReason(err error) pb.ErrorReason {
+	st, ok := status.FromError(err)
+	if !ok {
+		return pb.ErrorReason_ERROR_REASON_UNSPECIFIED
+	}
+	for _, d := range st.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != errorDomain {
+			continue
+		}
+		return pb.ErrorReason(pb.ErrorReason_value[info.Reason])
+	}
+	return pb.ErrorReason_ERROR_REASON_UNSPECIFIED
+}
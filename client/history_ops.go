@@ -0,0 +1,122 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ItemVersions returns the versions of the item kept in the history on the server, the latest first.
+func (c *Client) ItemVersions(itemID uuid.UUID) ([]models.ItemVersion, error) {
+	list, err := c.pbClient.ListItemVersions(c.ctx, &pb.ListItemVersionsRequest{
+		ItemId: itemID.String(),
+	}, c.auth)
+	if err != nil {
+		return nil, historyOpError("itemVersions", err)
+	}
+	versions := make([]models.ItemVersion, 0, len(list.Versions))
+	for _, v := range list.Versions {
+		versions = append(versions, models.ItemVersion{
+			ItemID:      itemID,
+			Version:     v.Version,
+			DataVersion: v.DataVersion,
+			ArchivedAt:  v.ArchivedAt.AsTime(),
+			Deleted:     v.Deleted,
+		})
+	}
+
+	return versions, nil
+}
+
+// GetItemVersion fetches the version of the item from the history on the server.
+func (c *Client) GetItemVersion(itemID uuid.UUID, version uint64) (models.Item, error) {
+	pbItem, err := c.pbClient.GetItemVersion(c.ctx, &pb.GetItemVersionRequest{
+		ItemId:  itemID.String(),
+		Version: version,
+	}, c.auth)
+	if err != nil {
+		return models.Item{}, historyOpError("getItemVersion", err)
+	}
+	item, err := models.PbToItem(pbItem)
+	if err != nil {
+		return models.Item{}, fmt.Errorf("getItemVersion: %w", err)
+	}
+
+	return item, nil
+}
+
+// RestoreItemVersion replaces the payload and the metadata of the item with ones of the old version
+// from the history and queues an event to publish the change as a new update.
+// A deleted item must be restored from the trash first.
+func (c *Client) RestoreItemVersion(itemID uuid.UUID, version uint64) error {
+	old, err := c.GetItemVersion(itemID, version)
+	if err != nil {
+		return err
+	}
+	if old.Payload == nil {
+		return fmt.Errorf("restoreItemVersion: version %d has no payload", version)
+	}
+	old.DeletedAt = nil
+	item, err := c.repo.UpdateItem(old)
+	if err != nil {
+		return fmt.Errorf("could not store the item to local repository: %w", err)
+	}
+	c.PublishEvent(models.Event{
+		Operation: models.OpUpdate,
+		Item:      item,
+	})
+	return nil
+}
+
+// RestoreVault restores all the items on the server to the state they had in the data version provided
+// and fetches the changes made by the restore. The user password is confirmed by SRP-6a proof.
+// It returns the number of the items changed.
+func (c *Client) RestoreVault(email, password string, dataVersion uint64) (int, error) {
+	_, proof, err := proveSRPPassword(c.ctx, c.pbClient, email, password, false)
+	if err != nil {
+		return 0, historyOpError("restoreVault", err)
+	}
+	resp, err := c.pbClient.RestoreVault(c.ctx, &pb.RestoreVaultRequest{
+		Proof:       proof,
+		DataVersion: dataVersion,
+	}, c.auth)
+	if err != nil {
+		return 0, historyOpError("restoreVault", err)
+	}
+	if resp.DataVersion != c.repo.GetDataVersion() {
+		if err := c.WhatsNew(); err != nil {
+			return 0, fmt.Errorf("restoreVault: %w", err)
+		}
+	}
+
+	return int(resp.ChangedItems), nil
+}
+
+// historyOpError logs the error returned by the server and converts it into a readable error.
+func historyOpError(op string, err error) error {
+	se, _ := status.FromError(err)
+	var errMsg string
+	switch se.Code() {
+	case codes.Internal:
+		errMsg = fmt.Sprintf("%s: internal server error: %s", op, se.Message())
+	case codes.NotFound:
+		errMsg = fmt.Sprintf("%s: item version is not found: %s", op, se.Message())
+	case codes.PermissionDenied:
+		errMsg = fmt.Sprintf("%s: wrong password: %s", op, se.Message())
+	case codes.FailedPrecondition:
+		errMsg = fmt.Sprintf("%s: the data version is no longer kept in the history: %s", op, se.Message())
+	case codes.Unauthenticated:
+		errMsg = fmt.Sprintf("%s: could not authenticate the user: %s", op, se.Message())
+	default:
+		errMsg = fmt.Sprintf("%s: %s", op, se.Message())
+	}
+	log.Println(errMsg)
+
+	return errors.New(errMsg)
+}
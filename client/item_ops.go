@@ -2,6 +2,7 @@ package client
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/google/uuid"
 	"github.com/vanamelnik/gophkeeper/models"
@@ -16,9 +17,11 @@ type (
 		Notes    string    `json:"notes,omitempty"`
 	}
 
+	// Blob refers to the content uploaded by UploadBlob, see CreateBlob and DownloadBlob.
 	Blob struct {
 		ID    uuid.UUID `json:"-"`
-		Data  []byte    `json:"-"`
+		Size  int64     `json:"-"`
+		Hash  string    `json:"-"`
 		Notes string    `json:"notes,omitempty"`
 	}
 
@@ -37,6 +40,31 @@ type (
 		CVC            uint32    `json:"-"`
 		Notes          string    `json:"notes,omitempty"`
 	}
+
+	// OTP is the key of the one-time passwords of a third-party service, see ImportOTP and OTPCode.
+	OTP struct {
+		ID        uuid.UUID `json:"-"`
+		Secret    []byte    `json:"-"`
+		Algorithm string    `json:"-"`
+		Digits    uint32    `json:"-"`
+		// Period is the validity period of a code in seconds.
+		Period  uint32 `json:"-"`
+		Issuer  string `json:"-"`
+		Account string `json:"-"`
+		Notes   string `json:"notes,omitempty"`
+	}
+
+	// SSHKey is the SSH key pair served by the vault ssh-agent, see ImportSSHKey and ServeSSHAgent.
+	SSHKey struct {
+		ID uuid.UUID `json:"-"`
+		// PrivateKey is PEM encoded, optionally encrypted with the Passphrase.
+		PrivateKey []byte `json:"-"`
+		// PublicKey is in the authorized_keys format.
+		PublicKey  string `json:"-"`
+		Comment    string `json:"-"`
+		Passphrase string `json:"-"`
+		Notes      string `json:"notes,omitempty"`
+	}
 )
 
 // CreatePassword creates a new Password item, stores it in local repository
@@ -73,9 +101,14 @@ func (c *Client) CreateCard(cc CreditCard) error {
 	return nil
 }
 
-// CreateBlob creates a new Blob item, stores it in local repository
-// and and queues an event to publish it to the server.
-func (c *Client) CreateBlob(b Blob) error {
+// CreateBlob uploads the content to the server, creates a new Blob item referring to it,
+// stores the item in local repository and and queues an event to publish it to the server.
+func (c *Client) CreateBlob(b Blob, content io.ReadSeeker) error {
+	ref, err := c.UploadBlob(content)
+	if err != nil {
+		return err
+	}
+	b.Size, b.Hash = ref.Size, ref.Hash
 	blob, err := BlobToItem(b)
 	if err != nil {
 		return err
@@ -90,6 +123,40 @@ func (c *Client) CreateBlob(b Blob) error {
 	return nil
 }
 
+// CreateOTP creates a new OTP item, stores it in local repository
+// and queues an event to publish it to the server.
+func (c *Client) CreateOTP(o OTP) error {
+	otp, err := OTPToItem(o)
+	if err != nil {
+		return err
+	}
+	if err := c.repo.CreateItem(otp); err != nil {
+		return fmt.Errorf("could not store the item to local repository: %w", err)
+	}
+	c.PublishEvent(models.Event{
+		Operation: models.OpCreate,
+		Item:      otp,
+	})
+	return nil
+}
+
+// CreateSSHKey creates a new SSHKey item, stores it in local repository
+// and queues an event to publish it to the server.
+func (c *Client) CreateSSHKey(k SSHKey) error {
+	sshKey, err := SSHKeyToItem(k)
+	if err != nil {
+		return err
+	}
+	if err := c.repo.CreateItem(sshKey); err != nil {
+		return fmt.Errorf("could not store the item to local repository: %w", err)
+	}
+	c.PublishEvent(models.Event{
+		Operation: models.OpCreate,
+		Item:      sshKey,
+	})
+	return nil
+}
+
 // CreateText creates a new Text item, stores it in local repository
 // and queues an event to publish it to the server.
 func (c *Client) CreateText(t Text) error {
@@ -114,7 +181,8 @@ func (c *Client) UpdatePassword(p Password) error {
 	if err != nil {
 		return err
 	}
-	if err := c.repo.UpdateItem(password); err != nil {
+	password, err = c.repo.UpdateItem(password)
+	if err != nil {
 		return fmt.Errorf("could not store the item to local repository: %w", err)
 	}
 	c.PublishEvent(models.Event{
@@ -131,7 +199,8 @@ func (c *Client) UpdateText(t Text) error {
 	if err != nil {
 		return err
 	}
-	if err := c.repo.UpdateItem(text); err != nil {
+	text, err = c.repo.UpdateItem(text)
+	if err != nil {
 		return fmt.Errorf("could not store the item to local repository: %w", err)
 	}
 	c.PublishEvent(models.Event{
@@ -141,14 +210,23 @@ func (c *Client) UpdateText(t Text) error {
 	return nil
 }
 
-// UpdateBlob updates a binary item in the local repository
-// and queues an event to publish the changes.
-func (c *Client) UpdateBlob(b Blob) error {
+// UpdateBlob updates a binary item in the local repository and queues an event to publish the changes.
+// If the content is not nil, it's uploaded to the server first, otherwise b must refer to
+// the content already uploaded.
+func (c *Client) UpdateBlob(b Blob, content io.ReadSeeker) error {
+	if content != nil {
+		ref, err := c.UploadBlob(content)
+		if err != nil {
+			return err
+		}
+		b.Size, b.Hash = ref.Size, ref.Hash
+	}
 	blob, err := BlobToItem(b)
 	if err != nil {
 		return err
 	}
-	if err := c.repo.UpdateItem(blob); err != nil {
+	blob, err = c.repo.UpdateItem(blob)
+	if err != nil {
 		return fmt.Errorf("could not store the item to local repository: %w", err)
 	}
 	c.PublishEvent(models.Event{
@@ -165,7 +243,8 @@ func (c *Client) UpdateCard(card CreditCard) error {
 	if err != nil {
 		return err
 	}
-	if err := c.repo.UpdateItem(creditCard); err != nil {
+	creditCard, err = c.repo.UpdateItem(creditCard)
+	if err != nil {
 		return fmt.Errorf("could not store the item to local repository: %w", err)
 	}
 	c.PublishEvent(models.Event{
@@ -174,3 +253,53 @@ func (c *Client) UpdateCard(card CreditCard) error {
 	})
 	return nil
 }
+
+// UpdateOTP updates an OTP item in the local repository
+// and queues an event to publish the changes.
+func (c *Client) UpdateOTP(o OTP) error {
+	otp, err := OTPToItem(o)
+	if err != nil {
+		return err
+	}
+	otp, err = c.repo.UpdateItem(otp)
+	if err != nil {
+		return fmt.Errorf("could not store the item to local repository: %w", err)
+	}
+	c.PublishEvent(models.Event{
+		Operation: models.OpUpdate,
+		Item:      otp,
+	})
+	return nil
+}
+
+// UpdateSSHKey updates an SSHKey item in the local repository
+// and queues an event to publish the changes.
+func (c *Client) UpdateSSHKey(k SSHKey) error {
+	sshKey, err := SSHKeyToItem(k)
+	if err != nil {
+		return err
+	}
+	sshKey, err = c.repo.UpdateItem(sshKey)
+	if err != nil {
+		return fmt.Errorf("could not store the item to local repository: %w", err)
+	}
+	c.PublishEvent(models.Event{
+		Operation: models.OpUpdate,
+		Item:      sshKey,
+	})
+	return nil
+}
+
+// DeleteItem turns the item in the local repository into a tombstone
+// and queues an event to publish the deletion.
+func (c *Client) DeleteItem(itemID uuid.UUID) error {
+	tombstone, err := c.repo.DeleteItem(itemID)
+	if err != nil {
+		return fmt.Errorf("could not delete the item from local repository: %w", err)
+	}
+	c.PublishEvent(models.Event{
+		Operation: models.OpDelete,
+		Item:      tombstone,
+	})
+	return nil
+}
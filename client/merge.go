@@ -17,6 +17,8 @@ func (c *Client) MergeItems(dataVersion uint64, items []models.Item) {
 	c.repo.StoreDataVersion(dataVersion)
 }
 
+// processConflictResolving asks the user to resolve the merge conflict. If the user prefers the local item,
+// it's queued to be sent again. Contract: must be called from the worker goroutine.
 func (c *Client) processConflictResolving(receivedItem models.Item, err error) {
 	if err == nil {
 		return
@@ -32,7 +34,7 @@ func (c *Client) processConflictResolving(receivedItem models.Item, err error) {
 		mergedItem := ce.LocalEntry.Item
 		mergedItem.Version = receivedItem.Version
 		c.repo.ForceMergeItem(mergedItem)
-		c.PublishEvent(models.Event{ // send this item again
+		c.queueEvent(models.Event{ // send this item again
 			Operation: models.OpUpdate,
 			Item:      mergedItem,
 		})
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/vanamelnik/gophkeeper/models"
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrMFARequired is returned by LogIn and UndeleteUser if the user has two-factor authentication enabled.
+// The login must be completed by LogInMFA with the challenge provided.
+type ErrMFARequired struct {
+	Challenge models.MFAChallenge
+}
+
+func (err ErrMFARequired) Error() string {
+	return "two-factor authentication required"
+}
+
+// LogInMFA completes the login of the user with two-factor authentication enabled.
+// The code may be either the TOTP code from the authenticator application or one of the recovery codes.
+func LogInMFA(ctx context.Context, pbClient pb.GophkeeperClient, challenge models.MFAChallenge, code string) (models.AccessToken, models.RefreshToken, error) {
+	userAuth, err := pbClient.LogInMFA(ctx, &pb.LogInMFARequest{
+		MfaChallenge: string(challenge),
+		Code:         code,
+		ClientInfo:   &pb.ClientInfo{Name: ClientName, Version: ClientVersion},
+	})
+	if err == nil {
+		return models.AccessToken(userAuth.AccessToken.AccessToken), models.RefreshToken(userAuth.RefreshToken.RefreshToken), nil
+	}
+
+	return "", "", mfaOpError("logInMFA", err)
+}
+
+// EnrollTOTP starts the enrollment of the TOTP second factor. It returns otpauth:// URI
+// that should be imported into the authenticator application and the recovery codes
+// that should be kept by the user in a safe place.
+// Two-factor authentication is enabled after the enrollment is confirmed by ConfirmTOTP.
+// The user's password is confirmed by SRP-6a proof.
+func (c *Client) EnrollTOTP(email, password string) (string, []string, error) {
+	_, proof, err := proveSRPPassword(c.ctx, c.pbClient, email, password, false)
+	if err != nil {
+		return "", nil, mfaOpError("enrollTOTP", err)
+	}
+	enrollment, err := c.pbClient.EnrollTOTP(c.ctx, &pb.EnrollTOTPRequest{Proof: proof}, c.auth)
+	if err != nil {
+		return "", nil, mfaOpError("enrollTOTP", err)
+	}
+	return enrollment.OtpauthUri, enrollment.RecoveryCodes, nil
+}
+
+// ConfirmTOTP enables two-factor authentication with the code from the authenticator application.
+func (c *Client) ConfirmTOTP(code string) error {
+	_, err := c.pbClient.ConfirmTOTP(c.ctx, &pb.ConfirmTOTPRequest{
+		Code: code,
+	}, c.auth)
+	if err != nil {
+		return mfaOpError("confirmTOTP", err)
+	}
+	return nil
+}
+
+// DisableTOTP disables two-factor authentication. The user's password is confirmed by SRP-6a proof.
+func (c *Client) DisableTOTP(email, password, code string) error {
+	_, proof, err := proveSRPPassword(c.ctx, c.pbClient, email, password, false)
+	if err == nil {
+		_, err = c.pbClient.DisableTOTP(c.ctx, &pb.DisableTOTPRequest{
+			Proof: proof,
+			Code:  code,
+		}, c.auth)
+	}
+	if err != nil {
+		return mfaOpError("disableTOTP", err)
+	}
+	return nil
+}
+
+// mfaOpError logs the error returned by the server and converts it into a readable error.
+func mfaOpError(op string, err error) error {
+	se, _ := status.FromError(err)
+	var errMsg string
+	switch se.Code() {
+	case codes.Internal:
+		errMsg = fmt.Sprintf("%s: internal server error: %s", op, se.Message())
+	case codes.Unauthenticated:
+		errMsg = fmt.Sprintf("%s: one-time code is not accepted: %s", op, se.Message())
+	case codes.PermissionDenied:
+		errMsg = fmt.Sprintf("%s: password is incorrect: %s", op, se.Message())
+	case codes.ResourceExhausted:
+		errMsg = fmt.Sprintf("%s: too many attempts, try again in %v", op, retryDelay(se))
+	default:
+		errMsg = fmt.Sprintf("%s: %s", op, se.Message())
+	}
+	log.Println(errMsg)
+
+	return errors.New(errMsg)
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/pkg/totp"
+)
+
+// ImportOTP creates a new OTP item with the key from otpauth:// URI, e.g. scanned from the QR code
+// shown by the service. The ID and the notes are taken from o, the key fields of o are ignored.
+func (c *Client) ImportOTP(o OTP, uri string) error {
+	key, err := totp.ParseURI(uri)
+	if err != nil {
+		return fmt.Errorf("importOTP: %w", err)
+	}
+	o.Secret = key.Secret
+	o.Algorithm = string(key.Algorithm)
+	o.Digits = uint32(key.Digits)
+	o.Period = uint32(key.Period / time.Second)
+	o.Issuer = key.Issuer
+	o.Account = key.Account
+
+	return c.CreateOTP(o)
+}
+
+// OTPCode generates the current code of the OTP item on the device. It returns the code
+// and the time during which the code remains valid. The deleted items and the invalid keys
+// are rejected with an error.
+func (c *Client) OTPCode(itemID uuid.UUID) (string, time.Duration, error) {
+	entry, err := c.repo.GetItemByID(itemID)
+	if err != nil {
+		return "", 0, fmt.Errorf("otpCode: %w", err)
+	}
+	if entry.Item.IsTombstone() {
+		return "", 0, fmt.Errorf("otpCode: item %s is deleted", itemID)
+	}
+	data, ok := entry.Item.Payload.(models.OTPData)
+	if !ok {
+		return "", 0, fmt.Errorf("otpCode: item %s is not an OTP key", itemID)
+	}
+	// the item may be received from another client, so the key is checked before use
+	if err := models.IsValidItem(entry.Item); err != nil {
+		return "", 0, fmt.Errorf("otpCode: item %s has an invalid OTP key: %w", itemID, err)
+	}
+	key := totp.Key{
+		Secret:    data.Secret,
+		Algorithm: totp.Algorithm(data.Algorithm),
+		Digits:    int(data.Digits),
+		Period:    time.Duration(data.Period) * time.Second,
+	}
+	now := time.Now()
+	code, err := key.Code(now)
+	if err != nil {
+		return "", 0, fmt.Errorf("otpCode: %w", err)
+	}
+
+	return code, key.Remaining(now), nil
+}
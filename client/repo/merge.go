@@ -1,6 +1,10 @@
 package repo
 
-import "github.com/vanamelnik/gophkeeper/models"
+import (
+	"reflect"
+
+	"github.com/vanamelnik/gophkeeper/models"
+)
 
 type ErrMergeConflict struct {
 	LocalEntry Entry
@@ -16,6 +20,14 @@ func (r *Repo) MergeItem(receivedItem models.Item) error {
 	defer r.Unlock()
 	for i, entry := range r.entries {
 		if entry.Item.ID == receivedItem.ID {
+			if receivedItem.Version == entry.Item.Version {
+				// the local item is based on the received version: it's either the same
+				// or changed locally and not sent yet
+				if entry.Pending && compareItemsData(receivedItem, entry.Item) {
+					r.entries[i].Pending = false
+				}
+				return nil
+			}
 			// merge condition boolean variables
 			remoteVersionIsNewer := receivedItem.Version != entry.Item.Version // just in case we use '!=' instead of '>'
 			confirmedPendingItem := entry.Pending && compareItemsData(receivedItem, entry.Item)
@@ -64,9 +76,59 @@ func (r *Repo) ForceMergeItem(item models.Item) {
 	})
 }
 
+// ConfirmItem stores the version assigned by the server to the item sent. If the local item
+// hasn't been changed since it was sent, 'Pending' flag is unset.
+func (r *Repo) ConfirmItem(sentItem models.Item, version uint64) {
+	r.Lock()
+	defer r.Unlock()
+	for i, entry := range r.entries {
+		if entry.Item.ID == sentItem.ID {
+			r.entries[i].Item.Version = version
+			if entry.Pending && compareItemsData(entry.Item, sentItem) {
+				r.entries[i].Pending = false
+			}
+			r.isChanged = true
+			return
+		}
+	}
+}
+
+// RestoreItem replaces the local tombstone with the item restored from the trash on the server.
+// If there is no local entry with such ID, the item is added. 'Pending' flag is unset.
+// If the local item is not deleted, ErrAlreadyExists returns.
+func (r *Repo) RestoreItem(item models.Item) error {
+	if err := models.IsValidItem(item); err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	for i, entry := range r.entries {
+		if entry.Item.ID == item.ID {
+			if !entry.Item.IsTombstone() {
+				return ErrAlreadyExists
+			}
+			r.entries[i] = Entry{
+				Item:    item,
+				Pending: false,
+			}
+			r.isChanged = true
+			return nil
+		}
+	}
+	r.entries = append(r.entries, Entry{
+		Item:    item,
+		Pending: false,
+	})
+	r.isChanged = true
+	return nil
+}
+
 // compareItemsData return true if the payload, DeleteAt fields and the Meta fields are the same for both items.
+// Two tombstones are considered the same regardless of the deletion time.
 func compareItemsData(item1, item2 models.Item) bool {
-	return item1.Payload == item2.Payload &&
-		item1.Meta == item2.Meta &&
-		item1.DeletedAt == item2.DeletedAt
+	if item1.IsTombstone() || item2.IsTombstone() {
+		return item1.IsTombstone() && item2.IsTombstone()
+	}
+	return reflect.DeepEqual(item1.Payload, item2.Payload) &&
+		item1.Meta == item2.Meta
 }
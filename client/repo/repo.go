@@ -63,7 +63,7 @@ func (r *Repo) CreateItem(item models.Item) error {
 	}
 	r.Lock()
 	defer r.Unlock()
-	if _, err := r.GetItemByID(item.ID); err == nil {
+	if _, ok := r.findEntry(item.ID); ok {
 		return ErrAlreadyExists
 	}
 	r.entries = append(r.entries, Entry{
@@ -76,29 +76,34 @@ func (r *Repo) CreateItem(item models.Item) error {
 }
 
 // UpdateItem updates the item in local repository and marks it as 'pending'.
-func (r *Repo) UpdateItem(item models.Item) error {
+// The version of the stored item is kept, since the update is based on it.
+// The item as it's stored is returned.
+func (r *Repo) UpdateItem(item models.Item) (models.Item, error) {
 	if err := models.IsValidItem(item); err != nil {
-		return err
+		return models.Item{}, err
 	}
 	r.Lock()
 	defer r.Unlock()
 	for i, storedItem := range r.entries {
 		if storedItem.Item.ID == item.ID && storedItem.Item.DeletedAt == nil { // Undelete is not possible by this method.
+			item.Version = storedItem.Item.Version
+			item.CreatedAt = storedItem.Item.CreatedAt
 			r.entries[i] = Entry{
 				Item:    item,
 				Pending: true,
 			}
 
 			r.isChanged = true
-			return nil
+			return item, nil
 		}
 	}
 
-	return ErrNotFound
+	return models.Item{}, ErrNotFound
 }
 
-// DeleteItem marks the item in local repository as 'deleted' and 'pending'
-func (r *Repo) DeleteItem(itemID uuid.UUID) error {
+// DeleteItem turns the item in local repository into a tombstone marked as 'deleted' and 'pending'.
+// The tombstone is returned.
+func (r *Repo) DeleteItem(itemID uuid.UUID) (models.Item, error) {
 	r.Lock()
 	defer r.Unlock()
 	for i, storedItem := range r.entries {
@@ -107,6 +112,7 @@ func (r *Repo) DeleteItem(itemID uuid.UUID) error {
 			r.entries[i] = Entry{
 				Item: models.Item{
 					ID:        itemID,
+					Version:   storedItem.Item.Version,
 					CreatedAt: storedItem.Item.CreatedAt,
 					DeletedAt: &now,
 					Payload:   nil, // We erase all user data in deleted items,
@@ -115,23 +121,47 @@ func (r *Repo) DeleteItem(itemID uuid.UUID) error {
 				Pending: true,
 			}
 			r.isChanged = true
-			return nil
+			return r.entries[i].Item, nil
 		}
 	}
 
-	return nil
+	return models.Item{}, ErrNotFound
+}
+
+// RemoveItem removes the entry from local repository completely.
+// It's used for the items that were deleted before reaching the server.
+func (r *Repo) RemoveItem(itemID uuid.UUID) {
+	r.Lock()
+	defer r.Unlock()
+	for i, entry := range r.entries {
+		if entry.Item.ID == itemID {
+			r.entries = append(r.entries[:i], r.entries[i+1:]...)
+			r.isChanged = true
+			return
+		}
+	}
 }
 
 // GetItemByID fetches the non deleted item with given ID from local repository.
 func (r *Repo) GetItemByID(itemID uuid.UUID) (Entry, error) {
 	r.RLock()
 	defer r.RUnlock()
-	for _, item := range r.entries {
-		if item.Item.ID == itemID && item.Item.DeletedAt != nil {
-			return item, nil
+	entry, ok := r.findEntry(itemID)
+	if !ok || entry.Item.DeletedAt != nil {
+		return Entry{}, ErrNotFound
+	}
+	return entry, nil
+}
+
+// findEntry returns the entry with given ID including the tombstones.
+// Contract: repo must be locked.
+func (r *Repo) findEntry(itemID uuid.UUID) (Entry, bool) {
+	for _, entry := range r.entries {
+		if entry.Item.ID == itemID {
+			return entry, true
 		}
 	}
-	return Entry{}, ErrNotFound
+	return Entry{}, false
 }
 
 // GetDataSnapshot retrieves the snapshot of all user data in local repository.
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SessionInfo describes an active session of the user.
+type SessionInfo struct {
+	ID            uuid.UUID
+	LoginAt       time.Time
+	ClientName    string
+	ClientVersion string
+	RemoteAddr    string
+	// Current is set for the session of this client.
+	Current bool
+}
+
+// ListSessions returns all active sessions of the user.
+func (c *Client) ListSessions() ([]SessionInfo, error) {
+	list, err := c.pbClient.ListSessions(c.ctx, &pb.ListSessionsRequest{}, c.auth)
+	if err != nil {
+		return nil, sessionOpError("listSessions", err)
+	}
+	sessions := make([]SessionInfo, 0, len(list.Sessions))
+	for _, s := range list.Sessions {
+		id, err := uuid.Parse(s.SessionId)
+		if err != nil {
+			return nil, fmt.Errorf("listSessions: %w", err)
+		}
+		sessions = append(sessions, SessionInfo{
+			ID:            id,
+			LoginAt:       s.LoginAt.AsTime(),
+			ClientName:    s.ClientName,
+			ClientVersion: s.ClientVersion,
+			RemoteAddr:    s.RemoteAddr,
+			Current:       s.Current,
+		})
+	}
+
+	return sessions, nil
+}
+
+// LogoutSession ends the session of the user with ID provided.
+func (c *Client) LogoutSession(sessionID uuid.UUID) error {
+	_, err := c.pbClient.LogoutSession(c.ctx, &pb.LogoutSessionRequest{
+		SessionId: sessionID.String(),
+	}, c.auth)
+	if err != nil {
+		return sessionOpError("logoutSession", err)
+	}
+	return nil
+}
+
+// LogoutAllSessions ends all sessions of the user including the current one.
+// The client is stopped and the auth tokens are erased.
+func (c *Client) LogoutAllSessions() error {
+	_, err := c.pbClient.LogoutAllSessions(c.ctx, &pb.LogoutAllSessionsRequest{}, c.auth)
+	if err != nil {
+		return sessionOpError("logoutAllSessions", err)
+	}
+	c.repo.StoreAccessToken("")
+	c.repo.StoreRefreshToken("")
+	c.Close()
+	return nil
+}
+
+// sessionOpError logs the error returned by the server and converts it into a readable error.
+func sessionOpError(op string, err error) error {
+	se, _ := status.FromError(err)
+	var errMsg string
+	switch se.Code() {
+	case codes.Internal:
+		errMsg = fmt.Sprintf("%s: internal server error: %s", op, se.Message())
+	case codes.NotFound:
+		errMsg = fmt.Sprintf("%s: session is not found: %s", op, se.Message())
+	case codes.Unauthenticated:
+		errMsg = fmt.Sprintf("%s: could not authenticate the user: %s", op, se.Message())
+	default:
+		errMsg = fmt.Sprintf("%s: %s", op, se.Message())
+	}
+	log.Println(errMsg)
+
+	return errors.New(errMsg)
+}
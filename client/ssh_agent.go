@@ -0,0 +1,245 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var (
+	// errSSHAgentReadOnly is returned for the requests to change the keys of the vault ssh-agent.
+	errSSHAgentReadOnly = errors.New("the keys are managed in the vault")
+	// errSSHSignDenied is returned when the user doesn't confirm the signature.
+	errSSHSignDenied = errors.New("the signature is denied by the user")
+)
+
+type (
+	// SSHSignRequest describes the signature requested from the vault ssh-agent.
+	SSHSignRequest struct {
+		ItemID  uuid.UUID
+		Comment string
+		// Fingerprint is the SHA256 fingerprint of the public key as printed by ssh-keygen -l.
+		Fingerprint string
+	}
+
+	// SSHConfirmFunc asks the user whether the key may sign the request. It's called before each signature.
+	SSHConfirmFunc func(req SSHSignRequest) bool
+
+	// vaultAgent is the ssh-agent that serves the SSH keys of the local repository.
+	// The keys can't be added or removed via the agent protocol.
+	vaultAgent struct {
+		c       *Client
+		confirm SSHConfirmFunc
+	}
+
+	// vaultKey is the SSH key of the local repository.
+	vaultKey struct {
+		itemID  uuid.UUID
+		comment string
+		signer  ssh.Signer
+	}
+)
+
+// ImportSSHKey creates a new SSHKey item with the PEM encoded private key. The passphrase
+// of the encrypted key is stored with it, so the agent can use the key without asking.
+// The public key is derived from the private one. The ID, the comment and the notes are taken from k.
+func (c *Client) ImportSSHKey(k SSHKey, privateKey []byte, passphrase string) error {
+	signer, err := parseSSHKey(privateKey, passphrase)
+	if err != nil {
+		return fmt.Errorf("importSSHKey: %w", err)
+	}
+	k.PrivateKey = privateKey
+	k.Passphrase = passphrase
+	k.PublicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+
+	return c.CreateSSHKey(k)
+}
+
+// ServeSSHAgent serves the SSH keys of the vault to the SSH clients via the ssh-agent protocol
+// on the unix socket, so the private keys are never written to the disk. Point SSH_AUTH_SOCK
+// to the socket path to use it. The directory of the socket is created if it doesn't exist;
+// it must be accessible only by the owner. The user is asked by confirm before each signature.
+// It blocks until the context is canceled.
+func (c *Client) ServeSSHAgent(ctx context.Context, socketPath string, confirm SSHConfirmFunc) error {
+	if confirm == nil {
+		return errors.New("serveSSHAgent: the confirmation callback is required")
+	}
+	// the socket is created with the permissions of the umask, so nobody else must be able
+	// to reach it through the directory until it's restricted
+	if err := prepareSocketDir(filepath.Dir(socketPath)); err != nil {
+		return fmt.Errorf("serveSSHAgent: %w", err)
+	}
+	// the socket left by the previous run prevents listening
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("serveSSHAgent: %w", err)
+	}
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("serveSSHAgent: %w", err)
+	}
+	defer os.Remove(socketPath)
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		l.Close()
+		return fmt.Errorf("serveSSHAgent: %w", err)
+	}
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	a := &vaultAgent{c: c, confirm: confirm}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Println("client: ssh-agent is stopped")
+				return nil
+			}
+			return fmt.Errorf("serveSSHAgent: %w", err)
+		}
+		go func() {
+			defer conn.Close()
+			if err := agent.ServeAgent(a, conn); err != nil && !errors.Is(err, io.EOF) {
+				log.Printf("client: ssh-agent: %s", err)
+			}
+		}()
+	}
+}
+
+// List implements agent.Agent interface.
+func (a *vaultAgent) List() ([]*agent.Key, error) {
+	keys := a.keys()
+	list := make([]*agent.Key, 0, len(keys))
+	for _, k := range keys {
+		public := k.signer.PublicKey()
+		list = append(list, &agent.Key{
+			Format:  public.Type(),
+			Blob:    public.Marshal(),
+			Comment: k.comment,
+		})
+	}
+	return list, nil
+}
+
+// Sign implements agent.Agent interface.
+func (a *vaultAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags implements agent.ExtendedAgent interface. The RSA keys sign with SHA-2
+// if it's requested by the flags.
+func (a *vaultAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	for _, k := range a.keys() {
+		public := k.signer.PublicKey()
+		if !bytes.Equal(public.Marshal(), key.Marshal()) {
+			continue
+		}
+		if !a.confirm(SSHSignRequest{
+			ItemID:      k.itemID,
+			Comment:     k.comment,
+			Fingerprint: ssh.FingerprintSHA256(public),
+		}) {
+			return nil, errSSHSignDenied
+		}
+		algSigner, ok := k.signer.(ssh.AlgorithmSigner)
+		if !ok || key.Type() != ssh.KeyAlgoRSA {
+			return k.signer.Sign(rand.Reader, data)
+		}
+		switch {
+		case flags&agent.SignatureFlagRsaSha256 != 0:
+			return algSigner.SignWithAlgorithm(rand.Reader, data, ssh.KeyAlgoRSASHA256)
+		case flags&agent.SignatureFlagRsaSha512 != 0:
+			return algSigner.SignWithAlgorithm(rand.Reader, data, ssh.KeyAlgoRSASHA512)
+		}
+		return k.signer.Sign(rand.Reader, data)
+	}
+	return nil, errors.New("the key is not found in the vault")
+}
+
+// Extension implements agent.ExtendedAgent interface. No extensions are supported.
+func (a *vaultAgent) Extension(string, []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
+
+// Signers implements agent.Agent interface. The signers aren't given out,
+// since they would sign without the confirmation.
+func (a *vaultAgent) Signers() ([]ssh.Signer, error) {
+	return nil, errSSHAgentReadOnly
+}
+
+// Add implements agent.Agent interface.
+func (a *vaultAgent) Add(agent.AddedKey) error { return errSSHAgentReadOnly }
+
+// Remove implements agent.Agent interface.
+func (a *vaultAgent) Remove(ssh.PublicKey) error { return errSSHAgentReadOnly }
+
+// RemoveAll implements agent.Agent interface.
+func (a *vaultAgent) RemoveAll() error { return errSSHAgentReadOnly }
+
+// Lock implements agent.Agent interface.
+func (a *vaultAgent) Lock([]byte) error { return errSSHAgentReadOnly }
+
+// Unlock implements agent.Agent interface.
+func (a *vaultAgent) Unlock([]byte) error { return errSSHAgentReadOnly }
+
+// keys returns the SSH keys of the local repository. The public keys are derived from the private ones,
+// since the items may be received from another client and the stored public key may not match.
+// The keys that can't be decoded are skipped.
+func (a *vaultAgent) keys() []vaultKey {
+	keys := make([]vaultKey, 0)
+	for _, entry := range a.c.repo.GetDataSnapshot() {
+		data, ok := entry.Item.Payload.(models.SSHKeyData)
+		if !ok || entry.Item.IsTombstone() {
+			continue
+		}
+		signer, err := parseSSHKey(data.PrivateKey, data.Passphrase)
+		if err != nil {
+			log.Printf("client: ssh-agent: item %s: invalid private key: %s", entry.Item.ID, err)
+			continue
+		}
+		stored, _, _, _, err := ssh.ParseAuthorizedKey([]byte(data.PublicKey))
+		if err != nil || !bytes.Equal(stored.Marshal(), signer.PublicKey().Marshal()) {
+			log.Printf("client: ssh-agent: item %s: the public key doesn't match the private key", entry.Item.ID)
+		}
+		keys = append(keys, vaultKey{itemID: entry.Item.ID, comment: data.Comment, signer: signer})
+	}
+	return keys
+}
+
+// prepareSocketDir creates the directory of the agent socket accessible only by the owner.
+// The existing directory is accepted only if it's not accessible by others.
+func prepareSocketDir(dir string) error {
+	err := os.Mkdir(dir, 0700)
+	if err == nil || !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() || info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("the socket directory %s must be accessible only by the owner", dir)
+	}
+	return nil
+}
+
+// parseSSHKey decodes the PEM encoded private key, decrypting it with the passphrase if it's not empty.
+func parseSSHKey(privateKey []byte, passphrase string) (ssh.Signer, error) {
+	if passphrase == "" {
+		return ssh.ParsePrivateKey(privateKey)
+	}
+	return ssh.ParsePrivateKeyWithPassphrase(privateKey, []byte(passphrase))
+}
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanamelnik/gophkeeper/client/repo"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// newEd25519Key returns the PEM encoded private key and its public key in the authorized_keys format.
+func newEd25519Key(t *testing.T) ([]byte, string) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+		strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+}
+
+// addSSHKey stores the SSH key item in the local repository.
+func addSSHKey(t *testing.T, c *Client, k SSHKey) {
+	item, err := SSHKeyToItem(k)
+	require.NoError(t, err)
+	require.NoError(t, c.repo.CreateItem(item))
+}
+
+// serveTestAgent serves the vault agent of the client over an in-memory pipe.
+func serveTestAgent(t *testing.T, c *Client, confirm SSHConfirmFunc) agent.ExtendedAgent {
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	// nolint: errcheck
+	go agent.ServeAgent(&vaultAgent{c: c, confirm: confirm}, server)
+	return agent.NewClient(client)
+}
+
+func TestSSHAgent(t *testing.T) {
+	c := &Client{repo: repo.New()}
+
+	edKey, edPublic := newEd25519Key(t)
+	edID := uuid.New()
+	addSSHKey(t, c, SSHKey{ID: edID, PrivateKey: edKey, PublicKey: edPublic, Comment: "ed25519"})
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	// nolint: staticcheck
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey),
+		[]byte("secret"), x509.PEMCipherAES256)
+	require.NoError(t, err)
+	rsaSigner, err := ssh.NewSignerFromKey(rsaKey)
+	require.NoError(t, err)
+	addSSHKey(t, c, SSHKey{
+		ID:         uuid.New(),
+		PrivateKey: pem.EncodeToMemory(block),
+		PublicKey:  string(ssh.MarshalAuthorizedKey(rsaSigner.PublicKey())),
+		Comment:    "rsa",
+		Passphrase: "secret",
+	})
+
+	allow := true
+	var requests []SSHSignRequest
+	a := serveTestAgent(t, c, func(req SSHSignRequest) bool {
+		requests = append(requests, req)
+		return allow
+	})
+
+	keys, err := a.List()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	data := []byte("session data")
+	for _, key := range keys {
+		sig, err := a.SignWithFlags(key, data, agent.SignatureFlagRsaSha256)
+		require.NoError(t, err, key.Comment)
+		assert.NoError(t, key.Verify(data, sig), key.Comment)
+		if key.Comment == "rsa" {
+			assert.Equal(t, ssh.KeyAlgoRSASHA256, sig.Format)
+		}
+	}
+	require.Len(t, requests, 2)
+
+	allow = false
+	_, err = a.Sign(keys[0], data)
+	assert.Error(t, err, "the signature denied by the user must fail")
+	assert.Len(t, requests, 3)
+
+	assert.Error(t, a.RemoveAll(), "the keys can't be removed via the agent")
+}
+
+func TestSSHAgentMismatchedPublicKey(t *testing.T) {
+	c := &Client{repo: repo.New()}
+	privateKey, _ := newEd25519Key(t)
+	_, otherPublic := newEd25519Key(t)
+	addSSHKey(t, c, SSHKey{ID: uuid.New(), PrivateKey: privateKey, PublicKey: otherPublic, Comment: "forged"})
+
+	var fingerprint string
+	a := serveTestAgent(t, c, func(req SSHSignRequest) bool {
+		fingerprint = req.Fingerprint
+		return true
+	})
+
+	keys, err := a.List()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	stored, _, _, _, err := ssh.ParseAuthorizedKey([]byte(otherPublic))
+	require.NoError(t, err)
+	assert.NotEqual(t, stored.Marshal(), keys[0].Marshal(), "the stored public key must not be served")
+
+	sig, err := a.Sign(keys[0], []byte("data"))
+	require.NoError(t, err)
+	assert.NoError(t, keys[0].Verify([]byte("data"), sig))
+	assert.Equal(t, ssh.FingerprintSHA256(keys[0]), fingerprint, "the user must confirm the key that signs")
+}
+
+func TestServeSSHAgentSocket(t *testing.T) {
+	c := &Client{repo: repo.New()}
+	confirm := func(SSHSignRequest) bool { return true }
+
+	// the directory accessible by others is rejected
+	shared := filepath.Join(t.TempDir(), "shared")
+	require.NoError(t, os.Mkdir(shared, 0755))
+	require.NoError(t, os.Chmod(shared, 0755))
+	assert.Error(t, c.ServeSSHAgent(context.Background(), filepath.Join(shared, "agent.sock"), confirm))
+
+	socketPath := filepath.Join(t.TempDir(), "agent", "agent.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.ServeSSHAgent(ctx, socketPath, confirm) }()
+
+	var conn net.Conn
+	require.Eventually(t, func() bool {
+		var err error
+		conn, err = net.Dial("unix", socketPath)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	defer conn.Close()
+
+	dirInfo, err := os.Stat(filepath.Dir(socketPath))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), dirInfo.Mode().Perm())
+	socketInfo, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), socketInfo.Mode().Perm())
+
+	keys, err := agent.NewClient(conn).List()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	cancel()
+	assert.NoError(t, <-done)
+	_, err = os.Stat(socketPath)
+	assert.ErrorIs(t, err, os.ErrNotExist, "the socket must be removed when the agent is stopped")
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListTrash returns the deleted items of the user kept in the trash on the server.
+func (c *Client) ListTrash() ([]models.Item, error) {
+	list, err := c.pbClient.ListTrash(c.ctx, &pb.ListTrashRequest{}, c.auth)
+	if err != nil {
+		return nil, trashOpError("listTrash", err)
+	}
+	items := make([]models.Item, 0, len(list.Items))
+	for _, pbItem := range list.Items {
+		item, err := models.PbToItem(pbItem)
+		if err != nil {
+			return nil, fmt.Errorf("listTrash: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// RestoreItem restores the deleted item from the trash and puts it back to the local repository.
+func (c *Client) RestoreItem(itemID uuid.UUID) error {
+	resp, err := c.pbClient.RestoreItem(c.ctx, &pb.RestoreItemRequest{
+		ItemId: itemID.String(),
+	}, c.auth)
+	if err != nil {
+		return trashOpError("restoreItem", err)
+	}
+	item, err := models.PbToItem(resp.Item)
+	if err != nil {
+		return fmt.Errorf("restoreItem: %w", err)
+	}
+	if err := c.repo.RestoreItem(item); err != nil {
+		return fmt.Errorf("restoreItem: could not store the item to local repository: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeItem permanently erases the deleted item from the trash and from the local repository.
+func (c *Client) PurgeItem(itemID uuid.UUID) error {
+	_, err := c.pbClient.PurgeItem(c.ctx, &pb.PurgeItemRequest{
+		ItemId: itemID.String(),
+	}, c.auth)
+	if err != nil {
+		return trashOpError("purgeItem", err)
+	}
+	c.repo.RemoveItem(itemID)
+
+	return nil
+}
+
+// trashOpError logs the error returned by the server and converts it into a readable error.
+func trashOpError(op string, err error) error {
+	se, _ := status.FromError(err)
+	var errMsg string
+	switch se.Code() {
+	case codes.Internal:
+		errMsg = fmt.Sprintf("%s: internal server error: %s", op, se.Message())
+	case codes.NotFound:
+		errMsg = fmt.Sprintf("%s: item is not found in the trash: %s", op, se.Message())
+	case codes.Unauthenticated:
+		errMsg = fmt.Sprintf("%s: could not authenticate the user: %s", op, se.Message())
+	default:
+		errMsg = fmt.Sprintf("%s: %s", op, se.Message())
+	}
+	log.Println(errMsg)
+
+	return errors.New(errMsg)
+}
@@ -5,17 +5,27 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/vanamelnik/gophkeeper/client/repo"
 	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/pkg/srp"
 	"github.com/vanamelnik/gophkeeper/proto"
 	pb "github.com/vanamelnik/gophkeeper/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 const minPasswordLength = 6
 
+// Client application info reported to the server at the start of the user session.
+// The values can be overridden at build time with -ldflags "-X ...".
+var (
+	ClientName    = "GophKeeper client"
+	ClientVersion = "dev"
+)
+
 // RenewTokens sends the refresh token to the server and renews client's accessToken and refreshToken.
 func (c *Client) RenewTokens() error {
 	userAuth, err := c.pbClient.GetNewTokens(c.ctx, &proto.RefreshToken{RefreshToken: string(c.repo.GetRefreshToken())})
@@ -23,21 +33,28 @@ func (c *Client) RenewTokens() error {
 		log.Printf("client: could not get new pair of tokens, relogin needed: %s", err)
 		return ErrReloginNeeded
 	}
-	c.repo.StoreAccessToken(models.AccessToken(userAuth.AccessToken.String()))
-	c.repo.StoreRefreshToken(models.RefreshToken(userAuth.RefreshToken.String()))
+	c.repo.StoreAccessToken(models.AccessToken(userAuth.AccessToken.AccessToken))
+	c.repo.StoreRefreshToken(models.RefreshToken(userAuth.RefreshToken.RefreshToken))
 
 	return nil
 }
 
-// SignUp sends user's email and password to the server to register a new user.
+// SignUp registers a new user on the server. The password is never sent to the server:
+// only SRP-6a verifier computed from the password is stored there.
 // If the registration is successfull, the new user session is created and auth token pair is returned.
 func SignUp(ctx context.Context, pbClient pb.GophkeeperClient, email, password string) (models.AccessToken, models.RefreshToken, error) {
 	if err := validatePassword(password); err != nil {
 		return "", "", err
 	}
-	userAuth, err := pbClient.SignUp(ctx, &pb.SignInData{
-		Email:        email,
-		UserPassword: password,
+	salt, err := srp.NewSalt()
+	if err != nil {
+		return "", "", fmt.Errorf("signUp: %w", err)
+	}
+	userAuth, err := pbClient.SRPSignUp(ctx, &pb.SRPSignUpRequest{
+		Email:      email,
+		Salt:       salt,
+		Verifier:   srp.ComputeVerifier(email, password, salt),
+		ClientInfo: &pb.ClientInfo{Name: ClientName, Version: ClientVersion},
 	})
 	if err == nil {
 		return models.AccessToken(userAuth.AccessToken.AccessToken), models.RefreshToken(userAuth.RefreshToken.RefreshToken), nil
@@ -49,34 +66,177 @@ func SignUp(ctx context.Context, pbClient pb.GophkeeperClient, email, password s
 		errMsg = fmt.Sprintf("signUp: internal server error: %s", se.Message())
 	case codes.AlreadyExists:
 		errMsg = fmt.Sprintf("signUp: user with email %s already exists: %s", email, se.Message())
+	case codes.ResourceExhausted:
+		errMsg = fmt.Sprintf("signUp: too many attempts, try again in %v", retryDelay(se))
+	default:
+		errMsg = fmt.Sprintf("signUp: %s", se.Message())
 	}
 	log.Println(errMsg)
 
 	return "", "", errors.New(errMsg)
 }
 
-// LogIn sends user's email and password to the server to authenticate the user and to create the new user session.
+// LogIn authenticates the user by SRP-6a handshake and creates the new user session.
+// The password is never sent to the server. The server's proof is checked, so the tokens
+// are accepted only from the server that knows the user's verifier.
+// If the user has two-factor authentication enabled, ErrMFARequired returns: the login must be completed by LogInMFA.
 func LogIn(ctx context.Context, pbClient pb.GophkeeperClient, email, password string) (models.AccessToken, models.RefreshToken, error) {
-	userAuth, err := pbClient.LogIn(ctx, &pb.SignInData{
+	srpClient, proof, err := proveSRPPassword(ctx, pbClient, email, password, false)
+	if err != nil {
+		return "", "", logInError(email, err)
+	}
+	result, err := pbClient.SRPLogInFinish(ctx, &pb.SRPLogInFinishRequest{
+		HandshakeId: proof.HandshakeId,
+		Email:       email,
+		ClientProof: proof.ClientProof,
+		ClientInfo:  &pb.ClientInfo{Name: ClientName, Version: ClientVersion},
+	})
+	if err != nil {
+		return "", "", logInError(email, err)
+	}
+	if err := srpClient.VerifyServerProof(result.ServerProof); err != nil {
+		return "", "", fmt.Errorf("logIn: could not authenticate the server: %w", err)
+	}
+
+	return userAuthTokens(result.Auth)
+}
+
+// proveSRPPassword starts SRP-6a handshake for the user and computes the proof of the password.
+// The proof is sent either by SRPLogInFinish or by the request that requires the password confirmation,
+// so the password itself is never sent to the server. If undelete is set, the handshake is started for
+// the deleted user. The error of the server is returned as is.
+func proveSRPPassword(ctx context.Context, pbClient pb.GophkeeperClient, email, password string,
+	undelete bool) (*srp.Client, *pb.SRPProof, error) {
+	srpClient, err := srp.NewClient(email, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	challenge, err := pbClient.SRPLogInStart(ctx, &pb.SRPLogInStartRequest{
 		Email:        email,
-		UserPassword: password,
+		ClientPublic: srpClient.PublicValue(),
+		Undelete:     undelete,
 	})
-	if err == nil {
-		return models.AccessToken(userAuth.AccessToken.AccessToken), models.RefreshToken(userAuth.RefreshToken.RefreshToken), nil
+	if err != nil {
+		return nil, nil, err
+	}
+	clientProof, err := srpClient.ProcessChallenge(challenge.Salt, challenge.ServerPublic)
+	if err != nil {
+		return nil, nil, err
 	}
+
+	return srpClient, &pb.SRPProof{
+		HandshakeId: challenge.HandshakeId,
+		ClientProof: clientProof,
+	}, nil
+}
+
+// userAuthTokens returns the tokens of successful login or ErrMFARequired if the login must be completed by LogInMFA.
+func userAuthTokens(userAuth *pb.UserAuth) (models.AccessToken, models.RefreshToken, error) {
+	if userAuth.GetMfaChallenge() != "" {
+		return "", "", ErrMFARequired{Challenge: models.MFAChallenge(userAuth.MfaChallenge)}
+	}
+	return models.AccessToken(userAuth.GetAccessToken().GetAccessToken()), models.RefreshToken(userAuth.GetRefreshToken().GetRefreshToken()), nil
+}
+
+// logInError converts the error of login request to the error for the user.
+func logInError(email string, err error) error {
 	se, _ := status.FromError(err)
 	var errMsg string
 	switch se.Code() {
 	case codes.Internal:
 		errMsg = fmt.Sprintf("logIn: internal server error: %s", se.Message())
-	case codes.NotFound:
-		errMsg = fmt.Sprintf("logIn: user with email %s is not found: %s", email, se.Message())
 	case codes.Unauthenticated:
-		errMsg = fmt.Sprintf("logIn: could not authenticate the user with email %s: %s", email, se.Message())
+		errMsg = fmt.Sprintf("logIn: wrong email or password for %s "+
+			"(the accounts registered before SRP support must reset the password): %s", email, se.Message())
+	case codes.ResourceExhausted:
+		errMsg = fmt.Sprintf("logIn: too many attempts, try again in %v", retryDelay(se))
+	default:
+		errMsg = fmt.Sprintf("logIn: %s", se.Message())
 	}
 	log.Println(errMsg)
 
-	return "", "", errors.New(errMsg)
+	return errors.New(errMsg)
+}
+
+// VerifyEmail sends the verification token received by email to the server.
+func VerifyEmail(ctx context.Context, pbClient pb.GophkeeperClient, token string) error {
+	_, err := pbClient.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: token})
+	if err == nil {
+		return nil
+	}
+	se, _ := status.FromError(err)
+	var errMsg string
+	switch se.Code() {
+	case codes.Internal:
+		errMsg = fmt.Sprintf("verifyEmail: internal server error: %s", se.Message())
+	case codes.InvalidArgument:
+		errMsg = fmt.Sprintf("verifyEmail: the token is incorrect or expired: %s", se.Message())
+	case codes.ResourceExhausted:
+		errMsg = fmt.Sprintf("verifyEmail: too many attempts, try again in %v", retryDelay(se))
+	default:
+		errMsg = fmt.Sprintf("verifyEmail: %s", se.Message())
+	}
+	log.Println(errMsg)
+
+	return errors.New(errMsg)
+}
+
+// RequestPasswordReset asks the server to send the password reset token to the email provided.
+func RequestPasswordReset(ctx context.Context, pbClient pb.GophkeeperClient, email string) error {
+	_, err := pbClient.RequestPasswordReset(ctx, &pb.RequestPasswordResetRequest{Email: email})
+	if err == nil {
+		return nil
+	}
+	se, _ := status.FromError(err)
+	var errMsg string
+	switch se.Code() {
+	case codes.Internal:
+		errMsg = fmt.Sprintf("requestPasswordReset: internal server error: %s", se.Message())
+	case codes.ResourceExhausted:
+		errMsg = fmt.Sprintf("requestPasswordReset: too many attempts, try again in %v", retryDelay(se))
+	default:
+		errMsg = fmt.Sprintf("requestPasswordReset: %s", se.Message())
+	}
+	log.Println(errMsg)
+
+	return errors.New(errMsg)
+}
+
+// ResetPassword sets the new password of the user with the reset token received by email.
+// Only SRP-6a verifier of the new password is sent to the server. All the user sessions are ended,
+// so the user must log in again.
+func ResetPassword(ctx context.Context, pbClient pb.GophkeeperClient, email, token, newPassword string) error {
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+	salt, err := srp.NewSalt()
+	if err != nil {
+		return fmt.Errorf("resetPassword: %w", err)
+	}
+	_, err = pbClient.ResetPassword(ctx, &pb.ResetPasswordRequest{
+		Token:    token,
+		Email:    email,
+		Salt:     salt,
+		Verifier: srp.ComputeVerifier(email, newPassword, salt),
+	})
+	if err == nil {
+		return nil
+	}
+	se, _ := status.FromError(err)
+	var errMsg string
+	switch se.Code() {
+	case codes.Internal:
+		errMsg = fmt.Sprintf("resetPassword: internal server error: %s", se.Message())
+	case codes.InvalidArgument:
+		errMsg = fmt.Sprintf("resetPassword: the token is incorrect or expired: %s", se.Message())
+	case codes.ResourceExhausted:
+		errMsg = fmt.Sprintf("resetPassword: too many attempts, try again in %v", retryDelay(se))
+	default:
+		errMsg = fmt.Sprintf("resetPassword: %s", se.Message())
+	}
+	log.Println(errMsg)
+
+	return errors.New(errMsg)
 }
 
 func LogOut(ctx context.Context, pbClient pb.GophkeeperClient, r *repo.Repo) error {
@@ -87,6 +247,116 @@ func LogOut(ctx context.Context, pbClient pb.GophkeeperClient, r *repo.Repo) err
 	return err
 }
 
+// ChangePassword changes the password of the user. The old password is confirmed by SRP-6a proof and
+// only the verifier of the new one is sent to the server. All other sessions of the user are ended by the server.
+func (c *Client) ChangePassword(email, oldPassword, newPassword string) error {
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+	salt, err := srp.NewSalt()
+	if err != nil {
+		return fmt.Errorf("changePassword: %w", err)
+	}
+	_, proof, err := proveSRPPassword(c.ctx, c.pbClient, email, oldPassword, false)
+	if err == nil {
+		_, err = c.pbClient.ChangePassword(c.ctx, &pb.ChangePasswordRequest{
+			Proof:       proof,
+			NewSalt:     salt,
+			NewVerifier: srp.ComputeVerifier(email, newPassword, salt),
+		}, c.auth)
+	}
+	if err == nil {
+		return nil
+	}
+	se, _ := status.FromError(err)
+	var errMsg string
+	switch se.Code() {
+	case codes.Internal:
+		errMsg = fmt.Sprintf("changePassword: internal server error: %s", se.Message())
+	case codes.PermissionDenied:
+		errMsg = fmt.Sprintf("changePassword: old password is incorrect: %s", se.Message())
+	default:
+		errMsg = fmt.Sprintf("changePassword: %s", se.Message())
+	}
+	log.Println(errMsg)
+
+	return errors.New(errMsg)
+}
+
+// DeleteUser deletes the user account on the server. The user's password is confirmed by SRP-6a proof.
+// If the operation is successful, the session is stopped and the auth tokens are erased.
+// The account can be restored with UndeleteUser during the grace period.
+func (c *Client) DeleteUser(email, password string) error {
+	_, proof, err := proveSRPPassword(c.ctx, c.pbClient, email, password, false)
+	if err == nil {
+		_, err = c.pbClient.DeleteUser(c.ctx, &pb.DeleteUserRequest{Proof: proof}, c.auth)
+	}
+	if err == nil {
+		c.repo.StoreAccessToken("")
+		c.repo.StoreRefreshToken("")
+		c.Close()
+		return nil
+	}
+	se, _ := status.FromError(err)
+	var errMsg string
+	switch se.Code() {
+	case codes.Internal:
+		errMsg = fmt.Sprintf("deleteUser: internal server error: %s", se.Message())
+	case codes.PermissionDenied:
+		errMsg = fmt.Sprintf("deleteUser: password is incorrect: %s", se.Message())
+	default:
+		errMsg = fmt.Sprintf("deleteUser: %s", se.Message())
+	}
+	log.Println(errMsg)
+
+	return errors.New(errMsg)
+}
+
+// UndeleteUser restores the deleted user account and creates a new user session.
+// The user's password is confirmed by SRP-6a proof.
+// If the user has two-factor authentication enabled, ErrMFARequired returns: the account is restored by LogInMFA.
+func UndeleteUser(ctx context.Context, pbClient pb.GophkeeperClient, email, password string) (models.AccessToken, models.RefreshToken, error) {
+	_, proof, err := proveSRPPassword(ctx, pbClient, email, password, true)
+	if err == nil {
+		var userAuth *pb.UserAuth
+		userAuth, err = pbClient.UndeleteUser(ctx, &pb.UndeleteUserRequest{
+			Email:      email,
+			Proof:      proof,
+			ClientInfo: &pb.ClientInfo{Name: ClientName, Version: ClientVersion},
+		})
+		if err == nil {
+			return userAuthTokens(userAuth)
+		}
+	}
+	se, _ := status.FromError(err)
+	var errMsg string
+	switch se.Code() {
+	case codes.Internal:
+		errMsg = fmt.Sprintf("undeleteUser: internal server error: %s", se.Message())
+	case codes.NotFound:
+		errMsg = fmt.Sprintf("undeleteUser: deleted user with email %s is not found: %s", email, se.Message())
+	case codes.Unauthenticated:
+		errMsg = fmt.Sprintf("undeleteUser: could not authenticate the user with email %s: %s", email, se.Message())
+	case codes.ResourceExhausted:
+		errMsg = fmt.Sprintf("undeleteUser: too many attempts, try again in %v", retryDelay(se))
+	default:
+		errMsg = fmt.Sprintf("undeleteUser: %s", se.Message())
+	}
+	log.Println(errMsg)
+
+	return "", "", errors.New(errMsg)
+}
+
+// retryDelay returns the delay from RetryInfo detail of the status. If there is no such detail, zero returns.
+func retryDelay(se *status.Status) time.Duration {
+	for _, d := range se.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			return ri.RetryDelay.AsDuration()
+		}
+	}
+	return 0
+}
+
 func validatePassword(p string) error {
 	// TODO: add more password complexity checks
 	if len([]rune(p)) < minPasswordLength {
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// watchTimeout is the time without any message after which the Watch stream is considered broken.
+	// The server sends heartbeats every 30 seconds by default.
+	watchTimeout = 90 * time.Second
+
+	watchRetryMin = time.Second
+	watchRetryMax = time.Minute
+)
+
+// watchNotice is the message from the watcher to the worker.
+type watchNotice struct {
+	// connected is false when the stream is broken and the worker must poll the server.
+	connected bool
+	// dataVersion is the data version on the server, zero for notices without the version.
+	dataVersion uint64
+}
+
+// watcher keeps the Watch stream open and reports the data versions received to the worker.
+// When the stream is broken, it reconnects with exponential backoff.
+func (c *Client) watcher(done <-chan struct{}) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	delay := watchRetryMin
+	for {
+		received, err := c.watch(ctx, done)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("client: watch: %s; reconnecting in %v", err, delay)
+		select {
+		case c.watchCh <- watchNotice{connected: false}:
+		case <-done:
+			return
+		}
+		if received {
+			delay = watchRetryMin
+		}
+		select {
+		case <-time.After(delay):
+		case <-done:
+			return
+		}
+		if delay *= 2; delay > watchRetryMax {
+			delay = watchRetryMax
+		}
+	}
+}
+
+// watch reads the Watch stream until it's broken. It reports whether any message was received.
+// The expired tokens are renewed by the worker that falls back to polling meanwhile.
+func (c *Client) watch(ctx context.Context, done <-chan struct{}) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// the stream is canceled if neither a change nor a heartbeat arrives in time
+	watchdog := time.AfterFunc(watchTimeout, cancel)
+	defer watchdog.Stop()
+
+	stream, err := c.pbClient.Watch(ctx, &pb.WatchRequest{}, c.auth)
+	if err != nil {
+		return false, err
+	}
+	received := false
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				err = status.Error(codes.Unavailable, "stream closed by the server")
+			}
+			return received, err
+		}
+		watchdog.Reset(watchTimeout)
+		received = true
+		dc := event.GetDataChanged()
+		if dc == nil { // heartbeat
+			continue
+		}
+		select {
+		case c.watchCh <- watchNotice{connected: true, dataVersion: dc.DataVersion}:
+		case <-done:
+			return received, ctx.Err()
+		}
+	}
+}
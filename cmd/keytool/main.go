@@ -0,0 +1,71 @@
+package main
+
+// keytool is the admin command for managing the token signing keys of GophKeeper server.
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/vanamelnik/gophkeeper/server/keyset"
+)
+
+const usage = `Usage: keytool <command> [flags]
+
+Commands:
+  roll    generate a new signing key; the current key is retired
+  list    list the keys, the last one is the signing key
+  prune   remove the retired keys whose tokens are expired
+
+Flags:
+`
+
+func main() {
+	fs := flag.NewFlagSet("keytool", flag.ExitOnError)
+	dir := fs.String("dir", "./keys", "directory with the keys")
+	keep := fs.Duration("keep", 24*time.Hour, "prune: keep the retired keys for this time (max token lifetime)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if len(os.Args) < 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	cmd := os.Args[1]
+	must(fs.Parse(os.Args[2:]))
+
+	switch cmd {
+	case "roll":
+		kid, err := keyset.Generate(*dir)
+		must(err)
+		fmt.Printf("new signing key: %s\n", kid)
+	case "list":
+		kids, err := keyset.List(*dir)
+		must(err)
+		for i, kid := range kids {
+			if i == len(kids)-1 {
+				fmt.Printf("%s (signing)\n", kid)
+				continue
+			}
+			fmt.Printf("%s (retired)\n", kid)
+		}
+	case "prune":
+		removed, err := keyset.Prune(*dir, *keep)
+		must(err)
+		for _, kid := range removed {
+			fmt.Printf("removed: %s\n", kid)
+		}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+func must(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
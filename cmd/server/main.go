@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net"
 	"os"
@@ -10,8 +12,12 @@ import (
 
 	"github.com/spf13/viper"
 	"github.com/vanamelnik/gophkeeper/server/api"
+	"github.com/vanamelnik/gophkeeper/server/blobstore"
 	"github.com/vanamelnik/gophkeeper/server/gophkeeper"
+	"github.com/vanamelnik/gophkeeper/server/keyset"
+	"github.com/vanamelnik/gophkeeper/server/mailer"
 	"github.com/vanamelnik/gophkeeper/server/storage/postgres"
+	"github.com/vanamelnik/gophkeeper/server/throttle"
 	"github.com/vanamelnik/gophkeeper/server/users"
 	"google.golang.org/grpc"
 )
@@ -21,6 +27,12 @@ const (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate-blobs" {
+		must(loadConfig())
+		migrateBlobs()
+		return
+	}
+
 	log.Println("Starting GophKeeper server")
 	must(loadConfig())
 
@@ -28,20 +40,50 @@ func main() {
 	must(err)
 	defer s.Close()
 
+	keys, err := loadKeys(viper.GetString("tokens.keysDir"))
+	must(err)
+
 	u := users.NewService(
 		s,
-		viper.GetString("tokens.secretKey"),
+		keys,
 		viper.GetDuration("tokens.accessTokenDuration"),
 		viper.GetDuration("tokens.refreshTokenDuration"),
+		users.WithDeletionGracePeriod(viper.GetDuration("users.deletionGracePeriod")),
+		users.WithRevokeAllOnTokenReuse(viper.GetBool("tokens.revokeAllSessionsOnReuse")),
+		users.WithMailer(newMailer()),
+		users.WithRequireVerifiedEmail(viper.GetBool("users.requireVerifiedEmail")),
+		users.WithFakeSaltKey([]byte(viper.GetString("users.fakeSaltKey"))),
 	)
 
-	g := gophkeeper.NewService(s)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go u.RunPurger(ctx, viper.GetDuration("users.purgeInterval"))
+	go keys.RunReloader(ctx, viper.GetDuration("tokens.keysReloadInterval"))
+
+	blobs, err := blobstore.New(blobStoreConfig())
+	must(err)
+	g := gophkeeper.NewService(s, gophkeeper.WithBlobStore(blobs))
 	defer g.Close()
+	go g.RunCompactor(ctx, viper.GetDuration("sync.compactInterval"), viper.GetUint64("sync.changeLogRetention"))
+	go g.RunTrashPurger(ctx, viper.GetDuration("trash.purgeInterval"), viper.GetDuration("trash.retention"))
+	go g.RunHistoryPruner(ctx, viper.GetDuration("history.pruneInterval"),
+		viper.GetInt("history.maxVersions"), viper.GetDuration("history.maxAge"))
+	go g.RunBlobCollector(ctx, viper.GetDuration("blobs.gcInterval"), viper.GetDuration("blobs.gcGracePeriod"))
 
 	sigint := make(chan os.Signal, 1)
 	signal.Notify(sigint, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 
-	server := api.NewServer(u, g)
+	l := throttle.NewLimiter(
+		s,
+		throttle.WithLockout(viper.GetInt("throttle.maxFailures"), viper.GetDuration("throttle.lockoutDuration")),
+		throttle.WithBackoff(viper.GetDuration("throttle.baseDelay"), viper.GetDuration("throttle.maxDelay")),
+		throttle.WithFailureWindow(viper.GetDuration("throttle.failureWindow")),
+	)
+
+	server := api.NewServer(u, g, l,
+		api.WithWatchHeartbeat(viper.GetDuration("server.watchHeartbeat")),
+		api.WithPasswordResetRate(viper.GetInt("throttle.passwordResetLimit"), viper.GetDuration("throttle.passwordResetWindow")),
+	)
 	go runServer(server)
 
 	<-sigint
@@ -61,6 +103,79 @@ func loadConfig() error {
 	return nil
 }
 
+// loadKeys loads the token signing keys. If there are no keys yet, the first one is generated.
+func loadKeys(dir string) (*keyset.KeySet, error) {
+	keys, err := keyset.Load(dir)
+	if errors.Is(err, keyset.ErrNoKeys) {
+		kid, err := keyset.Generate(dir)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("No signing keys found in %q, the new key %s is generated", dir, kid)
+		return keyset.Load(dir)
+	}
+	return keys, err
+}
+
+// newMailer creates the mailer configured by mail.driver: "smtp" (default), "file" or "log".
+// The file and log drivers expose the tokens sent to the users, so they must be chosen explicitly.
+func newMailer() mailer.Mailer {
+	from := viper.GetString("mail.from")
+	switch driver := viper.GetString("mail.driver"); driver {
+	case "smtp", "":
+		return mailer.NewSMTP(
+			viper.GetString("mail.smtp.host"),
+			viper.GetInt("mail.smtp.port"),
+			viper.GetString("mail.smtp.username"),
+			viper.GetString("mail.smtp.password"),
+			from,
+		)
+	case "file":
+		return mailer.NewFile(viper.GetString("mail.dir"), from)
+	case "log":
+		log.Println("WARNING: mail driver 'log' writes the verification and password reset tokens to the log; use it for local development only")
+		return mailer.Log{}
+	default:
+		log.Fatalf("unknown mail driver %q", driver)
+	}
+	return nil
+}
+
+// migrateBlobs moves the blob content kept in the database to the blob store configured.
+// It's run by "server migrate-blobs" command; the database is not reset.
+func migrateBlobs() {
+	blobs, err := blobstore.New(blobStoreConfig())
+	must(err)
+	if blobs == nil {
+		log.Fatal("migrate-blobs: blobs.store is not set")
+	}
+	s, err := postgres.NewStorage(viper.GetString("databaseDSN"))
+	must(err)
+	defer s.Close()
+
+	g := gophkeeper.NewService(s, gophkeeper.WithBlobStore(blobs))
+	defer g.Close()
+	n, err := g.MigrateBlobs(context.Background())
+	log.Printf("migrate-blobs: %d blob content(s) moved to %q store", n, viper.GetString("blobs.store"))
+	must(err)
+}
+
+// blobStoreConfig reads the config of the blob content store.
+func blobStoreConfig() blobstore.Config {
+	return blobstore.Config{
+		Backend: viper.GetString("blobs.store"),
+		Dir:     viper.GetString("blobs.dir"),
+		S3: blobstore.S3Config{
+			Endpoint:        viper.GetString("blobs.s3.endpoint"),
+			Region:          viper.GetString("blobs.s3.region"),
+			Bucket:          viper.GetString("blobs.s3.bucket"),
+			Prefix:          viper.GetString("blobs.s3.prefix"),
+			AccessKeyID:     viper.GetString("blobs.s3.accessKeyID"),
+			SecretAccessKey: viper.GetString("blobs.s3.secretAccessKey"),
+		},
+	}
+}
+
 func runServer(s *grpc.Server) {
 	port := viper.GetString("server.port")
 	listen, err := net.Listen("tcp", port)
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/hex"
+	"hash/crc32"
+
+	"github.com/google/uuid"
+)
+
+// BlobUpload is the state of the blob content stored on the server.
+// The content is identified by its hash within the user's data.
+type BlobUpload struct {
+	UserID uuid.UUID
+	Hash   string
+	Size   int64
+	// Received is the number of bytes of the content received so far.
+	Received int64
+	// Complete is set when the whole content is received and its hash is verified.
+	Complete bool
+}
+
+// castagnoli is the table of CRC-32C checksum of the blob chunks.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// BlobChunkChecksum returns the CRC-32C checksum of the blob chunk data.
+func BlobChunkChecksum(data []byte) uint32 {
+	return crc32.Checksum(data, castagnoli)
+}
+
+// IsValidBlobHash checks that the hash is a lowercase hex encoded SHA-256 hash.
+func IsValidBlobHash(hash string) bool {
+	if len(hash) != hex.EncodedLen(32) {
+		return false
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/google/uuid"
 	pb "github.com/vanamelnik/gophkeeper/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // PbToItem converts protobuf Item to canonical Item struct.
@@ -13,13 +14,15 @@ func PbToItem(item *pb.Item) (Item, error) {
 	if err != nil {
 		return Item{}, err
 	}
-	createdAt := item.CreatedAt.AsTime()
 	result := Item{
 		ID:        itemID,
 		Version:   item.Version,
-		CreatedAt: &createdAt,
 		DeletedAt: nil,
-		Meta:      JSONMetadata(item.Metadata.Metadata),
+		Meta:      JSONMetadata(item.GetMetadata().GetMetadata()),
+	}
+	if item.CreatedAt != nil {
+		createdAt := item.CreatedAt.AsTime()
+		result.CreatedAt = &createdAt
 	}
 	if item.DeletedAt != nil {
 		if !item.DeletedAt.IsValid() {
@@ -31,7 +34,7 @@ func PbToItem(item *pb.Item) (Item, error) {
 
 	switch pl := item.Payload.(type) {
 	case *pb.Item_Blob:
-		result.Payload = BinaryData{pl.Blob.Data}
+		result.Payload = BinaryData{Size: pl.Blob.Size, Hash: pl.Blob.Sha256}
 	case *pb.Item_Text:
 		result.Payload = TextData{pl.Text.Text}
 	case *pb.Item_Password:
@@ -43,28 +46,55 @@ func PbToItem(item *pb.Item) (Item, error) {
 			Date:           pl.Card.Date,
 			CVC:            pl.Card.Cvc,
 		}
+	case *pb.Item_Otp:
+		result.Payload = OTPData{
+			Secret:    pl.Otp.Secret,
+			Algorithm: pl.Otp.Algorithm,
+			Digits:    pl.Otp.Digits,
+			Period:    pl.Otp.Period,
+			Issuer:    pl.Otp.Issuer,
+			Account:   pl.Otp.Account,
+		}
+	case *pb.Item_SshKey:
+		result.Payload = SSHKeyData{
+			PrivateKey: pl.SshKey.PrivateKey,
+			PublicKey:  pl.SshKey.PublicKey,
+			Comment:    pl.SshKey.Comment,
+			Passphrase: pl.SshKey.Passphrase,
+		}
+	case nil:
+		if result.DeletedAt == nil {
+			return Item{}, errors.New("the payload is missing")
+		}
 	default:
 		return Item{}, errors.New("unknown type of the payload")
 	}
 	return result, nil
 }
 
-// ItemToPb converts canonical Item to protobuf Item
+// ItemToPb converts canonical Item to protobuf Item. Tombstones are converted without payload.
 func ItemToPb(item Item) *pb.Item {
 	pbItem := pb.Item{
 		ItemId: &pb.ItemID{
 			ItemId: item.ID.String(),
 		},
+		Version: item.Version,
 		Metadata: &pb.Metadata{
 			Metadata: string(item.Meta),
 		},
 	}
+	if item.CreatedAt != nil {
+		pbItem.CreatedAt = timestamppb.New(*item.CreatedAt)
+	}
+	if item.DeletedAt != nil {
+		pbItem.DeletedAt = timestamppb.New(*item.DeletedAt)
+	}
 	switch body := item.Payload.(type) {
 	case TextData:
 		text := pb.Item_Text{Text: &pb.Text{Text: body.Text}}
 		pbItem.Payload = &text
 	case BinaryData:
-		blob := pb.Item_Blob{Blob: &pb.Blob{Data: body.Binary}}
+		blob := pb.Item_Blob{Blob: &pb.Blob{Size: body.Size, Sha256: body.Hash}}
 		pbItem.Payload = &blob
 	case PasswordData:
 		password := pb.Item_Password{Password: &pb.Password{Password: body.Password}}
@@ -77,6 +107,24 @@ func ItemToPb(item Item) *pb.Item {
 			Cvc:    body.CVC,
 		}}
 		pbItem.Payload = &card
+	case OTPData:
+		otp := pb.Item_Otp{Otp: &pb.OTP{
+			Secret:    body.Secret,
+			Algorithm: body.Algorithm,
+			Digits:    body.Digits,
+			Period:    body.Period,
+			Issuer:    body.Issuer,
+			Account:   body.Account,
+		}}
+		pbItem.Payload = &otp
+	case SSHKeyData:
+		sshKey := pb.Item_SshKey{SshKey: &pb.SSHKey{
+			PrivateKey: body.PrivateKey,
+			PublicKey:  body.PublicKey,
+			Comment:    body.Comment,
+			Passphrase: body.Passphrase,
+		}}
+		pbItem.Payload = &sshKey
 	}
 	return &pbItem
 }
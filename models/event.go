@@ -1,5 +1,7 @@
 package models
 
+import "github.com/google/uuid"
+
 // Event represents a change in data in user's storage
 type Event struct {
 	Operation Operation
@@ -12,8 +14,26 @@ type Operation string
 const (
 	OpCreate Operation = "CREATE"
 	OpUpdate Operation = "UPDATE"
+	// OpDelete turns the item into a tombstone. Only ID and Version of the event item are used.
+	OpDelete Operation = "DELETE"
 )
 
 func (o Operation) Valid() bool {
-	return o == OpCreate || o == OpUpdate
+	return o == OpCreate || o == OpUpdate || o == OpDelete
+}
+
+// EventResult is the outcome of the event processed on the server.
+type EventResult struct {
+	ItemID uuid.UUID
+	// Version is the version of the item assigned by the server, zero if the event is not applied.
+	Version uint64
+	// Err is the reason why the event is not applied.
+	Err error
+}
+
+// PublishResult is the outcome of the pack of events processed on the server in one transaction.
+type PublishResult struct {
+	// DataVersion is the user data version after the transaction.
+	DataVersion uint64
+	Results     []EventResult
 }
@@ -21,6 +21,8 @@ type (
 		//	- BinaryData
 		//	- PasswordData
 		//	- CardData
+		//	- OTPData
+		//	- SSHKeyData
 		Payload interface{}
 
 		Meta JSONMetadata
@@ -33,9 +35,13 @@ type (
 		Text string
 	}
 
-	// BinaryData contains binary data.
+	// BinaryData is the reference to the binary content uploaded separately from the item.
+	// The content itself is transferred in chunks by UploadBlob and DownloadBlob calls.
 	BinaryData struct {
-		Binary []byte
+		// Size is the size of the content in bytes.
+		Size int64
+		// Hash is the hex encoded SHA-256 hash of the content.
+		Hash string
 	}
 
 	// PasswordData contains one of user's passwords.
@@ -51,18 +57,71 @@ type (
 		CVC            uint32
 	}
 
+	// OTPData contains the key of the time-based one-time passwords of a third-party service.
+	// The codes are generated on the client.
+	OTPData struct {
+		Secret []byte
+		// Algorithm is the HMAC hash function: SHA1, SHA256 or SHA512.
+		Algorithm string
+		Digits    uint32
+		// Period is the validity period of a code in seconds.
+		Period  uint32
+		Issuer  string
+		Account string
+	}
+
+	// SSHKeyData contains the SSH key pair served by the client-side ssh-agent.
+	SSHKeyData struct {
+		// PrivateKey is PEM encoded, optionally encrypted with the Passphrase.
+		PrivateKey []byte
+		// PublicKey is in the authorized_keys format.
+		PublicKey  string
+		Comment    string
+		Passphrase string
+	}
+
 	// JSONMetadata is a JSON string that represents a set of key-value pairs,
 	// that may contain different additional data such as login, bank name, kind of notes etc.
 	// The types of such data are not strictly defined and must be handled on the client side.
 	JSONMetadata string
 )
 
-// IsValidItem checks the type of item.Payload field.
+// IsValidItem checks the type of item.Payload field. A tombstone (deleted item) must have no payload.
 func IsValidItem(item Item) error {
-	switch item.Payload.(type) {
-	case TextData, CardData, PasswordData, BinaryData:
+	if item.IsTombstone() {
+		if item.Payload != nil {
+			return ErrInvalidPayload
+		}
+		return nil
+	}
+	switch data := item.Payload.(type) {
+	case TextData, CardData, PasswordData:
+		return nil
+	case BinaryData:
+		if data.Size < 0 || !IsValidBlobHash(data.Hash) {
+			return ErrInvalidPayload
+		}
+		return nil
+	case OTPData:
+		if len(data.Secret) == 0 || data.Digits < 6 || data.Digits > 8 || data.Period == 0 {
+			return ErrInvalidPayload
+		}
+		switch data.Algorithm {
+		case "SHA1", "SHA256", "SHA512":
+			return nil
+		}
+		return ErrInvalidPayload
+	case SSHKeyData:
+		if len(data.PrivateKey) == 0 || data.PublicKey == "" {
+			return ErrInvalidPayload
+		}
 		return nil
 	default:
 		return ErrInvalidPayload
 	}
 }
+
+// IsTombstone reports whether the item is deleted.
+func (i Item) IsTombstone() bool {
+	return i.DeletedAt != nil
+}
@@ -18,6 +18,75 @@ type User struct {
 	PasswordHash string
 	CreatedAt    *time.Time
 	DeletedAt    *time.Time
+	// TOTPSecret is base32 encoded secret of the user's TOTP second factor.
+	// It's set at the enrollment and is used only after TOTPEnabled is set by confirmation.
+	TOTPSecret  string
+	TOTPEnabled bool
+	// SRPSalt and SRPVerifier are SRP-6a password verifier of the user and its salt.
+	// The users registered before SRP support have only PasswordHash and must reset the password.
+	SRPSalt     []byte
+	SRPVerifier []byte
+	// EmailVerified is set when the user confirms the email by the token sent to it.
+	EmailVerified bool
+}
+
+// UserToken is a one-time token sent to the user's email. Only the hash of the token is stored.
+type UserToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Purpose   TokenPurpose
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// TokenPurpose is the operation the user token is issued for.
+type TokenPurpose string
+
+const (
+	TokenPurposeVerifyEmail   TokenPurpose = "VERIFY_EMAIL"
+	TokenPurposeResetPassword TokenPurpose = "RESET_PASSWORD"
+)
+
+// MFAChallengeState is the server state of the MFA challenge issued by the login. The challenge
+// can be used for a limited number of attempts and is removed after the successful one.
+type MFAChallengeState struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Attempts  int
+	ExpiresAt time.Time
+}
+
+// SRPHandshake is the server state of SRP-6a login kept between SRPLogInStart and SRPLogInFinish.
+type SRPHandshake struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	ClientPublic []byte
+	ServerSecret []byte
+	ExpiresAt    time.Time
+}
+
+// SRPChallenge is the server's response to the start of SRP-6a handshake.
+type SRPChallenge struct {
+	HandshakeID  uuid.UUID
+	Salt         []byte
+	ServerPublic []byte
+}
+
+// SRPProof is the client's proof M1 of the password for the handshake started by SRPLogInStart.
+// It confirms the password for the sensitive operations without sending it to the server.
+type SRPProof struct {
+	HandshakeID uuid.UUID
+	ClientProof []byte
+}
+
+// RecoveryCode is a one-time code that can be used instead of TOTP code
+// if the user has lost the second factor device. Only the hash of the code is stored.
+type RecoveryCode struct {
+	ID       uuid.UUID
+	UserID   uuid.UUID
+	CodeHash string
+	UsedAt   *time.Time
 }
 
 // Session represents a single client session of the user with given ID.
@@ -29,6 +98,56 @@ type Session struct {
 	RefreshToken RefreshToken
 	LoginAt      *time.Time
 	LogoutAt     *time.Time
+	// Client describes the client application and the device the session is started from.
+	Client ClientInfo
+}
+
+// ClientInfo contains the information about the client of the user session.
+type ClientInfo struct {
+	// Name and Version are reported by the client application.
+	Name    string
+	Version string
+	// RemoteAddr is the network address of the client taken from the connection.
+	RemoteAddr string
+}
+
+// SecurityEvent is a record about a suspicious activity related to the user account.
+type SecurityEvent struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	Kind      SecurityEventKind
+	// RemoteAddr is the network address of the client that caused the event.
+	RemoteAddr string
+	CreatedAt  *time.Time
+}
+
+// SecurityEventKind specifies the kind of the security event.
+type SecurityEventKind string
+
+const (
+	// SecurityEventRefreshTokenReuse means that a superseded refresh token was presented.
+	// This may happen if the refresh token was stolen.
+	SecurityEventRefreshTokenReuse SecurityEventKind = "REFRESH_TOKEN_REUSE"
+)
+
+// LoginAttempts contains the counter of failed authentication attempts for the key
+// (e.g. email or remote IP address).
+type LoginAttempts struct {
+	Key           string
+	Failures      int
+	LastFailureAt *time.Time
+	// BlockedUntil is the time before which the attempts for the key are rejected.
+	BlockedUntil *time.Time
+	// Pending is the number of the attempts reserved and not yet completed.
+	Pending int
+}
+
+// RequestCounter is the number of the requests for the key counted in the current time window.
+type RequestCounter struct {
+	Key         string
+	Count       int
+	WindowStart time.Time
 }
 
 type (
@@ -42,7 +161,13 @@ type (
 	// RefreshToken is a one-time JWT signed with a secret key.
 	// RefreshToken is stored in the user sessions table and represents a single user session.
 	// It has a long expiration time and is used to refresh an expired access token.
+	// Each time the tokens are refreshed, the previous refresh token is superseded. Presenting
+	// a superseded token is treated as token theft and the session is revoked.
 	RefreshToken string
+
+	// MFAChallenge is a short-lived JWT returned by LogIn to the user with two-factor authentication
+	// enabled. It must be exchanged together with the one-time code for the auth token pair.
+	MFAChallenge string
 )
 
 // UserData represents the snapshot of all user's data stored in the storage.
@@ -53,6 +178,25 @@ type UserData struct {
 	Items   []Item
 }
 
+// ItemVersion describes the version of the item kept in the item history.
+type ItemVersion struct {
+	ItemID  uuid.UUID
+	Version uint64
+	// DataVersion is the user data version in which the item version was written.
+	DataVersion uint64
+	ArchivedAt  time.Time
+	// Deleted is set if the version is a tombstone.
+	Deleted bool
+}
+
+// DataChange is the notification about the change of user's data.
+type DataChange struct {
+	UserID      uuid.UUID
+	DataVersion uint64
+	// SessionID is the session that made the change, uuid.Nil if it's unknown.
+	SessionID uuid.UUID
+}
+
 // Errors
 var (
 	ErrInvalidPayload = errors.New("invalid payload type")
@@ -0,0 +1,39 @@
+package passhash
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt is the bcrypt hasher.
+type Bcrypt struct {
+	cost int
+}
+
+// NewBcrypt creates a new bcrypt hasher. If the cost is out of the allowed range, bcrypt.DefaultCost is used.
+func NewBcrypt(cost int) Bcrypt {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return Bcrypt{cost: cost}
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", fmt.Errorf("passhash: bcrypt: %w", err)
+	}
+	return string(hashed), nil
+}
+
+func (b Bcrypt) Verify(password, hash string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatchedHashAndPassword
+		}
+		return fmt.Errorf("passhash: bcrypt: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package passhash
+
+// Package passhash contains the hashers of the secrets verified by the server, such as the recovery codes
+// of two-factor authentication. The user passwords are never received by the server, see pkg/srp.
+
+import "errors"
+
+var ErrMismatchedHashAndPassword = errors.New("hashedPassword is not the hash of the given password")
+
+// Hasher hashes and verifies passwords.
+type Hasher interface {
+	// Hash returns the hash of the password.
+	Hash(password string) (string, error)
+	// Verify compares the password with the hash in constant time.
+	// If they don't match, ErrMismatchedHashAndPassword returns.
+	Verify(password, hash string) error
+}
@@ -0,0 +1,19 @@
+package passhash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBcrypt(t *testing.T) {
+	password := "super secret password"
+	h := NewBcrypt(4)
+	hash, err := h.Hash(password)
+	require.NoError(t, err)
+	t.Logf("hash = %q", hash)
+
+	assert.NoError(t, h.Verify(password, hash))
+	assert.ErrorIs(t, h.Verify("Another secret password", hash), ErrMismatchedHashAndPassword)
+}
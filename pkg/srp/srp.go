@@ -0,0 +1,264 @@
+package srp
+
+// Package srp implements SRP-6a password authenticated key exchange (RFC 2945, RFC 5054)
+// with 2048-bit group from RFC 5054 and SHA-256 hash function.
+//
+// Registration: the client generates a random salt, computes the verifier v = g^x mod N,
+// where x = H(salt | argon2id(I | ":" | P, salt)), and sends the salt and the verifier to the server.
+// The password itself never leaves the client, and x is derived by the memory-hard function, so that
+// the leaked verifier is as expensive to brute-force as a password hash.
+//
+// Login:
+//
+//	Client                                      Server
+//	A = g^a                  --- I, A --->
+//	                         <-- s, B ----      B = k*v + g^b
+//	M1 = H(H(N) xor H(g) | H(I) | s | A | B | K)
+//	                         --- M1 ----->      verifies M1
+//	verifies M2              <-- M2 ------      M2 = H(A | M1 | K)
+//
+// where K = H(S) is the session key known only to both parties.
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// SaltSize is the size of the generated salt in bytes.
+	SaltSize = 16
+	// secretSize is the size of the private ephemeral values a and b in bytes.
+	secretSize = 32
+
+	// The parameters of argon2id derivation of x. They are the part of the protocol: changing them
+	// invalidates all the stored verifiers.
+	argon2Memory  = 64 * 1024
+	argon2Time    = 3
+	argon2Threads = 4
+	argon2KeyLen  = 32
+)
+
+var (
+	ErrInvalidPublicValue = errors.New("srp: invalid public ephemeral value")
+	ErrProofMismatch      = errors.New("srp: proof mismatch")
+)
+
+// 2048-bit group from RFC 5054, Appendix A.
+var (
+	groupN, _ = new(big.Int).SetString(""+
+		"AC6BDB41324A9A9BF166DE5E1389582FAF72B6651987EE07FC3192943DB56050"+
+		"A37329CBB4A099ED8193E0757767A13DD52312AB4B03310DCD7F48A9DA04FD50"+
+		"E8083969EDB767B0CF6095179A163AB3661A05FBD5FAAAE82918A9962F0B93B8"+
+		"55F97993EC975EEAA80D740ADBF4FF747359D041D5C33EA71D281E446B14773B"+
+		"CA97B43A23FB801676BD207A436C6481F1D2B9078717461A5B9D32E688F87748"+
+		"544523B524B0D57D5EA77A2775D2ECFA032CFBDBF52FB3786160279004E57AE6"+
+		"AF874E7303CE53299CCC041C7BC308D82A5698F3A8D0C38271AE35F8E9DBFBB6"+
+		"94B5C803D89F7AE435DE236D525F54759B65E372FCD68EF20FA7111F9E4AFF73", 16)
+	groupG = big.NewInt(2)
+	// groupK is the multiplier parameter k = H(N | PAD(g)).
+	groupK = hashInt(groupN.Bytes(), pad(groupG))
+)
+
+// NewSalt generates a random salt for the verifier.
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("srp: newSalt: %w", err)
+	}
+	return salt, nil
+}
+
+// ComputeVerifier returns the password verifier v = g^x mod N to be stored on the server.
+func ComputeVerifier(identity, password string, salt []byte) []byte {
+	x := privateKey(identity, password, salt)
+	return new(big.Int).Exp(groupG, x, groupN).Bytes()
+}
+
+// Client is the client side of the SRP handshake. It must not be reused.
+type Client struct {
+	identity string
+	password string
+	a        *big.Int
+	A        *big.Int
+	m1       []byte
+	key      []byte
+}
+
+// NewClient generates the client's ephemeral values for the handshake.
+func NewClient(identity, password string) (*Client, error) {
+	a, err := randomInt()
+	if err != nil {
+		return nil, fmt.Errorf("srp: newClient: %w", err)
+	}
+	return &Client{
+		identity: identity,
+		password: password,
+		a:        a,
+		A:        new(big.Int).Exp(groupG, a, groupN),
+	}, nil
+}
+
+// PublicValue returns the public ephemeral value A to be sent to the server.
+func (c *Client) PublicValue() []byte {
+	return c.A.Bytes()
+}
+
+// ProcessChallenge computes the session key and the client's proof M1 from the salt
+// and the server's public ephemeral value B.
+func (c *Client) ProcessChallenge(salt, serverPublic []byte) ([]byte, error) {
+	B := new(big.Int).SetBytes(serverPublic)
+	if new(big.Int).Mod(B, groupN).Sign() == 0 {
+		return nil, ErrInvalidPublicValue
+	}
+	u := hashInt(pad(c.A), pad(B))
+	if u.Sign() == 0 {
+		return nil, ErrInvalidPublicValue
+	}
+	x := privateKey(c.identity, c.password, salt)
+
+	// S = (B - k * g^x) ^ (a + u * x) mod N
+	kgx := new(big.Int).Mul(groupK, new(big.Int).Exp(groupG, x, groupN))
+	base := new(big.Int).Sub(B, kgx)
+	base.Mod(base, groupN)
+	exp := new(big.Int).Add(c.a, new(big.Int).Mul(u, x))
+	S := new(big.Int).Exp(base, exp, groupN)
+
+	c.key = hash(S.Bytes())
+	c.m1 = clientProof(c.identity, salt, c.A, B, c.key)
+	return c.m1, nil
+}
+
+// VerifyServerProof checks the server's proof M2. It ensures that the server knows the verifier.
+func (c *Client) VerifyServerProof(m2 []byte) error {
+	if c.m1 == nil {
+		return ErrProofMismatch
+	}
+	expected := hash(c.A.Bytes(), c.m1, c.key)
+	if subtle.ConstantTimeCompare(expected, m2) != 1 {
+		return ErrProofMismatch
+	}
+	return nil
+}
+
+// Key returns the shared session key. It's available after ProcessChallenge.
+func (c *Client) Key() []byte {
+	return c.key
+}
+
+// Server is the server side of the SRP handshake.
+type Server struct {
+	identity string
+	salt     []byte
+	verifier *big.Int
+	b        *big.Int
+	B        *big.Int
+}
+
+// NewServer generates the server's ephemeral values for the handshake with the user's verifier.
+func NewServer(identity string, salt, verifier []byte) (*Server, error) {
+	b, err := randomInt()
+	if err != nil {
+		return nil, fmt.Errorf("srp: newServer: %w", err)
+	}
+	return RestoreServer(identity, salt, verifier, b.Bytes()), nil
+}
+
+// RestoreServer restores the server side of the handshake from the private ephemeral value
+// stored between the handshake rounds.
+func RestoreServer(identity string, salt, verifier, secret []byte) *Server {
+	v := new(big.Int).SetBytes(verifier)
+	b := new(big.Int).SetBytes(secret)
+	// B = k*v + g^b mod N
+	B := new(big.Int).Mul(groupK, v)
+	B.Add(B, new(big.Int).Exp(groupG, b, groupN))
+	B.Mod(B, groupN)
+	return &Server{
+		identity: identity,
+		salt:     salt,
+		verifier: v,
+		b:        b,
+		B:        B,
+	}
+}
+
+// PublicValue returns the public ephemeral value B to be sent to the client.
+func (s *Server) PublicValue() []byte {
+	return s.B.Bytes()
+}
+
+// Secret returns the private ephemeral value b. It must be kept secret between the handshake rounds.
+func (s *Server) Secret() []byte {
+	return s.b.Bytes()
+}
+
+// VerifyClientProof checks the client's proof M1 for the client's public ephemeral value A.
+// If the proof is valid, the server's proof M2 and the shared session key return.
+func (s *Server) VerifyClientProof(clientPublic, m1 []byte) ([]byte, []byte, error) {
+	A := new(big.Int).SetBytes(clientPublic)
+	if new(big.Int).Mod(A, groupN).Sign() == 0 {
+		return nil, nil, ErrInvalidPublicValue
+	}
+	u := hashInt(pad(A), pad(s.B))
+	if u.Sign() == 0 {
+		return nil, nil, ErrInvalidPublicValue
+	}
+
+	// S = (A * v^u) ^ b mod N
+	base := new(big.Int).Mul(A, new(big.Int).Exp(s.verifier, u, groupN))
+	base.Mod(base, groupN)
+	S := new(big.Int).Exp(base, s.b, groupN)
+	key := hash(S.Bytes())
+
+	expected := clientProof(s.identity, s.salt, A, s.B, key)
+	if subtle.ConstantTimeCompare(expected, m1) != 1 {
+		return nil, nil, ErrProofMismatch
+	}
+	return hash(A.Bytes(), m1, key), key, nil
+}
+
+// privateKey returns x = H(salt | argon2id(I | ":" | P, salt)).
+func privateKey(identity, password string, salt []byte) *big.Int {
+	secret := []byte(identity + ":" + password)
+	return hashInt(salt, argon2.IDKey(secret, salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen))
+}
+
+// clientProof returns M1 = H(H(N) xor H(g) | H(I) | s | A | B | K).
+func clientProof(identity string, salt []byte, A, B *big.Int, key []byte) []byte {
+	hn := hash(groupN.Bytes())
+	hg := hash(groupG.Bytes())
+	for i := range hn {
+		hn[i] ^= hg[i]
+	}
+	return hash(hn, hash([]byte(identity)), salt, A.Bytes(), B.Bytes(), key)
+}
+
+func hash(data ...[]byte) []byte {
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+func hashInt(data ...[]byte) *big.Int {
+	return new(big.Int).SetBytes(hash(data...))
+}
+
+// pad returns the big-endian representation of the value left-padded with zeroes to the length of N.
+func pad(x *big.Int) []byte {
+	return x.FillBytes(make([]byte, len(groupN.Bytes())))
+}
+
+func randomInt() (*big.Int, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
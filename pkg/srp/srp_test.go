@@ -0,0 +1,66 @@
+package srp
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandshake(t *testing.T) {
+	const (
+		identity = "user@example.com"
+		password = "super secret password"
+	)
+	salt, err := NewSalt()
+	require.NoError(t, err)
+	verifier := ComputeVerifier(identity, password, salt)
+
+	t.Run("Valid password", func(t *testing.T) {
+		client, err := NewClient(identity, password)
+		require.NoError(t, err)
+		server, err := NewServer(identity, salt, verifier)
+		require.NoError(t, err)
+		// the server state is restored between the rounds
+		server = RestoreServer(identity, salt, verifier, server.Secret())
+
+		m1, err := client.ProcessChallenge(salt, server.PublicValue())
+		require.NoError(t, err)
+		m2, key, err := server.VerifyClientProof(client.PublicValue(), m1)
+		require.NoError(t, err)
+		assert.NoError(t, client.VerifyServerProof(m2))
+		assert.Equal(t, client.Key(), key)
+	})
+	t.Run("Wrong password", func(t *testing.T) {
+		client, err := NewClient(identity, "wrong password")
+		require.NoError(t, err)
+		server, err := NewServer(identity, salt, verifier)
+		require.NoError(t, err)
+
+		m1, err := client.ProcessChallenge(salt, server.PublicValue())
+		require.NoError(t, err)
+		_, _, err = server.VerifyClientProof(client.PublicValue(), m1)
+		assert.ErrorIs(t, err, ErrProofMismatch)
+	})
+	t.Run("Zero public value", func(t *testing.T) {
+		server, err := NewServer(identity, salt, verifier)
+		require.NoError(t, err)
+		_, _, err = server.VerifyClientProof(groupN.Bytes(), []byte("proof"))
+		assert.ErrorIs(t, err, ErrInvalidPublicValue)
+
+		client, err := NewClient(identity, password)
+		require.NoError(t, err)
+		_, err = client.ProcessChallenge(salt, big.NewInt(0).Bytes())
+		assert.ErrorIs(t, err, ErrInvalidPublicValue)
+	})
+	t.Run("Forged server proof", func(t *testing.T) {
+		client, err := NewClient(identity, password)
+		require.NoError(t, err)
+		server, err := NewServer(identity, salt, verifier)
+		require.NoError(t, err)
+		_, err = client.ProcessChallenge(salt, server.PublicValue())
+		require.NoError(t, err)
+		assert.ErrorIs(t, client.VerifyServerProof([]byte("forged")), ErrProofMismatch)
+	})
+}
@@ -0,0 +1,249 @@
+package totp
+
+// Package totp implements HOTP (RFC 4226) and TOTP (RFC 6238) one-time passwords.
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Algorithm is the HMAC hash function used for code generation.
+type Algorithm string
+
+const (
+	AlgorithmSHA1   Algorithm = "SHA1"
+	AlgorithmSHA256 Algorithm = "SHA256"
+	AlgorithmSHA512 Algorithm = "SHA512"
+)
+
+const (
+	DefaultDigits = 6
+	DefaultPeriod = 30 * time.Second
+
+	// DefaultSecretSize is the size of the generated secret in bytes (160 bits, as recommended by RFC 4226).
+	DefaultSecretSize = 20
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown hash algorithm")
+	ErrInvalidDigits    = errors.New("number of digits must be from 6 to 8")
+	ErrInvalidPeriod    = errors.New("period must be at least one second")
+	ErrInvalidURI       = errors.New("invalid otpauth URI")
+)
+
+// b32 is the base32 encoding used in otpauth:// URIs (without padding).
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Key contains the TOTP secret and the parameters of code generation.
+type Key struct {
+	Secret    []byte
+	Algorithm Algorithm
+	Digits    int
+	Period    time.Duration
+	// Issuer and Account are used only for the key URI.
+	Issuer  string
+	Account string
+}
+
+// NewKey generates a new key with a random secret and the default parameters.
+func NewKey(issuer, account string) (Key, error) {
+	secret := make([]byte, DefaultSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, fmt.Errorf("totp: newKey: %w", err)
+	}
+	return Key{
+		Secret:    secret,
+		Algorithm: AlgorithmSHA1,
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+		Issuer:    issuer,
+		Account:   account,
+	}, nil
+}
+
+// Code returns the TOTP code for the time provided.
+func (k Key) Code(t time.Time) (string, error) {
+	if k.Period < time.Second {
+		return "", ErrInvalidPeriod
+	}
+	return HOTP(k.Secret, k.counter(t), k.Digits, k.Algorithm)
+}
+
+// Remaining returns the time left until the code for the time provided expires.
+func (k Key) Remaining(t time.Time) time.Duration {
+	if k.Period < time.Second {
+		return 0
+	}
+	return k.Period - time.Duration(t.UnixNano())%k.Period
+}
+
+// Validate checks the code for the time provided. The codes of skew periods before and after
+// the current one are accepted too to tolerate clock drift between the devices.
+func (k Key) Validate(code string, t time.Time, skew int) bool {
+	_, ok := k.Match(code, t, skew)
+	return ok
+}
+
+// Match checks the code like Validate and returns the number of the time step the code belongs to.
+// The verifier must store the step of the accepted code and reject the codes of the same and
+// earlier steps, so that each code is accepted only once (RFC 6238, section 5.2).
+func (k Key) Match(code string, t time.Time, skew int) (uint64, bool) {
+	if k.Period < time.Second {
+		return 0, false
+	}
+	counter := k.counter(t)
+	for i := -skew; i <= skew; i++ {
+		if int64(counter)+int64(i) < 0 {
+			continue
+		}
+		step := uint64(int64(counter) + int64(i))
+		expected, err := HOTP(k.Secret, step, k.Digits, k.Algorithm)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// EncodedSecret returns the secret encoded in base32 as used in authenticator applications.
+func (k Key) EncodedSecret() string {
+	return b32.EncodeToString(k.Secret)
+}
+
+// URI returns otpauth:// URI of the key that can be imported into authenticator applications.
+func (k Key) URI() string {
+	label := url.PathEscape(k.Account)
+	if k.Issuer != "" {
+		label = url.PathEscape(k.Issuer) + ":" + label
+	}
+	q := url.Values{}
+	q.Set("secret", k.EncodedSecret())
+	if k.Issuer != "" {
+		q.Set("issuer", k.Issuer)
+	}
+	q.Set("algorithm", string(k.Algorithm))
+	q.Set("digits", strconv.Itoa(k.Digits))
+	q.Set("period", strconv.Itoa(int(k.Period/time.Second)))
+
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// ParseURI parses otpauth://totp/ URI exported by authenticator applications or web sites.
+// The parameters missing in the URI are set to the defaults. The issuer parameter takes
+// precedence over the issuer prefix of the label.
+func ParseURI(uri string) (Key, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return Key{}, fmt.Errorf("totp: %w: %s", ErrInvalidURI, err)
+	}
+	if u.Scheme != "otpauth" {
+		return Key{}, fmt.Errorf("totp: %w: scheme must be otpauth", ErrInvalidURI)
+	}
+	if !strings.EqualFold(u.Host, "totp") {
+		return Key{}, fmt.Errorf("totp: %w: unsupported type %q", ErrInvalidURI, u.Host)
+	}
+
+	key := Key{
+		Algorithm: AlgorithmSHA1,
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+	}
+	label := strings.TrimPrefix(u.Path, "/")
+	if i := strings.Index(label, ":"); i >= 0 {
+		key.Issuer, label = strings.TrimSpace(label[:i]), label[i+1:]
+	}
+	key.Account = strings.TrimSpace(label)
+
+	q := u.Query()
+	if key.Secret, err = DecodeSecret(q.Get("secret")); err != nil || len(key.Secret) == 0 {
+		return Key{}, fmt.Errorf("totp: %w: invalid secret", ErrInvalidURI)
+	}
+	if issuer := q.Get("issuer"); issuer != "" {
+		key.Issuer = issuer
+	}
+	if alg := q.Get("algorithm"); alg != "" {
+		key.Algorithm = Algorithm(strings.ToUpper(alg))
+		if _, err := key.Algorithm.hash(); err != nil {
+			return Key{}, err
+		}
+	}
+	if digits := q.Get("digits"); digits != "" {
+		if key.Digits, err = strconv.Atoi(digits); err != nil || key.Digits < 6 || key.Digits > 8 {
+			return Key{}, fmt.Errorf("totp: %w", ErrInvalidDigits)
+		}
+	}
+	if period := q.Get("period"); period != "" {
+		seconds, err := strconv.Atoi(period)
+		if err != nil || seconds < 1 {
+			return Key{}, fmt.Errorf("totp: %w", ErrInvalidPeriod)
+		}
+		key.Period = time.Duration(seconds) * time.Second
+	}
+
+	return key, nil
+}
+
+// DecodeSecret decodes base32 secret ignoring the case, spaces and padding.
+func DecodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	return b32.DecodeString(strings.TrimRight(secret, "="))
+}
+
+// HOTP returns the HMAC-based one-time password for the counter provided (RFC 4226).
+func HOTP(secret []byte, counter uint64, digits int, alg Algorithm) (string, error) {
+	if digits < 6 || digits > 8 {
+		return "", ErrInvalidDigits
+	}
+	hashFn, err := alg.hash()
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(hashFn, secret)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, bin%mod), nil
+}
+
+// counter returns the number of the time step for the time provided.
+func (k Key) counter(t time.Time) uint64 {
+	return uint64(t.Unix() / int64(k.Period/time.Second))
+}
+
+func (a Algorithm) hash() (func() hash.Hash, error) {
+	switch Algorithm(strings.ToUpper(string(a))) {
+	case AlgorithmSHA1, "":
+		return sha1.New, nil
+	case AlgorithmSHA256:
+		return sha256.New, nil
+	case AlgorithmSHA512:
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("totp: %w: %s", ErrUnknownAlgorithm, a)
+}
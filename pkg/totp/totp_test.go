@@ -0,0 +1,99 @@
+package totp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test vectors from RFC 6238, Appendix B.
+func TestCode(t *testing.T) {
+	secrets := map[Algorithm][]byte{
+		AlgorithmSHA1:   []byte("12345678901234567890"),
+		AlgorithmSHA256: []byte("12345678901234567890123456789012"),
+		AlgorithmSHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	tests := []struct {
+		unix int64
+		alg  Algorithm
+		code string
+	}{
+		{59, AlgorithmSHA1, "94287082"},
+		{59, AlgorithmSHA256, "46119246"},
+		{59, AlgorithmSHA512, "90693936"},
+		{1111111109, AlgorithmSHA1, "07081804"},
+		{1111111109, AlgorithmSHA256, "68084774"},
+		{1111111109, AlgorithmSHA512, "25091201"},
+		{1234567890, AlgorithmSHA1, "89005924"},
+		{2000000000, AlgorithmSHA256, "90698825"},
+		{20000000000, AlgorithmSHA512, "47863826"},
+	}
+	for _, tc := range tests {
+		key := Key{Secret: secrets[tc.alg], Algorithm: tc.alg, Digits: 8, Period: DefaultPeriod}
+		code, err := key.Code(time.Unix(tc.unix, 0))
+		require.NoError(t, err)
+		assert.Equal(t, tc.code, code, "%s at %d", tc.alg, tc.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	key, err := NewKey("GophKeeper", "gopher@example.com")
+	require.NoError(t, err)
+	now := time.Now()
+	code, err := key.Code(now)
+	require.NoError(t, err)
+	assert.Len(t, code, DefaultDigits)
+	assert.True(t, key.Validate(code, now, 0))
+	assert.True(t, key.Validate(code, now.Add(DefaultPeriod), 1))
+	assert.False(t, key.Validate(code, now.Add(3*DefaultPeriod), 1))
+
+	step, ok := key.Match(code, now.Add(DefaultPeriod), 1)
+	assert.True(t, ok)
+	assert.Equal(t, key.counter(now), step, "the step of the code, not of the time of the check")
+
+	secret, err := DecodeSecret(key.EncodedSecret())
+	require.NoError(t, err)
+	assert.Equal(t, key.Secret, secret)
+	assert.Contains(t, key.URI(), "otpauth://totp/GophKeeper:gopher@example.com?")
+}
+
+func TestParseURI(t *testing.T) {
+	key, err := ParseURI("otpauth://totp/Example:alice@google.com?secret=JBSWY3DPEHPK3PXP&issuer=Example")
+	require.NoError(t, err)
+	assert.Equal(t, "Example", key.Issuer)
+	assert.Equal(t, "alice@google.com", key.Account)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", key.EncodedSecret())
+	assert.Equal(t, AlgorithmSHA1, key.Algorithm)
+	assert.Equal(t, DefaultDigits, key.Digits)
+	assert.Equal(t, DefaultPeriod, key.Period)
+
+	key, err = ParseURI("otpauth://totp/ACME%20Co:john.doe@email.com?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ" +
+		"&issuer=ACME%20Co&algorithm=sha256&digits=8&period=60")
+	require.NoError(t, err)
+	assert.Equal(t, "ACME Co", key.Issuer)
+	assert.Equal(t, "john.doe@email.com", key.Account)
+	assert.Equal(t, AlgorithmSHA256, key.Algorithm)
+	assert.Equal(t, 8, key.Digits)
+	assert.Equal(t, time.Minute, key.Period)
+
+	generated, err := NewKey("GophKeeper", "gopher@example.com")
+	require.NoError(t, err)
+	parsed, err := ParseURI(generated.URI())
+	require.NoError(t, err)
+	assert.Equal(t, generated, parsed)
+
+	for _, uri := range []string{
+		"https://totp/Example:alice?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://hotp/Example:alice?secret=JBSWY3DPEHPK3PXP&counter=0",
+		"otpauth://totp/Example:alice",
+		"otpauth://totp/Example:alice?secret=!!!",
+		"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+		"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&digits=10",
+		"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&period=0",
+	} {
+		_, err := ParseURI(uri)
+		assert.Error(t, err, uri)
+	}
+}
@@ -23,25 +23,121 @@ const _ = grpc.SupportPackageIsVersion7
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type GophkeeperClient interface {
-	// SignUp registers a new user and creates a new user session.
-	SignUp(ctx context.Context, in *SignInData, opts ...grpc.CallOption) (*UserAuth, error)
-	// LogIn creates a new session for the user provided.
-	LogIn(ctx context.Context, in *SignInData, opts ...grpc.CallOption) (*UserAuth, error)
+	// SRPSignUp registers a new user with SRP-6a password verifier and creates a new user session.
+	// The password itself is never sent to the server.
+	SRPSignUp(ctx context.Context, in *SRPSignUpRequest, opts ...grpc.CallOption) (*UserAuth, error)
+	// SRPLogInStart starts SRP-6a handshake: the client sends its public ephemeral value A,
+	// the server responds with the salt and its public ephemeral value B.
+	// The handshake is finished either by SRPLogInFinish or by the request that requires
+	// the password confirmation (see SRPProof).
+	// If there is no such user or the user has no SRP verifier (the accounts registered before
+	// SRP support), the fake challenge is returned and the proof is rejected as a wrong password,
+	// so the registered emails can't be enumerated.
+	SRPLogInStart(ctx context.Context, in *SRPLogInStartRequest, opts ...grpc.CallOption) (*SRPChallenge, error)
+	// SRPLogInFinish verifies the client's proof M1 and creates a new session for the user.
+	// The server's proof M2 is returned together with the tokens, so the client can make
+	// sure that the server knows the verifier. If the user has two-factor authentication
+	// enabled, mfa_challenge is set instead of the tokens and must be exchanged for the tokens by LogInMFA.
+	SRPLogInFinish(ctx context.Context, in *SRPLogInFinishRequest, opts ...grpc.CallOption) (*SRPLogInResult, error)
+	// LogInMFA completes the login (or the undelete) of the user with two-factor authentication enabled.
+	// The code may be either TOTP code or one of the recovery codes.
+	LogInMFA(ctx context.Context, in *LogInMFARequest, opts ...grpc.CallOption) (*UserAuth, error)
 	// GetNewTokens generates a new AccessToken + RefreshToken pair.
 	// If refresh token is expired, the session ends.
 	GetNewTokens(ctx context.Context, in *RefreshToken, opts ...grpc.CallOption) (*UserAuth, error)
 	// LogOut ends current user session.
 	LogOut(ctx context.Context, in *RefreshToken, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ChangePassword verifies the proof of the old password of the user, stores the new SRP-6a verifier
+	// and ends all other active sessions of the user. The session of the access token the call
+	// is authenticated with stays alive.
+	ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// DeleteUser marks the user as deleted and ends all user sessions. The user's password
+	// must be confirmed by SRPProof. The user's data is permanently erased after the grace period.
+	DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// UndeleteUser restores the user deleted less than the grace period ago
+	// and creates a new session for the user. The password is confirmed by SRPProof of the handshake
+	// started with undelete flag. If the user has two-factor authentication enabled,
+	// only mfa_challenge is returned: the account is restored by LogInMFA.
+	UndeleteUser(ctx context.Context, in *UndeleteUserRequest, opts ...grpc.CallOption) (*UserAuth, error)
+	// VerifyEmail marks the user's email as verified. The token is sent to the email at the registration.
+	VerifyEmail(ctx context.Context, in *VerifyEmailRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// RequestPasswordReset sends the password reset token to the email provided. The response
+	// is the same whether the user with such email exists or not.
+	RequestPasswordReset(ctx context.Context, in *RequestPasswordResetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ResetPassword replaces the SRP-6a verifier of the user the token is issued for
+	// and ends all the user sessions.
+	ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// EnrollTOTP starts the enrollment of the TOTP second factor. The password must be confirmed
+	// (by SRPProof). It returns otpauth:// URI for authenticator applications and the recovery codes.
+	// Two-factor authentication is enabled after the enrollment is confirmed by ConfirmTOTP.
+	EnrollTOTP(ctx context.Context, in *EnrollTOTPRequest, opts ...grpc.CallOption) (*TOTPEnrollment, error)
+	// ConfirmTOTP enables two-factor authentication if the TOTP code is valid.
+	ConfirmTOTP(ctx context.Context, in *ConfirmTOTPRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// DisableTOTP disables two-factor authentication. The password (by SRPProof) and the one-time code
+	// (or a recovery code) must be confirmed.
+	DisableTOTP(ctx context.Context, in *DisableTOTPRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ListSessions returns all active sessions of the user.
+	ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...grpc.CallOption) (*SessionList, error)
+	// LogoutSession ends the session of the user with ID provided.
+	LogoutSession(ctx context.Context, in *LogoutSessionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// LogoutAllSessions ends all sessions of the user including the current one.
+	LogoutAllSessions(ctx context.Context, in *LogoutAllSessionsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	// PublishLocalChanges applies the changes to the storage on the server.
 	// This method is allowed only if the version of user's data on the client side is equal
-	// to the version number on the server. Otherwise the error is returned and the client
-	// must first update data from the server.
-	PublishLocalChanges(ctx context.Context, in *PublishLocalChangesRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// to the version number on the server. Otherwise FailedPrecondition with DATA_VERSION_STALE
+	// reason is returned and the client must first update data from the server.
+	// The events are applied in one transaction and the outcome of each event is returned
+	// together with the new data version. A failed event doesn't prevent the others from being applied.
+	PublishLocalChanges(ctx context.Context, in *PublishLocalChangesRequest, opts ...grpc.CallOption) (*PublishLocalChangesResponse, error)
 	// WhatsNew compares provided Data Version with such one stored on the server. If they are the same,
-	// nil error is returned. Otherwise, the "PermissionDenied" code and the error "out of date" is returned.
+	// nil error is returned. Otherwise, FailedPrecondition with DATA_VERSION_STALE reason is returned.
 	WhatsNew(ctx context.Context, in *WhatsNewRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Watch streams the notifications about the changes of the user's data. The first event
+	// carries the current data version, the next ones are sent after each change. If there is
+	// no change for a while, a heartbeat is sent, so the client can detect a broken stream.
+	// The stream is closed with Unauthenticated status when the session is logged out.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Gophkeeper_WatchClient, error)
 	// DownloadUserData analyses existing versions of the local items and downloads latest updates of the user's data from the server.
 	DownloadUserData(ctx context.Context, in *DownloadUserDataRequest, opts ...grpc.CallOption) (*UserData, error)
+	// GetChangesSince returns the items changed after the data version provided together with
+	// the current data version. If the server's change log no longer covers that version,
+	// FailedPrecondition with CHANGE_LOG_COMPACTED reason is returned and the client must
+	// use DownloadUserData instead.
+	GetChangesSince(ctx context.Context, in *GetChangesSinceRequest, opts ...grpc.CallOption) (*UserData, error)
+	// ListTrash returns the deleted items of the user with their payload. The deleted items
+	// are permanently erased after the retention period configured on the server.
+	ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*TrashList, error)
+	// RestoreItem restores the deleted item and returns it with the new version.
+	// NotFound is returned if there is no such item in the trash.
+	RestoreItem(ctx context.Context, in *RestoreItemRequest, opts ...grpc.CallOption) (*RestoreItemResponse, error)
+	// PurgeItem permanently erases the deleted item.
+	// NotFound is returned if there is no such item in the trash.
+	PurgeItem(ctx context.Context, in *PurgeItemRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ListItemVersions returns the versions of the item kept in the history, the latest first.
+	// NotFound is returned if there is no history of the item.
+	ListItemVersions(ctx context.Context, in *ListItemVersionsRequest, opts ...grpc.CallOption) (*ItemVersionList, error)
+	// GetItemVersion returns the version of the item from the history with its payload.
+	// NotFound is returned if there is no such version.
+	GetItemVersion(ctx context.Context, in *GetItemVersionRequest, opts ...grpc.CallOption) (*Item, error)
+	// RestoreVault brings all the items back to the state they had in the data version provided.
+	// The restore is recorded as a new data version, so it's delivered to the other devices as
+	// ordinary changes. The user password must be confirmed by SRPProof: PermissionDenied is returned if it's wrong.
+	// If the item history no longer covers the data version, FailedPrecondition with
+	// SNAPSHOT_NOT_RETAINED reason is returned.
+	RestoreVault(ctx context.Context, in *RestoreVaultRequest, opts ...grpc.CallOption) (*RestoreVaultResponse, error)
+	// UploadBlob receives the content of a blob item in chunks. The first chunk must carry
+	// the hash and the size of the whole content. Each chunk carries its offset and CRC-32C
+	// checksum: DataLoss with BLOB_CHECKSUM_MISMATCH reason is returned if the chunk or the whole
+	// content is corrupted. The upload may be interrupted and resumed from the offset returned
+	// by GetBlobUpload; if the offset of the chunk doesn't match the number of bytes received,
+	// FailedPrecondition with BLOB_OFFSET_MISMATCH reason is returned.
+	// The item referring to the content can be published only after the upload is complete.
+	UploadBlob(ctx context.Context, opts ...grpc.CallOption) (Gophkeeper_UploadBlobClient, error)
+	// GetBlobUpload returns the state of the upload of the content with the hash provided.
+	// NotFound is returned if the upload isn't started.
+	GetBlobUpload(ctx context.Context, in *GetBlobUploadRequest, opts ...grpc.CallOption) (*BlobUploadStatus, error)
+	// DownloadBlob sends the uploaded content of a blob item in chunks starting at the offset provided.
+	DownloadBlob(ctx context.Context, in *DownloadBlobRequest, opts ...grpc.CallOption) (Gophkeeper_DownloadBlobClient, error)
 }
 
 type gophkeeperClient struct {
@@ -52,18 +148,36 @@ func NewGophkeeperClient(cc grpc.ClientConnInterface) GophkeeperClient {
 	return &gophkeeperClient{cc}
 }
 
-func (c *gophkeeperClient) SignUp(ctx context.Context, in *SignInData, opts ...grpc.CallOption) (*UserAuth, error) {
+func (c *gophkeeperClient) SRPSignUp(ctx context.Context, in *SRPSignUpRequest, opts ...grpc.CallOption) (*UserAuth, error) {
 	out := new(UserAuth)
-	err := c.cc.Invoke(ctx, "/proto.gophkeeper/SignUp", in, out, opts...)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/SRPSignUp", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *gophkeeperClient) LogIn(ctx context.Context, in *SignInData, opts ...grpc.CallOption) (*UserAuth, error) {
+func (c *gophkeeperClient) SRPLogInStart(ctx context.Context, in *SRPLogInStartRequest, opts ...grpc.CallOption) (*SRPChallenge, error) {
+	out := new(SRPChallenge)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/SRPLogInStart", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) SRPLogInFinish(ctx context.Context, in *SRPLogInFinishRequest, opts ...grpc.CallOption) (*SRPLogInResult, error) {
+	out := new(SRPLogInResult)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/SRPLogInFinish", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) LogInMFA(ctx context.Context, in *LogInMFARequest, opts ...grpc.CallOption) (*UserAuth, error) {
 	out := new(UserAuth)
-	err := c.cc.Invoke(ctx, "/proto.gophkeeper/LogIn", in, out, opts...)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/LogInMFA", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -88,8 +202,116 @@ func (c *gophkeeperClient) LogOut(ctx context.Context, in *RefreshToken, opts ..
 	return out, nil
 }
 
-func (c *gophkeeperClient) PublishLocalChanges(ctx context.Context, in *PublishLocalChangesRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+func (c *gophkeeperClient) ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/ChangePassword", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/DeleteUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) UndeleteUser(ctx context.Context, in *UndeleteUserRequest, opts ...grpc.CallOption) (*UserAuth, error) {
+	out := new(UserAuth)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/UndeleteUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) VerifyEmail(ctx context.Context, in *VerifyEmailRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/VerifyEmail", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) RequestPasswordReset(ctx context.Context, in *RequestPasswordResetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/RequestPasswordReset", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/ResetPassword", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) EnrollTOTP(ctx context.Context, in *EnrollTOTPRequest, opts ...grpc.CallOption) (*TOTPEnrollment, error) {
+	out := new(TOTPEnrollment)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/EnrollTOTP", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) ConfirmTOTP(ctx context.Context, in *ConfirmTOTPRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/ConfirmTOTP", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) DisableTOTP(ctx context.Context, in *DisableTOTPRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/DisableTOTP", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...grpc.CallOption) (*SessionList, error) {
+	out := new(SessionList)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/ListSessions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) LogoutSession(ctx context.Context, in *LogoutSessionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/LogoutSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) LogoutAllSessions(ctx context.Context, in *LogoutAllSessionsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/LogoutAllSessions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) PublishLocalChanges(ctx context.Context, in *PublishLocalChangesRequest, opts ...grpc.CallOption) (*PublishLocalChangesResponse, error) {
+	out := new(PublishLocalChangesResponse)
 	err := c.cc.Invoke(ctx, "/proto.gophkeeper/PublishLocalChanges", in, out, opts...)
 	if err != nil {
 		return nil, err
@@ -106,6 +328,38 @@ func (c *gophkeeperClient) WhatsNew(ctx context.Context, in *WhatsNewRequest, op
 	return out, nil
 }
 
+func (c *gophkeeperClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Gophkeeper_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Gophkeeper_ServiceDesc.Streams[0], "/proto.gophkeeper/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gophkeeperWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Gophkeeper_WatchClient interface {
+	Recv() (*WatchEvent, error)
+	grpc.ClientStream
+}
+
+type gophkeeperWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *gophkeeperWatchClient) Recv() (*WatchEvent, error) {
+	m := new(WatchEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *gophkeeperClient) DownloadUserData(ctx context.Context, in *DownloadUserDataRequest, opts ...grpc.CallOption) (*UserData, error) {
 	out := new(UserData)
 	err := c.cc.Invoke(ctx, "/proto.gophkeeper/DownloadUserData", in, out, opts...)
@@ -115,29 +369,263 @@ func (c *gophkeeperClient) DownloadUserData(ctx context.Context, in *DownloadUse
 	return out, nil
 }
 
+func (c *gophkeeperClient) GetChangesSince(ctx context.Context, in *GetChangesSinceRequest, opts ...grpc.CallOption) (*UserData, error) {
+	out := new(UserData)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/GetChangesSince", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*TrashList, error) {
+	out := new(TrashList)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/ListTrash", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) RestoreItem(ctx context.Context, in *RestoreItemRequest, opts ...grpc.CallOption) (*RestoreItemResponse, error) {
+	out := new(RestoreItemResponse)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/RestoreItem", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) PurgeItem(ctx context.Context, in *PurgeItemRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/PurgeItem", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) ListItemVersions(ctx context.Context, in *ListItemVersionsRequest, opts ...grpc.CallOption) (*ItemVersionList, error) {
+	out := new(ItemVersionList)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/ListItemVersions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) GetItemVersion(ctx context.Context, in *GetItemVersionRequest, opts ...grpc.CallOption) (*Item, error) {
+	out := new(Item)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/GetItemVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) RestoreVault(ctx context.Context, in *RestoreVaultRequest, opts ...grpc.CallOption) (*RestoreVaultResponse, error) {
+	out := new(RestoreVaultResponse)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/RestoreVault", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) UploadBlob(ctx context.Context, opts ...grpc.CallOption) (Gophkeeper_UploadBlobClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Gophkeeper_ServiceDesc.Streams[1], "/proto.gophkeeper/UploadBlob", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gophkeeperUploadBlobClient{stream}
+	return x, nil
+}
+
+type Gophkeeper_UploadBlobClient interface {
+	Send(*BlobChunk) error
+	CloseAndRecv() (*BlobUploadStatus, error)
+	grpc.ClientStream
+}
+
+type gophkeeperUploadBlobClient struct {
+	grpc.ClientStream
+}
+
+func (x *gophkeeperUploadBlobClient) Send(m *BlobChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *gophkeeperUploadBlobClient) CloseAndRecv() (*BlobUploadStatus, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(BlobUploadStatus)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gophkeeperClient) GetBlobUpload(ctx context.Context, in *GetBlobUploadRequest, opts ...grpc.CallOption) (*BlobUploadStatus, error) {
+	out := new(BlobUploadStatus)
+	err := c.cc.Invoke(ctx, "/proto.gophkeeper/GetBlobUpload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) DownloadBlob(ctx context.Context, in *DownloadBlobRequest, opts ...grpc.CallOption) (Gophkeeper_DownloadBlobClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Gophkeeper_ServiceDesc.Streams[2], "/proto.gophkeeper/DownloadBlob", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gophkeeperDownloadBlobClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Gophkeeper_DownloadBlobClient interface {
+	Recv() (*BlobChunk, error)
+	grpc.ClientStream
+}
+
+type gophkeeperDownloadBlobClient struct {
+	grpc.ClientStream
+}
+
+func (x *gophkeeperDownloadBlobClient) Recv() (*BlobChunk, error) {
+	m := new(BlobChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // GophkeeperServer is the server API for Gophkeeper service.
 // All implementations must embed UnimplementedGophkeeperServer
 // for forward compatibility
 type GophkeeperServer interface {
-	// SignUp registers a new user and creates a new user session.
-	SignUp(context.Context, *SignInData) (*UserAuth, error)
-	// LogIn creates a new session for the user provided.
-	LogIn(context.Context, *SignInData) (*UserAuth, error)
+	// SRPSignUp registers a new user with SRP-6a password verifier and creates a new user session.
+	// The password itself is never sent to the server.
+	SRPSignUp(context.Context, *SRPSignUpRequest) (*UserAuth, error)
+	// SRPLogInStart starts SRP-6a handshake: the client sends its public ephemeral value A,
+	// the server responds with the salt and its public ephemeral value B.
+	// The handshake is finished either by SRPLogInFinish or by the request that requires
+	// the password confirmation (see SRPProof).
+	// If there is no such user or the user has no SRP verifier (the accounts registered before
+	// SRP support), the fake challenge is returned and the proof is rejected as a wrong password,
+	// so the registered emails can't be enumerated.
+	SRPLogInStart(context.Context, *SRPLogInStartRequest) (*SRPChallenge, error)
+	// SRPLogInFinish verifies the client's proof M1 and creates a new session for the user.
+	// The server's proof M2 is returned together with the tokens, so the client can make
+	// sure that the server knows the verifier. If the user has two-factor authentication
+	// enabled, mfa_challenge is set instead of the tokens and must be exchanged for the tokens by LogInMFA.
+	SRPLogInFinish(context.Context, *SRPLogInFinishRequest) (*SRPLogInResult, error)
+	// LogInMFA completes the login (or the undelete) of the user with two-factor authentication enabled.
+	// The code may be either TOTP code or one of the recovery codes.
+	LogInMFA(context.Context, *LogInMFARequest) (*UserAuth, error)
 	// GetNewTokens generates a new AccessToken + RefreshToken pair.
 	// If refresh token is expired, the session ends.
 	GetNewTokens(context.Context, *RefreshToken) (*UserAuth, error)
 	// LogOut ends current user session.
 	LogOut(context.Context, *RefreshToken) (*emptypb.Empty, error)
+	// ChangePassword verifies the proof of the old password of the user, stores the new SRP-6a verifier
+	// and ends all other active sessions of the user. The session of the access token the call
+	// is authenticated with stays alive.
+	ChangePassword(context.Context, *ChangePasswordRequest) (*emptypb.Empty, error)
+	// DeleteUser marks the user as deleted and ends all user sessions. The user's password
+	// must be confirmed by SRPProof. The user's data is permanently erased after the grace period.
+	DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error)
+	// UndeleteUser restores the user deleted less than the grace period ago
+	// and creates a new session for the user. The password is confirmed by SRPProof of the handshake
+	// started with undelete flag. If the user has two-factor authentication enabled,
+	// only mfa_challenge is returned: the account is restored by LogInMFA.
+	UndeleteUser(context.Context, *UndeleteUserRequest) (*UserAuth, error)
+	// VerifyEmail marks the user's email as verified. The token is sent to the email at the registration.
+	VerifyEmail(context.Context, *VerifyEmailRequest) (*emptypb.Empty, error)
+	// RequestPasswordReset sends the password reset token to the email provided. The response
+	// is the same whether the user with such email exists or not.
+	RequestPasswordReset(context.Context, *RequestPasswordResetRequest) (*emptypb.Empty, error)
+	// ResetPassword replaces the SRP-6a verifier of the user the token is issued for
+	// and ends all the user sessions.
+	ResetPassword(context.Context, *ResetPasswordRequest) (*emptypb.Empty, error)
+	// EnrollTOTP starts the enrollment of the TOTP second factor. The password must be confirmed
+	// (by SRPProof). It returns otpauth:// URI for authenticator applications and the recovery codes.
+	// Two-factor authentication is enabled after the enrollment is confirmed by ConfirmTOTP.
+	EnrollTOTP(context.Context, *EnrollTOTPRequest) (*TOTPEnrollment, error)
+	// ConfirmTOTP enables two-factor authentication if the TOTP code is valid.
+	ConfirmTOTP(context.Context, *ConfirmTOTPRequest) (*emptypb.Empty, error)
+	// DisableTOTP disables two-factor authentication. The password (by SRPProof) and the one-time code
+	// (or a recovery code) must be confirmed.
+	DisableTOTP(context.Context, *DisableTOTPRequest) (*emptypb.Empty, error)
+	// ListSessions returns all active sessions of the user.
+	ListSessions(context.Context, *ListSessionsRequest) (*SessionList, error)
+	// LogoutSession ends the session of the user with ID provided.
+	LogoutSession(context.Context, *LogoutSessionRequest) (*emptypb.Empty, error)
+	// LogoutAllSessions ends all sessions of the user including the current one.
+	LogoutAllSessions(context.Context, *LogoutAllSessionsRequest) (*emptypb.Empty, error)
 	// PublishLocalChanges applies the changes to the storage on the server.
 	// This method is allowed only if the version of user's data on the client side is equal
-	// to the version number on the server. Otherwise the error is returned and the client
-	// must first update data from the server.
-	PublishLocalChanges(context.Context, *PublishLocalChangesRequest) (*emptypb.Empty, error)
+	// to the version number on the server. Otherwise FailedPrecondition with DATA_VERSION_STALE
+	// reason is returned and the client must first update data from the server.
+	// The events are applied in one transaction and the outcome of each event is returned
+	// together with the new data version. A failed event doesn't prevent the others from being applied.
+	PublishLocalChanges(context.Context, *PublishLocalChangesRequest) (*PublishLocalChangesResponse, error)
 	// WhatsNew compares provided Data Version with such one stored on the server. If they are the same,
-	// nil error is returned. Otherwise, the "PermissionDenied" code and the error "out of date" is returned.
+	// nil error is returned. Otherwise, FailedPrecondition with DATA_VERSION_STALE reason is returned.
 	WhatsNew(context.Context, *WhatsNewRequest) (*emptypb.Empty, error)
+	// Watch streams the notifications about the changes of the user's data. The first event
+	// carries the current data version, the next ones are sent after each change. If there is
+	// no change for a while, a heartbeat is sent, so the client can detect a broken stream.
+	// The stream is closed with Unauthenticated status when the session is logged out.
+	Watch(*WatchRequest, Gophkeeper_WatchServer) error
 	// DownloadUserData analyses existing versions of the local items and downloads latest updates of the user's data from the server.
 	DownloadUserData(context.Context, *DownloadUserDataRequest) (*UserData, error)
+	// GetChangesSince returns the items changed after the data version provided together with
+	// the current data version. If the server's change log no longer covers that version,
+	// FailedPrecondition with CHANGE_LOG_COMPACTED reason is returned and the client must
+	// use DownloadUserData instead.
+	GetChangesSince(context.Context, *GetChangesSinceRequest) (*UserData, error)
+	// ListTrash returns the deleted items of the user with their payload. The deleted items
+	// are permanently erased after the retention period configured on the server.
+	ListTrash(context.Context, *ListTrashRequest) (*TrashList, error)
+	// RestoreItem restores the deleted item and returns it with the new version.
+	// NotFound is returned if there is no such item in the trash.
+	RestoreItem(context.Context, *RestoreItemRequest) (*RestoreItemResponse, error)
+	// PurgeItem permanently erases the deleted item.
+	// NotFound is returned if there is no such item in the trash.
+	PurgeItem(context.Context, *PurgeItemRequest) (*emptypb.Empty, error)
+	// ListItemVersions returns the versions of the item kept in the history, the latest first.
+	// NotFound is returned if there is no history of the item.
+	ListItemVersions(context.Context, *ListItemVersionsRequest) (*ItemVersionList, error)
+	// GetItemVersion returns the version of the item from the history with its payload.
+	// NotFound is returned if there is no such version.
+	GetItemVersion(context.Context, *GetItemVersionRequest) (*Item, error)
+	// RestoreVault brings all the items back to the state they had in the data version provided.
+	// The restore is recorded as a new data version, so it's delivered to the other devices as
+	// ordinary changes. The user password must be confirmed by SRPProof: PermissionDenied is returned if it's wrong.
+	// If the item history no longer covers the data version, FailedPrecondition with
+	// SNAPSHOT_NOT_RETAINED reason is returned.
+	RestoreVault(context.Context, *RestoreVaultRequest) (*RestoreVaultResponse, error)
+	// UploadBlob receives the content of a blob item in chunks. The first chunk must carry
+	// the hash and the size of the whole content. Each chunk carries its offset and CRC-32C
+	// checksum: DataLoss with BLOB_CHECKSUM_MISMATCH reason is returned if the chunk or the whole
+	// content is corrupted. The upload may be interrupted and resumed from the offset returned
+	// by GetBlobUpload; if the offset of the chunk doesn't match the number of bytes received,
+	// FailedPrecondition with BLOB_OFFSET_MISMATCH reason is returned.
+	// The item referring to the content can be published only after the upload is complete.
+	UploadBlob(Gophkeeper_UploadBlobServer) error
+	// GetBlobUpload returns the state of the upload of the content with the hash provided.
+	// NotFound is returned if the upload isn't started.
+	GetBlobUpload(context.Context, *GetBlobUploadRequest) (*BlobUploadStatus, error)
+	// DownloadBlob sends the uploaded content of a blob item in chunks starting at the offset provided.
+	DownloadBlob(*DownloadBlobRequest, Gophkeeper_DownloadBlobServer) error
 	mustEmbedUnimplementedGophkeeperServer()
 }
 
@@ -145,11 +633,17 @@ type GophkeeperServer interface {
 type UnimplementedGophkeeperServer struct {
 }
 
-func (UnimplementedGophkeeperServer) SignUp(context.Context, *SignInData) (*UserAuth, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SignUp not implemented")
+func (UnimplementedGophkeeperServer) SRPSignUp(context.Context, *SRPSignUpRequest) (*UserAuth, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SRPSignUp not implemented")
+}
+func (UnimplementedGophkeeperServer) SRPLogInStart(context.Context, *SRPLogInStartRequest) (*SRPChallenge, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SRPLogInStart not implemented")
+}
+func (UnimplementedGophkeeperServer) SRPLogInFinish(context.Context, *SRPLogInFinishRequest) (*SRPLogInResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SRPLogInFinish not implemented")
 }
-func (UnimplementedGophkeeperServer) LogIn(context.Context, *SignInData) (*UserAuth, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method LogIn not implemented")
+func (UnimplementedGophkeeperServer) LogInMFA(context.Context, *LogInMFARequest) (*UserAuth, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LogInMFA not implemented")
 }
 func (UnimplementedGophkeeperServer) GetNewTokens(context.Context, *RefreshToken) (*UserAuth, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetNewTokens not implemented")
@@ -157,15 +651,84 @@ func (UnimplementedGophkeeperServer) GetNewTokens(context.Context, *RefreshToken
 func (UnimplementedGophkeeperServer) LogOut(context.Context, *RefreshToken) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method LogOut not implemented")
 }
-func (UnimplementedGophkeeperServer) PublishLocalChanges(context.Context, *PublishLocalChangesRequest) (*emptypb.Empty, error) {
+func (UnimplementedGophkeeperServer) ChangePassword(context.Context, *ChangePasswordRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangePassword not implemented")
+}
+func (UnimplementedGophkeeperServer) DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedGophkeeperServer) UndeleteUser(context.Context, *UndeleteUserRequest) (*UserAuth, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UndeleteUser not implemented")
+}
+func (UnimplementedGophkeeperServer) VerifyEmail(context.Context, *VerifyEmailRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyEmail not implemented")
+}
+func (UnimplementedGophkeeperServer) RequestPasswordReset(context.Context, *RequestPasswordResetRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestPasswordReset not implemented")
+}
+func (UnimplementedGophkeeperServer) ResetPassword(context.Context, *ResetPasswordRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetPassword not implemented")
+}
+func (UnimplementedGophkeeperServer) EnrollTOTP(context.Context, *EnrollTOTPRequest) (*TOTPEnrollment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnrollTOTP not implemented")
+}
+func (UnimplementedGophkeeperServer) ConfirmTOTP(context.Context, *ConfirmTOTPRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmTOTP not implemented")
+}
+func (UnimplementedGophkeeperServer) DisableTOTP(context.Context, *DisableTOTPRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableTOTP not implemented")
+}
+func (UnimplementedGophkeeperServer) ListSessions(context.Context, *ListSessionsRequest) (*SessionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSessions not implemented")
+}
+func (UnimplementedGophkeeperServer) LogoutSession(context.Context, *LogoutSessionRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LogoutSession not implemented")
+}
+func (UnimplementedGophkeeperServer) LogoutAllSessions(context.Context, *LogoutAllSessionsRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LogoutAllSessions not implemented")
+}
+func (UnimplementedGophkeeperServer) PublishLocalChanges(context.Context, *PublishLocalChangesRequest) (*PublishLocalChangesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PublishLocalChanges not implemented")
 }
 func (UnimplementedGophkeeperServer) WhatsNew(context.Context, *WhatsNewRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method WhatsNew not implemented")
 }
+func (UnimplementedGophkeeperServer) Watch(*WatchRequest, Gophkeeper_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
 func (UnimplementedGophkeeperServer) DownloadUserData(context.Context, *DownloadUserDataRequest) (*UserData, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DownloadUserData not implemented")
 }
+func (UnimplementedGophkeeperServer) GetChangesSince(context.Context, *GetChangesSinceRequest) (*UserData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChangesSince not implemented")
+}
+func (UnimplementedGophkeeperServer) ListTrash(context.Context, *ListTrashRequest) (*TrashList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTrash not implemented")
+}
+func (UnimplementedGophkeeperServer) RestoreItem(context.Context, *RestoreItemRequest) (*RestoreItemResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreItem not implemented")
+}
+func (UnimplementedGophkeeperServer) PurgeItem(context.Context, *PurgeItemRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeItem not implemented")
+}
+func (UnimplementedGophkeeperServer) ListItemVersions(context.Context, *ListItemVersionsRequest) (*ItemVersionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListItemVersions not implemented")
+}
+func (UnimplementedGophkeeperServer) GetItemVersion(context.Context, *GetItemVersionRequest) (*Item, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetItemVersion not implemented")
+}
+func (UnimplementedGophkeeperServer) RestoreVault(context.Context, *RestoreVaultRequest) (*RestoreVaultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreVault not implemented")
+}
+func (UnimplementedGophkeeperServer) UploadBlob(Gophkeeper_UploadBlobServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadBlob not implemented")
+}
+func (UnimplementedGophkeeperServer) GetBlobUpload(context.Context, *GetBlobUploadRequest) (*BlobUploadStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlobUpload not implemented")
+}
+func (UnimplementedGophkeeperServer) DownloadBlob(*DownloadBlobRequest, Gophkeeper_DownloadBlobServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadBlob not implemented")
+}
 func (UnimplementedGophkeeperServer) mustEmbedUnimplementedGophkeeperServer() {}
 
 // UnsafeGophkeeperServer may be embedded to opt out of forward compatibility for this service.
@@ -179,146 +742,618 @@ func RegisterGophkeeperServer(s grpc.ServiceRegistrar, srv GophkeeperServer) {
 	s.RegisterService(&Gophkeeper_ServiceDesc, srv)
 }
 
-func _Gophkeeper_SignUp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(SignInData)
+func _Gophkeeper_SRPSignUp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SRPSignUpRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophkeeperServer).SignUp(ctx, in)
+		return srv.(GophkeeperServer).SRPSignUp(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.gophkeeper/SignUp",
+		FullMethod: "/proto.gophkeeper/SRPSignUp",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophkeeperServer).SignUp(ctx, req.(*SignInData))
+		return srv.(GophkeeperServer).SRPSignUp(ctx, req.(*SRPSignUpRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Gophkeeper_LogIn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(SignInData)
+func _Gophkeeper_SRPLogInStart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SRPLogInStartRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophkeeperServer).LogIn(ctx, in)
+		return srv.(GophkeeperServer).SRPLogInStart(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.gophkeeper/LogIn",
+		FullMethod: "/proto.gophkeeper/SRPLogInStart",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophkeeperServer).LogIn(ctx, req.(*SignInData))
+		return srv.(GophkeeperServer).SRPLogInStart(ctx, req.(*SRPLogInStartRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Gophkeeper_GetNewTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RefreshToken)
+func _Gophkeeper_SRPLogInFinish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SRPLogInFinishRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophkeeperServer).GetNewTokens(ctx, in)
+		return srv.(GophkeeperServer).SRPLogInFinish(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.gophkeeper/GetNewTokens",
+		FullMethod: "/proto.gophkeeper/SRPLogInFinish",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophkeeperServer).GetNewTokens(ctx, req.(*RefreshToken))
+		return srv.(GophkeeperServer).SRPLogInFinish(ctx, req.(*SRPLogInFinishRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Gophkeeper_LogOut_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RefreshToken)
+func _Gophkeeper_LogInMFA_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogInMFARequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophkeeperServer).LogOut(ctx, in)
+		return srv.(GophkeeperServer).LogInMFA(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.gophkeeper/LogOut",
+		FullMethod: "/proto.gophkeeper/LogInMFA",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophkeeperServer).LogOut(ctx, req.(*RefreshToken))
+		return srv.(GophkeeperServer).LogInMFA(ctx, req.(*LogInMFARequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Gophkeeper_PublishLocalChanges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(PublishLocalChangesRequest)
+func _Gophkeeper_GetNewTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshToken)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophkeeperServer).PublishLocalChanges(ctx, in)
+		return srv.(GophkeeperServer).GetNewTokens(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.gophkeeper/PublishLocalChanges",
+		FullMethod: "/proto.gophkeeper/GetNewTokens",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophkeeperServer).PublishLocalChanges(ctx, req.(*PublishLocalChangesRequest))
+		return srv.(GophkeeperServer).GetNewTokens(ctx, req.(*RefreshToken))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Gophkeeper_WhatsNew_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(WhatsNewRequest)
+func _Gophkeeper_LogOut_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshToken)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophkeeperServer).WhatsNew(ctx, in)
+		return srv.(GophkeeperServer).LogOut(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.gophkeeper/WhatsNew",
+		FullMethod: "/proto.gophkeeper/LogOut",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophkeeperServer).WhatsNew(ctx, req.(*WhatsNewRequest))
+		return srv.(GophkeeperServer).LogOut(ctx, req.(*RefreshToken))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Gophkeeper_DownloadUserData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DownloadUserDataRequest)
+func _Gophkeeper_ChangePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangePasswordRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(GophkeeperServer).DownloadUserData(ctx, in)
+		return srv.(GophkeeperServer).ChangePassword(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.gophkeeper/DownloadUserData",
+		FullMethod: "/proto.gophkeeper/ChangePassword",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GophkeeperServer).DownloadUserData(ctx, req.(*DownloadUserDataRequest))
+		return srv.(GophkeeperServer).ChangePassword(ctx, req.(*ChangePasswordRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-// Gophkeeper_ServiceDesc is the grpc.ServiceDesc for Gophkeeper service.
-// It's only intended for direct use with grpc.RegisterService,
+func _Gophkeeper_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/DeleteUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).DeleteUser(ctx, req.(*DeleteUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_UndeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UndeleteUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).UndeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/UndeleteUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).UndeleteUser(ctx, req.(*UndeleteUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_VerifyEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyEmailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).VerifyEmail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/VerifyEmail",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).VerifyEmail(ctx, req.(*VerifyEmailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_RequestPasswordReset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestPasswordResetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).RequestPasswordReset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/RequestPasswordReset",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).RequestPasswordReset(ctx, req.(*RequestPasswordResetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_ResetPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetPasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).ResetPassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/ResetPassword",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).ResetPassword(ctx, req.(*ResetPasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_EnrollTOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnrollTOTPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).EnrollTOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/EnrollTOTP",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).EnrollTOTP(ctx, req.(*EnrollTOTPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_ConfirmTOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmTOTPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).ConfirmTOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/ConfirmTOTP",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).ConfirmTOTP(ctx, req.(*ConfirmTOTPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_DisableTOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisableTOTPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).DisableTOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/DisableTOTP",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).DisableTOTP(ctx, req.(*DisableTOTPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_ListSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).ListSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/ListSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).ListSessions(ctx, req.(*ListSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_LogoutSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogoutSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).LogoutSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/LogoutSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).LogoutSession(ctx, req.(*LogoutSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_LogoutAllSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogoutAllSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).LogoutAllSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/LogoutAllSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).LogoutAllSessions(ctx, req.(*LogoutAllSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_PublishLocalChanges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishLocalChangesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).PublishLocalChanges(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/PublishLocalChanges",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).PublishLocalChanges(ctx, req.(*PublishLocalChangesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_WhatsNew_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WhatsNewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).WhatsNew(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/WhatsNew",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).WhatsNew(ctx, req.(*WhatsNewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GophkeeperServer).Watch(m, &gophkeeperWatchServer{stream})
+}
+
+type Gophkeeper_WatchServer interface {
+	Send(*WatchEvent) error
+	grpc.ServerStream
+}
+
+type gophkeeperWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *gophkeeperWatchServer) Send(m *WatchEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Gophkeeper_DownloadUserData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DownloadUserDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).DownloadUserData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/DownloadUserData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).DownloadUserData(ctx, req.(*DownloadUserDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_GetChangesSince_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetChangesSinceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).GetChangesSince(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/GetChangesSince",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).GetChangesSince(ctx, req.(*GetChangesSinceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_ListTrash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTrashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).ListTrash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/ListTrash",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).ListTrash(ctx, req.(*ListTrashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_RestoreItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).RestoreItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/RestoreItem",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).RestoreItem(ctx, req.(*RestoreItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_PurgeItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).PurgeItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/PurgeItem",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).PurgeItem(ctx, req.(*PurgeItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_ListItemVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListItemVersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).ListItemVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/ListItemVersions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).ListItemVersions(ctx, req.(*ListItemVersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_GetItemVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetItemVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).GetItemVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/GetItemVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).GetItemVersion(ctx, req.(*GetItemVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_RestoreVault_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreVaultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).RestoreVault(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/RestoreVault",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).RestoreVault(ctx, req.(*RestoreVaultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_UploadBlob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GophkeeperServer).UploadBlob(&gophkeeperUploadBlobServer{stream})
+}
+
+type Gophkeeper_UploadBlobServer interface {
+	SendAndClose(*BlobUploadStatus) error
+	Recv() (*BlobChunk, error)
+	grpc.ServerStream
+}
+
+type gophkeeperUploadBlobServer struct {
+	grpc.ServerStream
+}
+
+func (x *gophkeeperUploadBlobServer) SendAndClose(m *BlobUploadStatus) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *gophkeeperUploadBlobServer) Recv() (*BlobChunk, error) {
+	m := new(BlobChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Gophkeeper_GetBlobUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBlobUploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).GetBlobUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.gophkeeper/GetBlobUpload",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).GetBlobUpload(ctx, req.(*GetBlobUploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_DownloadBlob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadBlobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GophkeeperServer).DownloadBlob(m, &gophkeeperDownloadBlobServer{stream})
+}
+
+type Gophkeeper_DownloadBlobServer interface {
+	Send(*BlobChunk) error
+	grpc.ServerStream
+}
+
+type gophkeeperDownloadBlobServer struct {
+	grpc.ServerStream
+}
+
+func (x *gophkeeperDownloadBlobServer) Send(m *BlobChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Gophkeeper_ServiceDesc is the grpc.ServiceDesc for Gophkeeper service.
+// It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var Gophkeeper_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.gophkeeper",
 	HandlerType: (*GophkeeperServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
-			MethodName: "SignUp",
-			Handler:    _Gophkeeper_SignUp_Handler,
+			MethodName: "SRPSignUp",
+			Handler:    _Gophkeeper_SRPSignUp_Handler,
+		},
+		{
+			MethodName: "SRPLogInStart",
+			Handler:    _Gophkeeper_SRPLogInStart_Handler,
+		},
+		{
+			MethodName: "SRPLogInFinish",
+			Handler:    _Gophkeeper_SRPLogInFinish_Handler,
 		},
 		{
-			MethodName: "LogIn",
-			Handler:    _Gophkeeper_LogIn_Handler,
+			MethodName: "LogInMFA",
+			Handler:    _Gophkeeper_LogInMFA_Handler,
 		},
 		{
 			MethodName: "GetNewTokens",
@@ -328,6 +1363,54 @@ var Gophkeeper_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "LogOut",
 			Handler:    _Gophkeeper_LogOut_Handler,
 		},
+		{
+			MethodName: "ChangePassword",
+			Handler:    _Gophkeeper_ChangePassword_Handler,
+		},
+		{
+			MethodName: "DeleteUser",
+			Handler:    _Gophkeeper_DeleteUser_Handler,
+		},
+		{
+			MethodName: "UndeleteUser",
+			Handler:    _Gophkeeper_UndeleteUser_Handler,
+		},
+		{
+			MethodName: "VerifyEmail",
+			Handler:    _Gophkeeper_VerifyEmail_Handler,
+		},
+		{
+			MethodName: "RequestPasswordReset",
+			Handler:    _Gophkeeper_RequestPasswordReset_Handler,
+		},
+		{
+			MethodName: "ResetPassword",
+			Handler:    _Gophkeeper_ResetPassword_Handler,
+		},
+		{
+			MethodName: "EnrollTOTP",
+			Handler:    _Gophkeeper_EnrollTOTP_Handler,
+		},
+		{
+			MethodName: "ConfirmTOTP",
+			Handler:    _Gophkeeper_ConfirmTOTP_Handler,
+		},
+		{
+			MethodName: "DisableTOTP",
+			Handler:    _Gophkeeper_DisableTOTP_Handler,
+		},
+		{
+			MethodName: "ListSessions",
+			Handler:    _Gophkeeper_ListSessions_Handler,
+		},
+		{
+			MethodName: "LogoutSession",
+			Handler:    _Gophkeeper_LogoutSession_Handler,
+		},
+		{
+			MethodName: "LogoutAllSessions",
+			Handler:    _Gophkeeper_LogoutAllSessions_Handler,
+		},
 		{
 			MethodName: "PublishLocalChanges",
 			Handler:    _Gophkeeper_PublishLocalChanges_Handler,
@@ -340,7 +1423,55 @@ var Gophkeeper_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DownloadUserData",
 			Handler:    _Gophkeeper_DownloadUserData_Handler,
 		},
+		{
+			MethodName: "GetChangesSince",
+			Handler:    _Gophkeeper_GetChangesSince_Handler,
+		},
+		{
+			MethodName: "ListTrash",
+			Handler:    _Gophkeeper_ListTrash_Handler,
+		},
+		{
+			MethodName: "RestoreItem",
+			Handler:    _Gophkeeper_RestoreItem_Handler,
+		},
+		{
+			MethodName: "PurgeItem",
+			Handler:    _Gophkeeper_PurgeItem_Handler,
+		},
+		{
+			MethodName: "ListItemVersions",
+			Handler:    _Gophkeeper_ListItemVersions_Handler,
+		},
+		{
+			MethodName: "GetItemVersion",
+			Handler:    _Gophkeeper_GetItemVersion_Handler,
+		},
+		{
+			MethodName: "RestoreVault",
+			Handler:    _Gophkeeper_RestoreVault_Handler,
+		},
+		{
+			MethodName: "GetBlobUpload",
+			Handler:    _Gophkeeper_GetBlobUpload_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Gophkeeper_Watch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "UploadBlob",
+			Handler:       _Gophkeeper_UploadBlob_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadBlob",
+			Handler:       _Gophkeeper_DownloadBlob_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/gophkeeper.proto",
 }
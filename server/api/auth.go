@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/users"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vanamelnik/gophkeeper/proto"
+)
+
+const (
+	authorizationHeader = "authorization"
+	bearerPrefix        = "bearer "
+)
+
+// publicMethods are the methods that are called without the access token.
+var publicMethods = map[string]bool{
+	fullMethod("SRPSignUp"):            true,
+	fullMethod("SRPLogInStart"):        true,
+	fullMethod("SRPLogInFinish"):       true,
+	fullMethod("LogInMFA"):             true,
+	fullMethod("GetNewTokens"):         true,
+	fullMethod("LogOut"):               true,
+	fullMethod("UndeleteUser"):         true,
+	fullMethod("VerifyEmail"):          true,
+	fullMethod("RequestPasswordReset"): true,
+	fullMethod("ResetPassword"):        true,
+}
+
+func fullMethod(name string) string {
+	return "/" + pb.Gophkeeper_ServiceDesc.ServiceName + "/" + name
+}
+
+type (
+	// userIDKey is the context key of the authenticated user ID.
+	userIDKey struct{}
+	// sessionIDKey is the context key of the session ID of the access token.
+	sessionIDKey struct{}
+)
+
+// deprecatedTokenRequest is the request that carries the access token in the message.
+// The token field is deprecated in favour of "authorization" metadata.
+type deprecatedTokenRequest interface {
+	GetToken() *pb.AccessToken
+}
+
+// unaryAuthInterceptor authenticates the calls of all non-public methods by the bearer token
+// from "authorization" metadata and puts the user ID into the context of the handler.
+// For older clients the token is taken from the request message if there is no metadata.
+func (s server) unaryAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := req.(deprecatedTokenRequest); ok && token == "" {
+		token = models.AccessToken(r.GetToken().GetAccessToken())
+	}
+	ctx, err = s.authenticate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamAuthInterceptor authenticates the streaming calls of all non-public methods
+// by the bearer token from "authorization" metadata.
+func (s server) streamAuthInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if publicMethods[info.FullMethod] {
+		return handler(srv, ss)
+	}
+	token, err := bearerToken(ss.Context())
+	if err != nil {
+		return err
+	}
+	ctx, err := s.authenticate(ss.Context(), token)
+	if err != nil {
+		return err
+	}
+	return handler(srv, authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate checks the access token and its session and returns the context with the user ID and the session ID.
+func (s server) authenticate(ctx context.Context, token models.AccessToken) (context.Context, error) {
+	if token == "" {
+		return nil, reasonError(codes.Unauthenticated, pb.ErrorReason_TOKEN_INVALID, "access token is missing")
+	}
+	userID, sessionID, err := s.users.Authenticate(ctx, token)
+	if err != nil {
+		if errors.Is(err, users.ErrAccessTokenExpired) {
+			return nil, reasonError(codes.Unauthenticated, pb.ErrorReason_TOKEN_EXPIRED, err.Error())
+		}
+		return nil, reasonError(codes.Unauthenticated, pb.ErrorReason_TOKEN_INVALID, err.Error())
+	}
+	ctx = context.WithValue(ctx, userIDKey{}, userID)
+	return context.WithValue(ctx, sessionIDKey{}, sessionID), nil
+}
+
+// bearerToken returns the access token from "authorization" metadata.
+// If there is no such metadata, an empty token returns.
+func bearerToken(ctx context.Context) (models.AccessToken, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 {
+		return "", nil
+	}
+	if !strings.HasPrefix(strings.ToLower(values[0]), bearerPrefix) {
+		return "", reasonError(codes.Unauthenticated, pb.ErrorReason_TOKEN_INVALID, "authorization metadata must contain bearer token")
+	}
+	return models.AccessToken(strings.TrimSpace(values[0][len(bearerPrefix):])), nil
+}
+
+// userIDFromContext returns the ID of the user authenticated by the interceptor.
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	userID, ok := ctx.Value(userIDKey{}).(uuid.UUID)
+	if !ok {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "the call is not authenticated")
+	}
+	return userID, nil
+}
+
+// sessionIDFromContext returns the session ID of the access token the call is authenticated with.
+// It returns uuid.Nil if the token doesn't contain the session ID.
+func sessionIDFromContext(ctx context.Context) uuid.UUID {
+	sessionID, _ := ctx.Value(sessionIDKey{}).(uuid.UUID)
+	return sessionID
+}
+
+// authenticatedStream is the server stream with the context containing the user ID.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s authenticatedStream) Context() context.Context {
+	return s.ctx
+}
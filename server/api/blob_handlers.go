@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/vanamelnik/gophkeeper/models"
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"github.com/vanamelnik/gophkeeper/server/gophkeeper"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// downloadChunkSize is the maximum size of the blob chunk sent by DownloadBlob.
+const downloadChunkSize = 1 << 20
+
+// UploadBlob implements GophkeeperServer interface.
+func (s server) UploadBlob(stream pb.Gophkeeper_UploadBlobServer) error {
+	ctx := stream.Context()
+	userID, err := s.syncUserID(ctx)
+	if err != nil {
+		return err
+	}
+	chunk, err := stream.Recv()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return status.Error(codes.InvalidArgument, "no blob chunks received")
+		}
+		return err
+	}
+	hash, size := chunk.Sha256, chunk.Size
+	if !models.IsValidBlobHash(hash) || size < 0 {
+		return status.Error(codes.InvalidArgument, "the first chunk must contain SHA-256 hash and size of the content")
+	}
+	upload, err := s.gophkeeper.StartBlobUpload(ctx, userID, hash, size)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	for !upload.Complete {
+		if models.BlobChunkChecksum(chunk.Data) != chunk.Crc32C {
+			return reasonError(codes.DataLoss, pb.ErrorReason_BLOB_CHECKSUM_MISMATCH, "blob chunk checksum mismatch")
+		}
+		if len(chunk.Data) > 0 {
+			if err := s.gophkeeper.AppendBlobChunk(ctx, userID, hash, chunk.Offset, chunk.Data); err != nil {
+				return blobError(err)
+			}
+			upload.Received = chunk.Offset + int64(len(chunk.Data))
+		}
+		if upload.Received == upload.Size {
+			if err := s.gophkeeper.CompleteBlobUpload(ctx, userID, hash); err != nil {
+				return blobError(err)
+			}
+			upload.Complete = true
+			break
+		}
+		chunk, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break // the upload is interrupted by the client and may be resumed later
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return stream.SendAndClose(blobUploadToPb(upload))
+}
+
+// GetBlobUpload implements GophkeeperServer interface.
+func (s server) GetBlobUpload(ctx context.Context, r *pb.GetBlobUploadRequest) (*pb.BlobUploadStatus, error) {
+	userID, err := s.syncUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !models.IsValidBlobHash(r.Sha256) {
+		return nil, status.Error(codes.InvalidArgument, "invalid SHA-256 hash")
+	}
+	upload, err := s.gophkeeper.GetBlobUpload(ctx, userID, r.Sha256)
+	if err != nil {
+		return nil, blobError(err)
+	}
+
+	return blobUploadToPb(upload), nil
+}
+
+// DownloadBlob implements GophkeeperServer interface.
+func (s server) DownloadBlob(r *pb.DownloadBlobRequest, stream pb.Gophkeeper_DownloadBlobServer) error {
+	ctx := stream.Context()
+	userID, err := s.syncUserID(ctx)
+	if err != nil {
+		return err
+	}
+	if !models.IsValidBlobHash(r.Sha256) {
+		return status.Error(codes.InvalidArgument, "invalid SHA-256 hash")
+	}
+	upload, err := s.gophkeeper.GetBlobUpload(ctx, userID, r.Sha256)
+	if err != nil {
+		return blobError(err)
+	}
+	if !upload.Complete {
+		return status.Error(codes.NotFound, "blob content is not uploaded")
+	}
+	if r.Offset < 0 || r.Offset > upload.Size {
+		return status.Error(codes.OutOfRange, "offset is beyond the content")
+	}
+
+	content, err := s.gophkeeper.OpenBlob(ctx, upload, r.Offset)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer content.Close()
+	buf := make([]byte, downloadChunkSize)
+	for offset := r.Offset; offset < upload.Size; {
+		n, err := io.ReadFull(content, buf)
+		if n == 0 && err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF // the content is shorter than its size
+			}
+			return status.Error(codes.Internal, err.Error())
+		}
+		if err := stream.Send(&pb.BlobChunk{
+			Sha256: upload.Hash,
+			Size:   upload.Size,
+			Offset: offset,
+			Data:   buf[:n],
+			Crc32C: models.BlobChunkChecksum(buf[:n]),
+		}); err != nil {
+			return err
+		}
+		offset += int64(n)
+	}
+
+	return nil
+}
+
+// blobError converts the error of the blob operation into gRPC status error.
+func blobError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return status.Error(codes.NotFound, "blob upload is not found")
+	case errors.Is(err, storage.ErrBlobOffsetMismatch):
+		return reasonError(codes.FailedPrecondition, pb.ErrorReason_BLOB_OFFSET_MISMATCH, err.Error())
+	case errors.Is(err, gophkeeper.ErrBlobHashMismatch):
+		return reasonError(codes.DataLoss, pb.ErrorReason_BLOB_CHECKSUM_MISMATCH, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func blobUploadToPb(upload models.BlobUpload) *pb.BlobUploadStatus {
+	return &pb.BlobUploadStatus{
+		Sha256:   upload.Hash,
+		Size:     upload.Size,
+		Received: upload.Received,
+		Complete: upload.Complete,
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+	"github.com/vanamelnik/gophkeeper/server/throttle"
+	"github.com/vanamelnik/gophkeeper/server/users"
+
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// VerifyEmail implements GophkeeperServer interface.
+func (s server) VerifyEmail(ctx context.Context, r *pb.VerifyEmailRequest) (*empty.Empty, error) {
+	keys := authKeys("", clientInfo(ctx, nil).RemoteAddr)
+	attempt, err := s.reserveAttempt(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	defer attempt.release()
+	if err := s.users.VerifyEmail(ctx, r.Token); err != nil {
+		if errors.Is(err, users.ErrIncorrectEmailToken) {
+			attempt.fail()
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &empty.Empty{}, nil
+}
+
+// RequestPasswordReset implements GophkeeperServer interface.
+func (s server) RequestPasswordReset(ctx context.Context, r *pb.RequestPasswordResetRequest) (*empty.Empty, error) {
+	// every request is counted per email and per remote address to prevent flooding the user's mailbox
+	keys := authKeys(r.Email, clientInfo(ctx, nil).RemoteAddr)
+	for i, key := range keys {
+		keys[i] = throttle.PasswordResetKey(key)
+	}
+	if err := s.limiter.Allow(ctx, s.passwordResetRate, keys...); err != nil {
+		return nil, throttleError(err)
+	}
+
+	if err := s.users.RequestPasswordReset(ctx, r.Email); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &empty.Empty{}, nil
+}
+
+// ResetPassword implements GophkeeperServer interface.
+func (s server) ResetPassword(ctx context.Context, r *pb.ResetPasswordRequest) (*empty.Empty, error) {
+	keys := authKeys("", clientInfo(ctx, nil).RemoteAddr)
+	attempt, err := s.reserveAttempt(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	defer attempt.release()
+	if err := s.users.ResetPassword(ctx, r.Token, r.Email, r.Salt, r.Verifier); err != nil {
+		if errors.Is(err, users.ErrIncorrectEmailToken) {
+			attempt.fail()
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, users.ErrInvalidSRPVerifier) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &empty.Empty{}, nil
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vanamelnik/gophkeeper/proto"
+)
+
+// errorDomain is the domain of google.rpc.ErrorInfo details of the service errors.
+const errorDomain = "gophkeeper"
+
+// reasonError returns gRPC status error with google.rpc.ErrorInfo detail containing the reason provided.
+func reasonError(code codes.Code, reason pb.ErrorReason, msg string) error {
+	st, err := reasonStatus(code, reason, msg)
+	if err != nil {
+		return status.Error(code, msg)
+	}
+	return st.Err()
+}
+
+// reasonStatus returns gRPC status with google.rpc.ErrorInfo detail containing the reason provided.
+// More details can be added to the status.
+func reasonStatus(code codes.Code, reason pb.ErrorReason, msg string) (*status.Status, error) {
+	return status.New(code, msg).WithDetails(&errdetails.ErrorInfo{
+		Reason: reason.String(),
+		Domain: errorDomain,
+	})
+}
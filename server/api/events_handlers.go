@@ -11,6 +11,7 @@ import (
 	pb "github.com/vanamelnik/gophkeeper/proto"
 	"github.com/vanamelnik/gophkeeper/server/gophkeeper"
 	"github.com/vanamelnik/gophkeeper/server/storage"
+	"github.com/vanamelnik/gophkeeper/server/users"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -18,9 +19,9 @@ import (
 
 // DownloadUserData implements GophkeeperServer interface.
 func (s server) DownloadUserData(ctx context.Context, r *pb.DownloadUserDataRequest) (*pb.UserData, error) {
-	userID, err := s.users.Authenticate(ctx, models.AccessToken(r.Token.AccessToken))
+	userID, err := s.syncUserID(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
+		return nil, err
 	}
 	versionMap := make(map[uuid.UUID]uint64)
 	if err := json.Unmarshal([]byte(r.VersionMap), &versionMap); err != nil {
@@ -48,11 +49,39 @@ func (s server) DownloadUserData(ctx context.Context, r *pb.DownloadUserDataRequ
 	}, nil
 }
 
+// GetChangesSince implements GophkeeperServer interface.
+func (s server) GetChangesSince(ctx context.Context, r *pb.GetChangesSinceRequest) (*pb.UserData, error) {
+	userID, err := s.syncUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.gophkeeper.GetChangesSince(ctx, userID, r.DataVersion)
+	if err != nil {
+		if errors.Is(err, storage.ErrChangeLogCompacted) {
+			return nil, reasonError(codes.FailedPrecondition, pb.ErrorReason_CHANGE_LOG_COMPACTED, "full sync needed")
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	pbItems := make([]*pb.Item, 0, len(data.Items))
+	for _, item := range data.Items {
+		pbItems = append(pbItems, models.ItemToPb(item))
+	}
+
+	return &pb.UserData{
+		DataVersion: data.Version,
+		Items:       pbItems,
+	}, nil
+}
+
 // PublishLocalChanges implements GophkeeperServer interface.
-func (s server) PublishLocalChanges(ctx context.Context, r *pb.PublishLocalChangesRequest) (*emptypb.Empty, error) {
-	userID, err := s.users.Authenticate(ctx, models.AccessToken(r.Token.AccessToken))
+func (s server) PublishLocalChanges(ctx context.Context, r *pb.PublishLocalChangesRequest) (*pb.PublishLocalChangesResponse, error) {
+	userID, err := s.syncUserID(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
+		return nil, err
 	}
 	dataVersion, err := s.users.GetDataVersion(ctx, userID)
 	if err != nil {
@@ -62,7 +91,7 @@ func (s server) PublishLocalChanges(ctx context.Context, r *pb.PublishLocalChang
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 	if r.DataVersion < dataVersion {
-		return nil, status.Error(codes.PermissionDenied, "local data version is out of date")
+		return nil, reasonError(codes.FailedPrecondition, pb.ErrorReason_DATA_VERSION_STALE, "local data version is out of date")
 	}
 	// convert events to canonical format
 	events := make([]models.Event, 0, len(r.Events))
@@ -76,6 +105,10 @@ func (s server) PublishLocalChanges(ctx context.Context, r *pb.PublishLocalChang
 		if err != nil {
 			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
+		if op != models.OpDelete && (item.IsTombstone() || models.IsValidItem(item) != nil) {
+			return nil, status.Error(codes.InvalidArgument,
+				fmt.Sprintf("%s event must contain the item with payload", op))
+		}
 		event := models.Event{
 			Operation: op,
 			Item:      item,
@@ -84,16 +117,53 @@ func (s server) PublishLocalChanges(ctx context.Context, r *pb.PublishLocalChang
 	}
 
 	// process local events
-	s.gophkeeper.PublishUserData(ctx, userID, events)
+	result, err := s.gophkeeper.PublishUserData(ctx, userID, sessionIDFromContext(ctx), events)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
 
-	return &emptypb.Empty{}, nil
+	resp := &pb.PublishLocalChangesResponse{
+		DataVersion: result.DataVersion,
+		Results:     make([]*pb.EventResult, 0, len(result.Results)),
+	}
+	for _, r := range result.Results {
+		resp.Results = append(resp.Results, eventResultToPb(r))
+	}
+	return resp, nil
+}
+
+// eventResultToPb converts the outcome of the event into the protobuf message.
+func eventResultToPb(r models.EventResult) *pb.EventResult {
+	if r.Err == nil {
+		return &pb.EventResult{
+			ItemId:      r.ItemID.String(),
+			Applied:     true,
+			ItemVersion: r.Version,
+		}
+	}
+	result := &pb.EventResult{
+		ItemId: r.ItemID.String(),
+		Error:  r.Err.Error(),
+	}
+	var conflict storage.ErrVersionConflict
+	if errors.As(r.Err, &conflict) {
+		result.Reason = pb.ErrorReason_ITEM_VERSION_CONFLICT
+		result.ConflictingItem = models.ItemToPb(conflict.Item)
+	}
+	if errors.Is(r.Err, storage.ErrBlobNotUploaded) {
+		result.Reason = pb.ErrorReason_BLOB_NOT_UPLOADED
+	}
+	return result
 }
 
 // WhatsNew implements GophkeeperServer interface.
 func (s server) WhatsNew(ctx context.Context, r *pb.WhatsNewRequest) (*emptypb.Empty, error) {
-	userID, err := s.users.Authenticate(ctx, models.AccessToken(r.Token.AccessToken))
+	userID, err := s.syncUserID(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
+		return nil, err
 	}
 	dataVersion, err := s.users.GetDataVersion(ctx, userID)
 	if err != nil {
@@ -103,8 +173,27 @@ func (s server) WhatsNew(ctx context.Context, r *pb.WhatsNewRequest) (*emptypb.E
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 	if r.DataVersion != dataVersion {
-		return nil, status.Error(codes.PermissionDenied, "out of date")
+		return nil, reasonError(codes.FailedPrecondition, pb.ErrorReason_DATA_VERSION_STALE, "out of date")
 	}
 
 	return &emptypb.Empty{}, nil
 }
+
+// syncUserID returns the ID of the authenticated user and checks whether the user is allowed
+// to sync the data. The error returned is gRPC status.
+func (s server) syncUserID(ctx context.Context) (uuid.UUID, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := s.users.CheckSyncAllowed(ctx, userID); err != nil {
+		if errors.Is(err, users.ErrEmailNotVerified) {
+			return uuid.Nil, reasonError(codes.FailedPrecondition, pb.ErrorReason_EMAIL_NOT_VERIFIED, err.Error())
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			return uuid.Nil, status.Error(codes.NotFound, err.Error())
+		}
+		return uuid.Nil, status.Error(codes.Internal, err.Error())
+	}
+	return userID, nil
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ListItemVersions implements GophkeeperServer interface.
+func (s server) ListItemVersions(ctx context.Context, r *pb.ListItemVersionsRequest) (*pb.ItemVersionList, error) {
+	userID, err := s.syncUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := uuid.Parse(r.ItemId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	versions, err := s.gophkeeper.ListItemVersions(ctx, userID, itemID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "the item has no history")
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	pbVersions := make([]*pb.ItemVersion, 0, len(versions))
+	for _, v := range versions {
+		pbVersions = append(pbVersions, &pb.ItemVersion{
+			Version:     v.Version,
+			DataVersion: v.DataVersion,
+			ArchivedAt:  timestamppb.New(v.ArchivedAt),
+			Deleted:     v.Deleted,
+		})
+	}
+
+	return &pb.ItemVersionList{Versions: pbVersions}, nil
+}
+
+// GetItemVersion implements GophkeeperServer interface.
+func (s server) GetItemVersion(ctx context.Context, r *pb.GetItemVersionRequest) (*pb.Item, error) {
+	userID, err := s.syncUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := uuid.Parse(r.ItemId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	item, err := s.gophkeeper.GetItemVersion(ctx, userID, itemID, r.Version)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "the item version is not found")
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return models.ItemToPb(item), nil
+}
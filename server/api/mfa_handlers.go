@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/pkg/passhash"
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+	"github.com/vanamelnik/gophkeeper/server/throttle"
+	"github.com/vanamelnik/gophkeeper/server/users"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LogInMFA implements GophkeeperServer interface.
+func (s server) LogInMFA(ctx context.Context, r *pb.LogInMFARequest) (*pb.UserAuth, error) {
+	client := clientInfo(ctx, r.ClientInfo)
+	challenge := models.MFAChallenge(r.MfaChallenge)
+	userID, err := s.users.MFAChallengeUser(challenge)
+	if err != nil {
+		return nil, mfaError(err)
+	}
+	mfaKey := throttle.MFAKey(userID)
+	attempt, err := s.reserveAttempt(ctx, append(authKeys("", client.RemoteAddr), mfaKey)...)
+	if err != nil {
+		return nil, err
+	}
+	defer attempt.release()
+	accessToken, refreshToken, err := s.users.LoginMFA(ctx, challenge, r.Code, client)
+	if err != nil {
+		if errors.Is(err, users.ErrInvalidOTPCode) {
+			attempt.fail()
+		}
+		return nil, mfaError(err)
+	}
+	s.resetFailures(ctx, mfaKey)
+
+	return &pb.UserAuth{
+		AccessToken:  &pb.AccessToken{AccessToken: string(accessToken)},
+		RefreshToken: &pb.RefreshToken{RefreshToken: string(refreshToken)},
+	}, nil
+}
+
+// EnrollTOTP implements GophkeeperServer interface.
+func (s server) EnrollTOTP(ctx context.Context, r *pb.EnrollTOTPRequest) (*pb.TOTPEnrollment, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := srpProof(r.Proof)
+	if err != nil {
+		return nil, err
+	}
+	attempt, err := s.reserveAttempt(ctx, confirmationKeys(ctx, userID)...)
+	if err != nil {
+		return nil, err
+	}
+	defer attempt.release()
+	uri, recoveryCodes, err := s.users.EnrollTOTP(ctx, userID, proof)
+	if err != nil {
+		if errors.Is(err, passhash.ErrMismatchedHashAndPassword) {
+			attempt.fail()
+		}
+		return nil, mfaError(err)
+	}
+	s.resetFailures(ctx, throttle.PasswordKey(userID))
+
+	return &pb.TOTPEnrollment{
+		OtpauthUri:    uri,
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// ConfirmTOTP implements GophkeeperServer interface.
+func (s server) ConfirmTOTP(ctx context.Context, r *pb.ConfirmTOTPRequest) (*empty.Empty, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mfaKey := throttle.MFAKey(userID)
+	attempt, err := s.reserveAttempt(ctx, append(authKeys("", clientInfo(ctx, nil).RemoteAddr), mfaKey)...)
+	if err != nil {
+		return nil, err
+	}
+	defer attempt.release()
+	if err := s.users.ConfirmTOTP(ctx, userID, r.Code); err != nil {
+		if errors.Is(err, users.ErrInvalidOTPCode) {
+			attempt.fail()
+		}
+		return nil, mfaError(err)
+	}
+	s.resetFailures(ctx, mfaKey)
+
+	return &empty.Empty{}, nil
+}
+
+// DisableTOTP implements GophkeeperServer interface.
+func (s server) DisableTOTP(ctx context.Context, r *pb.DisableTOTPRequest) (*empty.Empty, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := srpProof(r.Proof)
+	if err != nil {
+		return nil, err
+	}
+	mfaKey := throttle.MFAKey(userID)
+	attempt, err := s.reserveAttempt(ctx, append(confirmationKeys(ctx, userID), mfaKey)...)
+	if err != nil {
+		return nil, err
+	}
+	defer attempt.release()
+	if err := s.users.DisableTOTP(ctx, userID, proof, r.Code); err != nil {
+		if errors.Is(err, users.ErrInvalidOTPCode) || errors.Is(err, passhash.ErrMismatchedHashAndPassword) {
+			attempt.fail()
+		}
+		return nil, mfaError(err)
+	}
+	s.resetFailures(ctx, mfaKey, throttle.PasswordKey(userID))
+
+	return &empty.Empty{}, nil
+}
+
+// mfaError converts the errors of two-factor authentication operations to gRPC status.
+func mfaError(err error) error {
+	switch {
+	case errors.Is(err, users.ErrIncorrectMFAChallenge),
+		errors.Is(err, users.ErrInvalidOTPCode):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, passhash.ErrMismatchedHashAndPassword):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, users.ErrMFAAlreadyEnabled),
+		errors.Is(err, users.ErrMFANotEnabled),
+		errors.Is(err, users.ErrMFANotEnrolled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, storage.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vanamelnik/gophkeeper/pkg/passhash"
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+	"github.com/vanamelnik/gophkeeper/server/throttle"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RestoreVault implements GophkeeperServer interface.
+func (s server) RestoreVault(ctx context.Context, r *pb.RestoreVaultRequest) (*pb.RestoreVaultResponse, error) {
+	userID, err := s.syncUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := srpProof(r.Proof)
+	if err != nil {
+		return nil, err
+	}
+	attempt, err := s.reserveAttempt(ctx, confirmationKeys(ctx, userID)...)
+	if err != nil {
+		return nil, err
+	}
+	defer attempt.release()
+	if err := s.users.ConfirmPassword(ctx, userID, proof); err != nil {
+		if errors.Is(err, passhash.ErrMismatchedHashAndPassword) {
+			attempt.fail()
+		}
+		return nil, passwordProofError(err)
+	}
+	s.resetFailures(ctx, throttle.PasswordKey(userID))
+	dataVersion, changed, err := s.gophkeeper.RestoreVault(ctx, userID, sessionIDFromContext(ctx), r.DataVersion)
+	if err != nil {
+		if errors.Is(err, storage.ErrSnapshotNotRetained) {
+			return nil, reasonError(codes.FailedPrecondition, pb.ErrorReason_SNAPSHOT_NOT_RETAINED, "the data version is not retained in the history")
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.RestoreVaultResponse{
+		DataVersion:  dataVersion,
+		ChangedItems: uint32(changed),
+	}, nil
+}
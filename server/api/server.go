@@ -1,8 +1,11 @@
 package api
 
 import (
+	"time"
+
 	pb "github.com/vanamelnik/gophkeeper/proto"
 	"github.com/vanamelnik/gophkeeper/server/gophkeeper"
+	"github.com/vanamelnik/gophkeeper/server/throttle"
 	"github.com/vanamelnik/gophkeeper/server/users"
 	"google.golang.org/grpc"
 )
@@ -11,15 +14,57 @@ import (
 type server struct {
 	users      users.Service
 	gophkeeper gophkeeper.Service
+	// limiter protects the authentication methods from brute-force attacks.
+	limiter throttle.Limiter
+	// watchHeartbeat is the interval of heartbeats sent to the idle Watch streams.
+	watchHeartbeat time.Duration
+	// passwordResetRate limits the password reset requests per email and per remote address.
+	passwordResetRate throttle.Rate
 
 	pb.UnimplementedGophkeeperServer
 }
 
-func NewServer(u users.Service, g gophkeeper.Service) *grpc.Server {
-	s := grpc.NewServer()
-	pb.RegisterGophkeeperServer(s, &server{
-		users:      u,
-		gophkeeper: g,
-	})
+const defaultWatchHeartbeat = 30 * time.Second
+
+var defaultPasswordResetRate = throttle.Rate{Limit: 5, Window: time.Hour}
+
+// Option is a functional option for the server.
+type Option func(s *server)
+
+func NewServer(u users.Service, g gophkeeper.Service, l throttle.Limiter, opts ...Option) *grpc.Server {
+	srv := &server{
+		users:             u,
+		gophkeeper:        g,
+		limiter:           l,
+		watchHeartbeat:    defaultWatchHeartbeat,
+		passwordResetRate: defaultPasswordResetRate,
+	}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(srv.unaryAuthInterceptor),
+		grpc.ChainStreamInterceptor(srv.streamAuthInterceptor),
+	)
+	pb.RegisterGophkeeperServer(s, srv)
 	return s
 }
+
+// WithWatchHeartbeat sets the interval of heartbeats sent to the idle Watch streams.
+func WithWatchHeartbeat(d time.Duration) Option {
+	return func(s *server) {
+		if d > 0 {
+			s.watchHeartbeat = d
+		}
+	}
+}
+
+// WithPasswordResetRate sets the number of the password reset requests allowed for an email
+// or a remote address in the time window.
+func WithPasswordResetRate(limit int, window time.Duration) Option {
+	return func(s *server) {
+		if limit > 0 && window > 0 {
+			s.passwordResetRate = throttle.Rate{Limit: limit, Window: window}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ListSessions implements GophkeeperServer interface.
+func (s server) ListSessions(ctx context.Context, r *pb.ListSessionsRequest) (*pb.SessionList, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	currentSessionID := sessionIDFromContext(ctx)
+	sessions, err := s.users.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	pbSessions := make([]*pb.Session, 0, len(sessions))
+	for _, session := range sessions {
+		pbSession := &pb.Session{
+			SessionId:     session.ID.String(),
+			ClientName:    session.Client.Name,
+			ClientVersion: session.Client.Version,
+			RemoteAddr:    session.Client.RemoteAddr,
+			Current:       session.ID == currentSessionID,
+		}
+		if session.LoginAt != nil {
+			pbSession.LoginAt = timestamppb.New(*session.LoginAt)
+		}
+		pbSessions = append(pbSessions, pbSession)
+	}
+
+	return &pb.SessionList{Sessions: pbSessions}, nil
+}
+
+// LogoutSession implements GophkeeperServer interface.
+func (s server) LogoutSession(ctx context.Context, r *pb.LogoutSessionRequest) (*empty.Empty, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := uuid.Parse(r.SessionId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.users.LogoutSession(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &empty.Empty{}, nil
+}
+
+// LogoutAllSessions implements GophkeeperServer interface.
+func (s server) LogoutAllSessions(ctx context.Context, r *pb.LogoutAllSessionsRequest) (*empty.Empty, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.LogoutAll(ctx, userID); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &empty.Empty{}, nil
+}
+
+// clientInfo builds the description of the client from the data reported by the client
+// and the remote address of the connection.
+func clientInfo(ctx context.Context, info *pb.ClientInfo) models.ClientInfo {
+	client := models.ClientInfo{}
+	if info != nil {
+		client.Name = info.Name
+		client.Version = info.Version
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		client.RemoteAddr = p.Addr.String()
+	}
+	return client
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/pkg/passhash"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+	"github.com/vanamelnik/gophkeeper/server/throttle"
+	"github.com/vanamelnik/gophkeeper/server/users"
+
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SRPSignUp implements GophkeeperServer interface.
+func (s server) SRPSignUp(ctx context.Context, r *pb.SRPSignUpRequest) (*pb.UserAuth, error) {
+	client := clientInfo(ctx, r.ClientInfo)
+	signUpKey := throttle.SignUpKey(client.RemoteAddr)
+	attempt, err := s.reserveAttempt(ctx, signUpKey)
+	if err != nil {
+		return nil, err
+	}
+	attempt.fail()
+
+	userID, err := s.users.CreateSRPUser(ctx, r.Email, r.Salt, r.Verifier)
+	if err != nil {
+		if errors.Is(err, users.ErrInvalidSRPVerifier) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, users.ErrInvalidEmail) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	accessToken, refreshToken, err := s.users.CreateSession(ctx, userID, client)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.UserAuth{
+		AccessToken:  &pb.AccessToken{AccessToken: string(accessToken)},
+		RefreshToken: &pb.RefreshToken{RefreshToken: string(refreshToken)},
+	}, nil
+}
+
+// SRPLogInStart implements GophkeeperServer interface.
+func (s server) SRPLogInStart(ctx context.Context, r *pb.SRPLogInStartRequest) (*pb.SRPChallenge, error) {
+	keys := authKeys(r.Email, clientInfo(ctx, nil).RemoteAddr)
+	attempt, err := s.reserveAttempt(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	defer attempt.release()
+	challenge, err := s.users.StartSRPLogin(ctx, r.Email, r.ClientPublic, r.Undelete)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.SRPChallenge{
+		HandshakeId:  challenge.HandshakeID.String(),
+		Salt:         challenge.Salt,
+		ServerPublic: challenge.ServerPublic,
+	}, nil
+}
+
+// SRPLogInFinish implements GophkeeperServer interface.
+func (s server) SRPLogInFinish(ctx context.Context, r *pb.SRPLogInFinishRequest) (*pb.SRPLogInResult, error) {
+	client := clientInfo(ctx, r.ClientInfo)
+	keys := authKeys(r.Email, client.RemoteAddr)
+	attempt, err := s.reserveAttempt(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	defer attempt.release()
+	proof, err := srpProof(&pb.SRPProof{HandshakeId: r.HandshakeId, ClientProof: r.ClientProof})
+	if err != nil {
+		return nil, err
+	}
+	serverProof, accessToken, refreshToken, err := s.users.FinishSRPLogin(ctx, r.Email, proof, client)
+	if err != nil {
+		var mfaErr users.ErrMFARequired
+		if errors.As(err, &mfaErr) {
+			s.resetFailures(ctx, throttle.EmailKey(r.Email))
+			return &pb.SRPLogInResult{
+				ServerProof: serverProof,
+				Auth:        &pb.UserAuth{MfaChallenge: string(mfaErr.Challenge)},
+			}, nil
+		}
+		if errors.Is(err, passhash.ErrMismatchedHashAndPassword) {
+			attempt.fail()
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if errors.Is(err, users.ErrInvalidSRPVerifier) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	s.resetFailures(ctx, throttle.EmailKey(r.Email))
+
+	return &pb.SRPLogInResult{
+		ServerProof: serverProof,
+		Auth: &pb.UserAuth{
+			AccessToken:  &pb.AccessToken{AccessToken: string(accessToken)},
+			RefreshToken: &pb.RefreshToken{RefreshToken: string(refreshToken)},
+		},
+	}, nil
+}
+
+// srpProof converts the proof of the password from the request. The error returned is already converted
+// to gRPC status.
+func srpProof(p *pb.SRPProof) (models.SRPProof, error) {
+	handshakeID, err := uuid.Parse(p.GetHandshakeId())
+	if err != nil {
+		return models.SRPProof{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return models.SRPProof{HandshakeID: handshakeID, ClientProof: p.GetClientProof()}, nil
+}
+
+// passwordProofError converts the errors of the operations confirmed by the password to gRPC status.
+func passwordProofError(err error) error {
+	switch {
+	case errors.Is(err, passhash.ErrMismatchedHashAndPassword):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, users.ErrInvalidSRPVerifier):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, storage.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/server/throttle"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/vanamelnik/gophkeeper/proto"
+)
+
+// authKeys returns the keys of the failed attempts counters for the authentication request.
+// Empty email or remote address is skipped.
+func authKeys(email, remoteAddr string) []string {
+	keys := make([]string, 0, 2)
+	if email != "" {
+		keys = append(keys, throttle.EmailKey(email))
+	}
+	if remoteAddr != "" {
+		keys = append(keys, throttle.IPKey(remoteAddr))
+	}
+	return keys
+}
+
+// confirmationKeys returns the keys of the failed attempts counters for the password confirmation
+// of the signed-in user.
+func confirmationKeys(ctx context.Context, userID uuid.UUID) []string {
+	return append(authKeys("", clientInfo(ctx, nil).RemoteAddr), throttle.PasswordKey(userID))
+}
+
+// authAttempt is the authentication attempt reserved in the limiter before the credentials are verified.
+type authAttempt struct {
+	s    server
+	ctx  context.Context
+	keys []string
+	done bool
+}
+
+// reserveAttempt reserves the attempt for the keys provided. The error returned is already converted
+// to gRPC status. The attempt must be completed by fail or release; release is no-op after fail,
+// so it can be deferred right after the reservation.
+func (s server) reserveAttempt(ctx context.Context, keys ...string) (*authAttempt, error) {
+	if err := s.limiter.Reserve(ctx, keys...); err != nil {
+		return nil, throttleError(err)
+	}
+	return &authAttempt{s: s, ctx: ctx, keys: keys}, nil
+}
+
+// fail registers the failure of the attempt.
+func (a *authAttempt) fail() {
+	if a.done {
+		return
+	}
+	a.done = true
+	a.s.registerFailure(a.ctx, a.keys...)
+}
+
+// release completes the attempt without registering the failure.
+func (a *authAttempt) release() {
+	if a.done {
+		return
+	}
+	a.done = true
+	if err := a.s.limiter.Release(a.ctx, a.keys...); err != nil {
+		log.Printf("api: %s", err)
+	}
+}
+
+// registerFailure registers the failed attempt. The errors are only logged,
+// because they must not change the response to the client.
+func (s server) registerFailure(ctx context.Context, keys ...string) {
+	if err := s.limiter.Fail(ctx, keys...); err != nil {
+		log.Printf("api: %s", err)
+	}
+}
+
+// resetFailures removes the failed attempts counters after successful authentication.
+func (s server) resetFailures(ctx context.Context, keys ...string) {
+	if err := s.limiter.Reset(ctx, keys...); err != nil {
+		log.Printf("api: %s", err)
+	}
+}
+
+// throttleError converts the limiter error to gRPC status. ErrTooManyAttempts is converted
+// to ResourceExhausted status with TOO_MANY_ATTEMPTS reason and RetryInfo detail.
+func throttleError(err error) error {
+	var tooMany throttle.ErrTooManyAttempts
+	if !errors.As(err, &tooMany) {
+		return status.Error(codes.Internal, err.Error())
+	}
+	st, detErr := reasonStatus(codes.ResourceExhausted, pb.ErrorReason_TOO_MANY_ATTEMPTS, err.Error())
+	if detErr == nil {
+		st, detErr = st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(tooMany.RetryAfter)})
+	}
+	if detErr != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return st.Err()
+}
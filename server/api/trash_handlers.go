@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListTrash implements GophkeeperServer interface.
+func (s server) ListTrash(ctx context.Context, r *pb.ListTrashRequest) (*pb.TrashList, error) {
+	userID, err := s.syncUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.gophkeeper.ListTrash(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	pbItems := make([]*pb.Item, 0, len(items))
+	for _, item := range items {
+		pbItems = append(pbItems, models.ItemToPb(item))
+	}
+
+	return &pb.TrashList{Items: pbItems}, nil
+}
+
+// RestoreItem implements GophkeeperServer interface.
+func (s server) RestoreItem(ctx context.Context, r *pb.RestoreItemRequest) (*pb.RestoreItemResponse, error) {
+	userID, err := s.syncUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := uuid.Parse(r.ItemId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	item, dataVersion, err := s.gophkeeper.RestoreItem(ctx, userID, sessionIDFromContext(ctx), itemID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "the item is not found in the trash")
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.RestoreItemResponse{
+		Item:        models.ItemToPb(item),
+		DataVersion: dataVersion,
+	}, nil
+}
+
+// PurgeItem implements GophkeeperServer interface.
+func (s server) PurgeItem(ctx context.Context, r *pb.PurgeItemRequest) (*empty.Empty, error) {
+	userID, err := s.syncUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := uuid.Parse(r.ItemId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.gophkeeper.PurgeItem(ctx, userID, sessionIDFromContext(ctx), itemID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "the item is not found in the trash")
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &empty.Empty{}, nil
+}
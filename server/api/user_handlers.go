@@ -6,8 +6,9 @@ import (
 
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/vanamelnik/gophkeeper/models"
-	"github.com/vanamelnik/gophkeeper/pkg/bcrypt"
+	"github.com/vanamelnik/gophkeeper/pkg/passhash"
 	"github.com/vanamelnik/gophkeeper/server/storage"
+	"github.com/vanamelnik/gophkeeper/server/throttle"
 	"github.com/vanamelnik/gophkeeper/server/users"
 
 	pb "github.com/vanamelnik/gophkeeper/proto"
@@ -15,22 +16,33 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-// SignUp implements GophkeeperServer interface.
-func (s server) SignUp(ctx context.Context, data *pb.SignInData) (*pb.UserAuth, error) {
-	pwHash, err := bcrypt.BcryptPassword(data.UserPassword)
+// LogOut implements GophkeeperServer interface.
+func (s server) LogOut(ctx context.Context, rt *pb.RefreshToken) (*empty.Empty, error) {
+	sessionID, err := s.users.GetSessionID(models.RefreshToken(rt.RefreshToken))
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	userID, err := s.users.CreateUser(ctx, data.Email, pwHash)
-	if err != nil {
-		if errors.Is(err, storage.ErrAlreadyExists) {
-			return nil, status.Error(codes.AlreadyExists, err.Error())
+	if err := s.users.Logout(ctx, sessionID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
 		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
-	accessToken, refreshToken, err := s.users.CreateSession(ctx, userID)
+
+	return &empty.Empty{}, nil
+}
+
+// GetNewTokens implements GophkeeperServer interface.
+func (s server) GetNewTokens(ctx context.Context, rt *pb.RefreshToken) (*pb.UserAuth, error) {
+	accessToken, refreshToken, err := s.users.RefreshTheTokens(ctx, models.RefreshToken(rt.RefreshToken), clientInfo(ctx, nil))
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		// logout if refresh token is expired
+		if errors.Is(err, users.ErrRefreshTokenExpired) {
+			sessionID, _ := s.users.GetSessionID(models.RefreshToken(rt.RefreshToken))
+			// nolint: errcheck
+			s.users.Logout(ctx, sessionID)
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
 	}
 
 	return &pb.UserAuth{
@@ -39,53 +51,91 @@ func (s server) SignUp(ctx context.Context, data *pb.SignInData) (*pb.UserAuth,
 	}, nil
 }
 
-// LogIn implements GophkeeperServer interface.
-func (s server) LogIn(ctx context.Context, data *pb.SignInData) (*pb.UserAuth, error) {
-	accessToken, refreshToken, err := s.users.Login(ctx, data.Email, data.UserPassword)
+// ChangePassword implements GophkeeperServer interface.
+func (s server) ChangePassword(ctx context.Context, r *pb.ChangePasswordRequest) (*empty.Empty, error) {
+	userID, err := userIDFromContext(ctx)
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return nil, status.Error(codes.Unauthenticated, err.Error())
-		}
-		if errors.Is(err, storage.ErrNotFound) {
-			return nil, status.Error(codes.NotFound, err.Error())
+		return nil, err
+	}
+	// the surviving session is the one of the verified access token, not the one the client claims
+	sessionID := sessionIDFromContext(ctx)
+	proof, err := srpProof(r.Proof)
+	if err != nil {
+		return nil, err
+	}
+	attempt, err := s.reserveAttempt(ctx, confirmationKeys(ctx, userID)...)
+	if err != nil {
+		return nil, err
+	}
+	defer attempt.release()
+	if err := s.users.ChangePassword(ctx, userID, sessionID, proof, r.NewSalt, r.NewVerifier); err != nil {
+		if errors.Is(err, passhash.ErrMismatchedHashAndPassword) {
+			attempt.fail()
 		}
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, passwordProofError(err)
 	}
+	s.resetFailures(ctx, throttle.PasswordKey(userID))
 
-	return &pb.UserAuth{
-		AccessToken:  &pb.AccessToken{AccessToken: string(accessToken)},
-		RefreshToken: &pb.RefreshToken{RefreshToken: string(refreshToken)},
-	}, nil
+	return &empty.Empty{}, nil
 }
 
-// LogOut implements GophkeeperServer interface.
-func (s server) LogOut(ctx context.Context, rt *pb.RefreshToken) (*empty.Empty, error) {
-	sessionID, err := s.users.GetSessionID(models.RefreshToken(rt.RefreshToken))
+// DeleteUser implements GophkeeperServer interface.
+func (s server) DeleteUser(ctx context.Context, r *pb.DeleteUserRequest) (*empty.Empty, error) {
+	userID, err := userIDFromContext(ctx)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, err
 	}
-	if err := s.users.Logout(ctx, sessionID); err != nil {
-		if errors.Is(err, storage.ErrNotFound) {
-			return nil, status.Error(codes.NotFound, err.Error())
+	proof, err := srpProof(r.Proof)
+	if err != nil {
+		return nil, err
+	}
+	attempt, err := s.reserveAttempt(ctx, confirmationKeys(ctx, userID)...)
+	if err != nil {
+		return nil, err
+	}
+	defer attempt.release()
+	if err := s.users.DeleteUser(ctx, userID, proof); err != nil {
+		if errors.Is(err, passhash.ErrMismatchedHashAndPassword) {
+			attempt.fail()
 		}
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, passwordProofError(err)
 	}
+	s.resetFailures(ctx, throttle.PasswordKey(userID))
 
 	return &empty.Empty{}, nil
 }
 
-// GetNewTokens implements GophkeeperServer interface.
-func (s server) GetNewTokens(ctx context.Context, rt *pb.RefreshToken) (*pb.UserAuth, error) {
-	accessToken, refreshToken, err := s.users.RefreshTheTokens(ctx, models.RefreshToken(rt.RefreshToken))
+// UndeleteUser implements GophkeeperServer interface.
+func (s server) UndeleteUser(ctx context.Context, r *pb.UndeleteUserRequest) (*pb.UserAuth, error) {
+	client := clientInfo(ctx, r.ClientInfo)
+	proof, err := srpProof(r.Proof)
 	if err != nil {
-		// logout if refresh token is expired
-		if errors.Is(err, users.ErrRefreshTokenExpired) {
-			sessionID, _ := s.users.GetSessionID(models.RefreshToken(rt.RefreshToken))
-			// nolint: errcheck
-			s.users.Logout(ctx, sessionID)
+		return nil, err
+	}
+	keys := authKeys(r.Email, client.RemoteAddr)
+	attempt, err := s.reserveAttempt(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	defer attempt.release()
+	accessToken, refreshToken, err := s.users.UndeleteUser(ctx, r.Email, proof, client)
+	if err != nil {
+		var mfaErr users.ErrMFARequired
+		if errors.As(err, &mfaErr) {
+			s.resetFailures(ctx, throttle.EmailKey(r.Email))
+			return &pb.UserAuth{MfaChallenge: string(mfaErr.Challenge)}, nil
 		}
-		return nil, status.Error(codes.Unauthenticated, err.Error())
+		if errors.Is(err, passhash.ErrMismatchedHashAndPassword) {
+			attempt.fail()
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, users.ErrGracePeriodExpired) {
+			attempt.fail()
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
 	}
+	s.resetFailures(ctx, throttle.EmailKey(r.Email))
 
 	return &pb.UserAuth{
 		AccessToken:  &pb.AccessToken{AccessToken: string(accessToken)},
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	pb "github.com/vanamelnik/gophkeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Watch implements GophkeeperServer interface.
+func (s server) Watch(r *pb.WatchRequest, stream pb.Gophkeeper_WatchServer) error {
+	ctx := stream.Context()
+	userID, err := s.syncUserID(ctx)
+	if err != nil {
+		return err
+	}
+	sessionID := sessionIDFromContext(ctx)
+
+	// subscribe before reading the version, so no change is lost in between
+	changes, cancel := s.gophkeeper.Watch(userID)
+	defer cancel()
+
+	dataVersion, err := s.users.GetDataVersion(ctx, userID)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if err := stream.Send(dataChangedEvent(models.DataChange{UserID: userID, DataVersion: dataVersion})); err != nil {
+		return err
+	}
+
+	heartbeat := time.NewTicker(s.watchHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case change := <-changes:
+			if err := stream.Send(dataChangedEvent(change)); err != nil {
+				return err
+			}
+		case t := <-heartbeat.C:
+			// the access token is checked only once, so the logout is detected here
+			active, err := s.users.IsSessionActive(ctx, sessionID)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			if !active {
+				return reasonError(codes.Unauthenticated, pb.ErrorReason_TOKEN_INVALID, "the session is logged out")
+			}
+			if err := stream.Send(&pb.WatchEvent{
+				Event: &pb.WatchEvent_Heartbeat{Heartbeat: &pb.Heartbeat{Time: timestamppb.New(t)}},
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func dataChangedEvent(change models.DataChange) *pb.WatchEvent {
+	dc := &pb.DataChanged{DataVersion: change.DataVersion}
+	if change.SessionID != uuid.Nil {
+		dc.SessionId = change.SessionID.String()
+	}
+	return &pb.WatchEvent{Event: &pb.WatchEvent_DataChanged{DataChanged: dc}}
+}
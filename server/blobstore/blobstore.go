@@ -0,0 +1,77 @@
+package blobstore
+
+// Package blobstore contains the stores of the blob content addressed by its SHA-256 hash.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("blob content not found")
+	// ErrHashMismatch means that the content doesn't match the hash or the size it's stored under.
+	ErrHashMismatch = errors.New("blob content doesn't match its hash")
+	ErrInvalidHash  = errors.New("invalid blob content hash")
+)
+
+// BlobStore stores the blob content by the hex encoded SHA-256 hash of the content.
+type BlobStore interface {
+	// Put stores the content of the size provided read from r. The content is verified while it's stored:
+	// if it doesn't match the hash or the size, nothing is stored and ErrHashMismatch returns.
+	// The content is verified even if it's already stored, so a successful Put proves that
+	// the caller has the content, not only its hash.
+	Put(ctx context.Context, hash string, size int64, r io.Reader) error
+	// Get returns the reader of the content starting at the offset provided.
+	// If there is no such content, ErrNotFound returns.
+	Get(ctx context.Context, hash string, offset int64) (io.ReadCloser, error)
+	// Stat returns the information about the content. If there is no such content, ErrNotFound returns.
+	Stat(ctx context.Context, hash string) (Info, error)
+	// Delete removes the content. Deleting the content that doesn't exist is not an error.
+	Delete(ctx context.Context, hash string) error
+}
+
+// Info describes the stored content.
+type Info struct {
+	Hash     string
+	Size     int64
+	StoredAt time.Time
+}
+
+// Config specifies the blob store to create.
+type Config struct {
+	// Backend is "fs", "s3" or empty: the content is kept in the database then.
+	Backend string
+	// Dir is the root directory of the fs store.
+	Dir string
+	S3  S3Config
+}
+
+// New creates the blob store specified by the config. If no backend is set, nil returns.
+func New(cfg Config) (BlobStore, error) {
+	switch cfg.Backend {
+	case "fs":
+		return NewFS(cfg.Dir)
+	case "s3":
+		return NewS3(cfg.S3)
+	case "":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("blobstore: unknown backend %q", cfg.Backend)
+}
+
+// validHash checks that the hash is a lowercase hex encoded SHA-256 hash, so it's safe to be used
+// as a file name or an object key.
+func validHash(hash string) error {
+	if len(hash) != 64 {
+		return ErrInvalidHash
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return ErrInvalidHash
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FS stores the content in the files of the local filesystem. The files are sharded into
+// subdirectories by the first bytes of the hash: <root>/ab/cd/abcd...
+// The content is written into a temporary file first and renamed when it's verified,
+// so a partially written content is never visible.
+type FS struct {
+	root string
+}
+
+var _ BlobStore = (*FS)(nil)
+
+// NewFS creates the filesystem store in the root directory provided.
+func NewFS(root string) (*FS, error) {
+	if root == "" {
+		return nil, errors.New("blobstore: newFS: root directory is not set")
+	}
+	if err := os.MkdirAll(filepath.Join(root, "tmp"), 0o700); err != nil {
+		return nil, fmt.Errorf("blobstore: newFS: %w", err)
+	}
+	return &FS{root: root}, nil
+}
+
+// Put implements BlobStore interface.
+func (s *FS) Put(ctx context.Context, hash string, size int64, r io.Reader) error {
+	if err := validHash(hash); err != nil {
+		return err
+	}
+	path := s.path(hash)
+	tmp, err := os.CreateTemp(filepath.Join(s.root, "tmp"), hash+"-*")
+	if err != nil {
+		return fmt.Errorf("blobstore: put: %w", err)
+	}
+	// the temporary file is removed unless it's renamed
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(&ctxReader{ctx: ctx, r: r}, size+1))
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("blobstore: put: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("blobstore: put: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("blobstore: put: %w", err)
+	}
+	if n != size || hex.EncodeToString(h.Sum(nil)) != hash {
+		return ErrHashMismatch
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("blobstore: put: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("blobstore: put: %w", err)
+	}
+	return nil
+}
+
+// Get implements BlobStore interface.
+func (s *FS) Get(ctx context.Context, hash string, offset int64) (io.ReadCloser, error) {
+	if err := validHash(hash); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(s.path(hash))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("blobstore: get: %w", err)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("blobstore: get: %w", err)
+	}
+	return f, nil
+}
+
+// Stat implements BlobStore interface.
+func (s *FS) Stat(ctx context.Context, hash string) (Info, error) {
+	if err := validHash(hash); err != nil {
+		return Info{}, err
+	}
+	fi, err := os.Stat(s.path(hash))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Info{}, ErrNotFound
+		}
+		return Info{}, fmt.Errorf("blobstore: stat: %w", err)
+	}
+	return Info{Hash: hash, Size: fi.Size(), StoredAt: fi.ModTime()}, nil
+}
+
+// Delete implements BlobStore interface.
+func (s *FS) Delete(ctx context.Context, hash string) error {
+	if err := validHash(hash); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("blobstore: delete: %w", err)
+	}
+	return nil
+}
+
+// path returns the path of the file with the content.
+func (s *FS) path(hash string) string {
+	return filepath.Join(s.root, hash[0:2], hash[2:4], hash)
+}
+
+// ctxReader stops reading when the context is canceled.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
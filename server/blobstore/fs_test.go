@@ -0,0 +1,80 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hashOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// testStore checks the behaviour common for all the stores.
+func testStore(t *testing.T, s BlobStore) {
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("gophkeeper"), 1000)
+	hash := hashOf(content)
+
+	_, err := s.Stat(ctx, hash)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Get(ctx, hash, 0)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// corrupted content is not stored
+	corrupted := append([]byte{}, content...)
+	corrupted[0] = 'G'
+	assert.ErrorIs(t, s.Put(ctx, hash, int64(len(corrupted)), bytes.NewReader(corrupted)), ErrHashMismatch)
+	_, err = s.Stat(ctx, hash)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, s.Put(ctx, hash, int64(len(content)), bytes.NewReader(content)))
+	info, err := s.Stat(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), info.Size)
+	// the content already stored is verified again
+	require.NoError(t, s.Put(ctx, hash, int64(len(content)), bytes.NewReader(content)))
+	assert.ErrorIs(t, s.Put(ctx, hash, int64(len(corrupted)), bytes.NewReader(corrupted)), ErrHashMismatch)
+
+	for _, offset := range []int64{0, 1234, int64(len(content))} {
+		r, err := s.Get(ctx, hash, offset)
+		require.NoError(t, err)
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		assert.Equal(t, content[offset:], got)
+	}
+
+	require.NoError(t, s.Delete(ctx, hash))
+	_, err = s.Stat(ctx, hash)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, s.Delete(ctx, hash))
+
+	assert.ErrorIs(t, s.Put(ctx, "../../etc/passwd", 0, bytes.NewReader(nil)), ErrInvalidHash)
+}
+
+func TestFS(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewFS(root)
+	require.NoError(t, err)
+	testStore(t, s)
+
+	// the content is sharded and no temporary files are left
+	content := []byte("sharded")
+	hash := hashOf(content)
+	require.NoError(t, s.Put(context.Background(), hash, int64(len(content)), bytes.NewReader(content)))
+	_, err = os.Stat(filepath.Join(root, hash[0:2], hash[2:4], hash))
+	assert.NoError(t, err)
+	tmp, err := os.ReadDir(filepath.Join(root, "tmp"))
+	require.NoError(t, err)
+	assert.Empty(t, tmp)
+}
@@ -0,0 +1,256 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is SHA-256 hash of the empty request body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config contains the parameters of S3-compatible object storage.
+type S3Config struct {
+	// Endpoint is the URL of the storage, e.g. "https://s3.eu-central-1.amazonaws.com" or "http://localhost:9000".
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Prefix is prepended to the keys of the objects.
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3 stores the content as the objects of S3-compatible storage. The bucket is addressed in the path
+// of the URL and the requests are signed with AWS Signature Version 4. The SHA-256 hash of the content
+// is sent with the upload, so the storage itself rejects a corrupted content.
+type S3 struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	// now returns the time of the request signing.
+	now func() time.Time
+}
+
+var _ BlobStore = (*S3)(nil)
+
+// NewS3 creates S3 store with the config provided.
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("blobstore: newS3: bucket is not set")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("blobstore: newS3: %w", err)
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("blobstore: newS3: invalid endpoint %q", cfg.Endpoint)
+	}
+	return &S3{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{},
+		now:      time.Now,
+	}, nil
+}
+
+// Put implements BlobStore interface.
+func (s *S3) Put(ctx context.Context, hash string, size int64, r io.Reader) error {
+	req, err := s.newRequest(ctx, http.MethodPut, hash, io.LimitReader(r, size))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	s.sign(req, hash)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("blobstore: put: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest {
+		// the storage has computed another hash of the content
+		if code := errorCode(resp); code == "XAmzContentSHA256Mismatch" || code == "BadDigest" {
+			return ErrHashMismatch
+		}
+	}
+	return checkResponse("put", resp)
+}
+
+// Get implements BlobStore interface.
+func (s *S3) Get(ctx context.Context, hash string, offset int64) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, hash, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	s.sign(req, emptyPayloadHash)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("blobstore: get: %w", err)
+	}
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// the offset is at the end of the content
+		resp.Body.Close()
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	if err := checkResponse("get", resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Stat implements BlobStore interface.
+func (s *S3) Stat(ctx context.Context, hash string) (Info, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, hash, nil)
+	if err != nil {
+		return Info{}, err
+	}
+	s.sign(req, emptyPayloadHash)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Info{}, fmt.Errorf("blobstore: stat: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse("stat", resp); err != nil {
+		return Info{}, err
+	}
+	info := Info{Hash: hash, Size: resp.ContentLength}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.StoredAt = t
+	}
+	return info, nil
+}
+
+// Delete implements BlobStore interface.
+func (s *S3) Delete(ctx context.Context, hash string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, hash, nil)
+	if err != nil {
+		return err
+	}
+	s.sign(req, emptyPayloadHash)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("blobstore: delete: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkResponse("delete", resp)
+}
+
+// newRequest creates the request to the object with the content of the hash provided.
+func (s *S3) newRequest(ctx context.Context, method, hash string, body io.Reader) (*http.Request, error) {
+	if err := validHash(hash); err != nil {
+		return nil, err
+	}
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.cfg.Bucket + "/" + s.cfg.Prefix + hash
+	u.RawPath = ""
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("blobstore: %w", err)
+	}
+	return req, nil
+}
+
+// sign signs the request with AWS Signature Version 4. Only the host and x-amz-* headers are signed.
+func (s *S3) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL.Path),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.cfg.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalURI encodes the path of the request as required by AWS Signature Version 4.
+func canonicalURI(path string) string {
+	if path == "" {
+		return "/"
+	}
+	var b strings.Builder
+	for _, c := range []byte(path) {
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkResponse converts the unsuccessful response of the storage into an error.
+func checkResponse(op string, resp *http.Response) error {
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	}
+	return fmt.Errorf("blobstore: %s: unexpected status %s", op, resp.Status)
+}
+
+// errorCode returns the code of S3 error response: <Error><Code>...</Code></Error>.
+func errorCode(resp *http.Response) string {
+	var e struct {
+		Code string `xml:"Code"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&e); err != nil {
+		return ""
+	}
+	return e.Code
+}
@@ -0,0 +1,117 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is a local stand-in of S3 storage. It checks the request signatures
+// and the payload hashes like the real storage does.
+type fakeS3 struct {
+	sync.Mutex
+	t       *testing.T
+	cfg     S3Config
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.validSignature(r) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+		return
+	}
+	f.Lock()
+	defer f.Unlock()
+	key := r.URL.Path
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		require.NoError(f.t, err)
+		sum := sha256.Sum256(body)
+		if hex.EncodeToString(sum[:]) != r.Header.Get("x-amz-content-sha256") {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "<Error><Code>XAmzContentSHA256Mismatch</Code></Error>")
+			return
+		}
+		f.objects[key] = body
+	case http.MethodGet, http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if rng := r.Header.Get("Range"); rng != "" {
+			offset, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			require.NoError(f.t, err)
+			if offset >= len(obj) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			obj = obj[offset:]
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// validSignature recomputes the signature of the request with the secret key.
+func (f *fakeS3) validSignature(r *http.Request) bool {
+	s := &S3{cfg: f.cfg, now: func() time.Time {
+		t, _ := time.Parse("20060102T150405Z", r.Header.Get("x-amz-date"))
+		return t
+	}}
+	signed := r.Clone(r.Context())
+	signed.URL.Host = r.Host
+	s.sign(signed, r.Header.Get("x-amz-content-sha256"))
+	return signed.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func TestS3(t *testing.T) {
+	cfg := S3Config{
+		Region:          "eu-central-1",
+		Bucket:          "gophkeeper",
+		Prefix:          "blobs/",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+	}
+	fake := &fakeS3{t: t, cfg: cfg, objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	cfg.Endpoint = srv.URL
+	s, err := NewS3(cfg)
+	require.NoError(t, err)
+	testStore(t, s)
+
+	// the objects are kept under the bucket and the prefix
+	content := []byte("s3 object")
+	hash := hashOf(content)
+	require.NoError(t, s.Put(context.Background(), hash, int64(len(content)), bytes.NewReader(content)))
+	require.Contains(t, fake.objects, "/gophkeeper/blobs/"+hash)
+
+	// the requests signed with a wrong key are rejected
+	cfg.SecretAccessKey = "wrong"
+	wrong, err := NewS3(cfg)
+	require.NoError(t, err)
+	_, err = wrong.Stat(context.Background(), hash)
+	require.Error(t, err)
+}
@@ -0,0 +1,227 @@
+package gophkeeper
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/blobstore"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+// GetBlobUpload returns the state of the upload of the user's blob content.
+func (s Service) GetBlobUpload(ctx context.Context, userID uuid.UUID, hash string) (models.BlobUpload, error) {
+	return s.storage.GetBlobUpload(ctx, userID, hash)
+}
+
+// StartBlobUpload starts the upload of the user's blob content or returns the state of the upload
+// already started. The empty content is complete at once.
+func (s Service) StartBlobUpload(ctx context.Context, userID uuid.UUID, hash string, size int64) (models.BlobUpload, error) {
+	upload, err := s.storage.CreateBlobUpload(ctx, userID, hash, size)
+	if err != nil {
+		return models.BlobUpload{}, err
+	}
+	if upload.Size != size {
+		return models.BlobUpload{}, fmt.Errorf("the size of the content %s is %d, not %d", hash, upload.Size, size)
+	}
+	if size == 0 && !upload.Complete {
+		if err := s.CompleteBlobUpload(ctx, userID, hash); err != nil {
+			return models.BlobUpload{}, err
+		}
+		upload.Complete = true
+	}
+	return upload, nil
+}
+
+// AppendBlobChunk stores the next chunk of the user's blob content.
+func (s Service) AppendBlobChunk(ctx context.Context, userID uuid.UUID, hash string, offset int64, data []byte) error {
+	return s.storage.AppendBlobChunk(ctx, userID, hash, offset, data)
+}
+
+// CompleteBlobUpload verifies the hash of the completely received content and marks it as uploaded,
+// so the items may refer to it. If the blob store is set, the content is moved there from the database.
+// If the content is corrupted, it's removed and ErrBlobHashMismatch returns.
+func (s Service) CompleteBlobUpload(ctx context.Context, userID uuid.UUID, hash string) error {
+	upload, err := s.storage.GetBlobUpload(ctx, userID, hash)
+	if err != nil {
+		return err
+	}
+	if s.blobs == nil {
+		h := sha256.New()
+		if _, err := io.Copy(h, newChunkReader(ctx, s.storage, upload, 0)); err != nil {
+			return err
+		}
+		if hex.EncodeToString(h.Sum(nil)) != hash {
+			return s.rejectBlobUpload(ctx, userID, hash)
+		}
+		return s.storage.CompleteBlobUpload(ctx, userID, hash)
+	}
+
+	err = s.blobs.Put(ctx, hash, upload.Size, newChunkReader(ctx, s.storage, upload, 0))
+	if errors.Is(err, blobstore.ErrHashMismatch) {
+		return s.rejectBlobUpload(ctx, userID, hash)
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.storage.CompleteBlobUpload(ctx, userID, hash); err != nil {
+		return err
+	}
+	return s.storage.DeleteBlobChunks(ctx, userID, hash)
+}
+
+// rejectBlobUpload removes the corrupted content and returns ErrBlobHashMismatch.
+func (s Service) rejectBlobUpload(ctx context.Context, userID uuid.UUID, hash string) error {
+	if err := s.storage.DeleteBlobUpload(ctx, userID, hash); err != nil {
+		return err
+	}
+	return ErrBlobHashMismatch
+}
+
+// OpenBlob returns the reader of the user's uploaded blob content starting at the offset.
+// The content that isn't moved to the blob store yet is read from the database.
+func (s Service) OpenBlob(ctx context.Context, upload models.BlobUpload, offset int64) (io.ReadCloser, error) {
+	if s.blobs != nil {
+		r, err := s.blobs.Get(ctx, upload.Hash, offset)
+		if !errors.Is(err, blobstore.ErrNotFound) {
+			return r, err
+		}
+	}
+	return io.NopCloser(newChunkReader(ctx, s.storage, upload, offset)), nil
+}
+
+// MigrateBlobs moves the uploaded blob content kept in the database to the blob store.
+// It returns the number of the contents moved.
+func (s Service) MigrateBlobs(ctx context.Context) (int, error) {
+	if s.blobs == nil {
+		return 0, errors.New("migrateBlobs: the blob store is not set")
+	}
+	uploads, err := s.storage.GetStagedBlobs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("migrateBlobs: %w", err)
+	}
+	moved := 0
+	for _, upload := range uploads {
+		err := s.blobs.Put(ctx, upload.Hash, upload.Size, newChunkReader(ctx, s.storage, upload, 0))
+		if errors.Is(err, blobstore.ErrHashMismatch) {
+			// the content is damaged in the database, it's left there for the investigation
+			log.Printf("migrateBlobs: content %s of user %s doesn't match its hash", upload.Hash, upload.UserID)
+			continue
+		}
+		if err != nil {
+			return moved, fmt.Errorf("migrateBlobs: %w", err)
+		}
+		if err := s.storage.DeleteBlobChunks(ctx, upload.UserID, upload.Hash); err != nil {
+			return moved, fmt.Errorf("migrateBlobs: %w", err)
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// RunBlobCollector periodically removes the user blob contents that are not referred to by any item
+// or item version for the grace period, and the uploads abandoned for that time. The content that no user
+// has anymore is deleted from the blob store. It blocks until the context is canceled.
+func (s Service) RunBlobCollector(ctx context.Context, interval, grace time.Duration) {
+	if interval <= 0 {
+		interval = defaultBlobCollectInterval
+	}
+	if grace <= 0 {
+		grace = defaultBlobGracePeriod
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("blob collector is stopped")
+			return
+		case <-ticker.C:
+			n, err := s.storage.CollectBlobs(ctx, time.Now().Add(-grace))
+			if err != nil {
+				log.Printf("blob collector: %s", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("blob collector: %d unreferenced blob content(s) removed", n)
+			}
+			released, err := s.releaseBlobGarbage(ctx)
+			if err != nil {
+				log.Printf("blob collector: %s", err)
+			}
+			if released > 0 {
+				log.Printf("blob collector: %d blob content(s) deleted from the store", released)
+			}
+		}
+	}
+}
+
+// releaseBlobGarbage deletes from the blob store the queued contents that no user has anymore.
+// It returns the number of the contents deleted.
+func (s Service) releaseBlobGarbage(ctx context.Context) (int, error) {
+	hashes, err := s.storage.GetBlobGarbage(ctx)
+	if err != nil {
+		return 0, err
+	}
+	release := func(string) error { return nil }
+	if s.blobs != nil {
+		release = func(hash string) error { return s.blobs.Delete(ctx, hash) }
+	}
+	n := 0
+	for _, hash := range hashes {
+		hash := hash
+		ok, err := s.storage.ReleaseBlobGarbage(ctx, hash, func() error { return release(hash) })
+		if err != nil {
+			return n, err
+		}
+		if ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// chunkReader reads the blob content from the chunks kept in the database.
+type chunkReader struct {
+	ctx     context.Context
+	storage storage.Storage
+	upload  models.BlobUpload
+	offset  int64
+	buf     []byte
+}
+
+func newChunkReader(ctx context.Context, db storage.Storage, upload models.BlobUpload, offset int64) *chunkReader {
+	return &chunkReader{
+		ctx:     ctx,
+		storage: db,
+		upload:  upload,
+		offset:  offset,
+	}
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.offset >= r.upload.Size {
+			return 0, io.EOF
+		}
+		data, err := r.storage.ReadBlobChunk(r.ctx, r.upload.UserID, r.upload.Hash, r.offset)
+		if err != nil {
+			return 0, err
+		}
+		if len(data) == 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		r.buf = data
+		r.offset += int64(len(data))
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
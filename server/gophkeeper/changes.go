@@ -0,0 +1,52 @@
+package gophkeeper
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+)
+
+const (
+	defaultCompactInterval = time.Hour
+	// defaultChangeLogRetention is the number of the latest data versions kept in the change log.
+	defaultChangeLogRetention = 1000
+)
+
+// GetChangesSince returns the items changed after the data version provided.
+// If the change log is compacted beyond that version, storage.ErrChangeLogCompacted returns
+// and the client must fall back to the full sync.
+func (s Service) GetChangesSince(ctx context.Context, userID uuid.UUID, dataVersion uint64) (*models.UserData, error) {
+	return s.storage.GetChangesSince(ctx, userID, dataVersion)
+}
+
+// RunCompactor periodically removes the old records from the change log leaving
+// only the latest keepVersions data versions of each user. It blocks until the context is canceled.
+func (s Service) RunCompactor(ctx context.Context, interval time.Duration, keepVersions uint64) {
+	if interval <= 0 {
+		interval = defaultCompactInterval
+	}
+	if keepVersions == 0 {
+		keepVersions = defaultChangeLogRetention
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("change log compactor is stopped")
+			return
+		case <-ticker.C:
+			n, err := s.storage.CompactChangeLog(ctx, keepVersions)
+			if err != nil {
+				log.Printf("change log compactor: %s", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("change log compactor: %d record(s) removed", n)
+			}
+		}
+	}
+}
@@ -7,13 +7,17 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/blobstore"
 	"github.com/vanamelnik/gophkeeper/server/storage"
 )
 
 type (
 	// Service represents the main service that implements the business logic of the server.
 	Service struct {
-		storage storage.Storage
+		storage  storage.Storage
+		watchers *watchers
+		// blobs stores the uploaded blob content. If it's nil, the content is kept in the database.
+		blobs blobstore.BlobStore
 
 		eventCh chan eventsPack
 		stopCh  chan struct{}
@@ -22,22 +26,46 @@ type (
 
 	// eventsPack is the pack of events received from the client.
 	eventsPack struct {
-		ctx    context.Context
-		userID uuid.UUID
-		events []models.Event
+		ctx       context.Context
+		userID    uuid.UUID
+		sessionID uuid.UUID
+		events    []models.Event
+		// resultCh receives the outcome of the pack processing.
+		resultCh chan publishResult
+	}
+
+	publishResult struct {
+		result *models.PublishResult
+		err    error
 	}
 )
 
 var (
 	ErrVersionUpToDate = errors.New("data version is up to date")
+	// ErrBlobHashMismatch means that the uploaded blob content doesn't match its hash.
+	ErrBlobHashMismatch = errors.New("blob content doesn't match its hash")
 )
 
-func NewService(db storage.Storage) Service {
+// Option is a functional option for the gophkeeper service.
+type Option func(s *Service)
+
+// WithBlobStore sets the store of the uploaded blob content.
+func WithBlobStore(store blobstore.BlobStore) Option {
+	return func(s *Service) {
+		s.blobs = store
+	}
+}
+
+func NewService(db storage.Storage, opts ...Option) Service {
 	s := Service{
-		storage: db,
-		wg:      &sync.WaitGroup{},
-		eventCh: make(chan eventsPack, 1),
-		stopCh:  make(chan struct{}),
+		storage:  db,
+		watchers: newWatchers(),
+		wg:       &sync.WaitGroup{},
+		eventCh:  make(chan eventsPack, 1),
+		stopCh:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(&s)
 	}
 
 	go s.processor() // TODO: implement a worker pool to limit DB connections
@@ -65,13 +93,30 @@ func (s Service) GetUserData(ctx context.Context, userID uuid.UUID, versionMap m
 	return &updates, nil
 }
 
-// PublishUserData applies local changes of user data to the database.
-func (s Service) PublishUserData(ctx context.Context, userID uuid.UUID, events []models.Event) {
+// PublishUserData applies local changes of user data made in the session provided to the database
+// in one transaction and waits for the outcome. An event that can't be applied doesn't prevent
+// the others from being applied, its error is reported in the result.
+// The watchers of the user are notified after the changes are applied.
+func (s Service) PublishUserData(ctx context.Context, userID, sessionID uuid.UUID, events []models.Event) (*models.PublishResult, error) {
+	p := eventsPack{
+		ctx:       ctx,
+		userID:    userID,
+		sessionID: sessionID,
+		events:    events,
+		resultCh:  make(chan publishResult, 1),
+	}
 	s.wg.Add(1)
-	s.eventCh <- eventsPack{
-		ctx:    ctx,
-		userID: userID,
-		events: events,
+	select {
+	case s.eventCh <- p:
+	case <-ctx.Done():
+		s.wg.Done()
+		return nil, ctx.Err()
+	}
+	select {
+	case r := <-p.resultCh:
+		return r.result, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
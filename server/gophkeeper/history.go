@@ -0,0 +1,58 @@
+package gophkeeper
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+)
+
+const (
+	defaultHistoryPruneInterval = time.Hour
+	defaultHistoryVersions      = 50
+	defaultHistoryMaxAge        = 90 * 24 * time.Hour
+)
+
+// ListItemVersions returns the versions of the user's item kept in the history, the latest first.
+func (s Service) ListItemVersions(ctx context.Context, userID, itemID uuid.UUID) ([]models.ItemVersion, error) {
+	return s.storage.GetItemVersions(ctx, userID, itemID)
+}
+
+// GetItemVersion returns the version of the user's item from the history.
+func (s Service) GetItemVersion(ctx context.Context, userID, itemID uuid.UUID, version uint64) (models.Item, error) {
+	return s.storage.GetItemVersion(ctx, userID, itemID, version)
+}
+
+// RunHistoryPruner periodically removes from the item history the versions beyond keepVersions
+// latest ones and the versions older than maxAge. It blocks until the context is canceled.
+func (s Service) RunHistoryPruner(ctx context.Context, interval time.Duration, keepVersions int, maxAge time.Duration) {
+	if interval <= 0 {
+		interval = defaultHistoryPruneInterval
+	}
+	if keepVersions <= 0 {
+		keepVersions = defaultHistoryVersions
+	}
+	if maxAge <= 0 {
+		maxAge = defaultHistoryMaxAge
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("history pruner is stopped")
+			return
+		case <-ticker.C:
+			n, err := s.storage.PruneItemHistory(ctx, keepVersions, time.Now().Add(-maxAge))
+			if err != nil {
+				log.Printf("history pruner: %s", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("history pruner: %d item version(s) removed", n)
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package gophkeeper
 
 import (
 	"context"
+	"fmt"
 	"log"
 
 	"github.com/google/uuid"
@@ -17,40 +18,68 @@ processorLoop:
 			break processorLoop // TODO: implement graceful shutdown
 		case p := <-s.eventCh:
 			go func(p eventsPack) {
-				if err := s.processUserData(p.ctx, p.userID, p.events); err != nil {
+				result, err := s.processUserData(p.ctx, p.userID, p.sessionID, p.events)
+				p.resultCh <- publishResult{result: result, err: err}
+				if err != nil {
 					log.Printf("gophkeeper processor: %s", err)
 					return
 				}
-				log.Printf("gophkeeper processor: successfully processed %d event(s) of user %v", len(p.events), p.userID)
+				log.Printf("gophkeeper processor: processed %d event(s) of user %v", len(p.events), p.userID)
 			}(p)
 		}
 	}
 	log.Println("GophKeeper processor is stopped")
 }
 
-// processUserData creates a new user transaction and sends user's events into the storage.
-func (s Service) processUserData(ctx context.Context, userID uuid.UUID, events []models.Event) error {
+// processUserData creates a new user transaction, sends user's events into the storage
+// and notifies the watchers about the new data version.
+// If none of the events is applied, the transaction is rolled back and the data version stays the same.
+func (s Service) processUserData(ctx context.Context, userID, sessionID uuid.UUID, events []models.Event) (*models.PublishResult, error) {
 	defer s.wg.Done()
 
 	tx, err := s.storage.NewUserTransaction(ctx, userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// nolint: errcheck
 	defer tx.Rollback()
 
+	result := models.PublishResult{
+		Results: make([]models.EventResult, 0, len(events)),
+	}
+	applied := 0
 	for _, event := range events {
+		r := models.EventResult{ItemID: event.Item.ID}
 		switch event.Operation {
 		case models.OpCreate:
-			if err := tx.CreateItem(ctx, event.Item); err != nil {
-				return err
-			}
+			r.Version, r.Err = tx.CreateItem(ctx, event.Item)
 		case models.OpUpdate:
-			if err := tx.UpdateItem(ctx, event.Item); err != nil {
-				return err
-			}
+			r.Version, r.Err = tx.UpdateItem(ctx, event.Item)
+		case models.OpDelete:
+			r.Version, r.Err = tx.DeleteItem(ctx, event.Item.ID, event.Item.Version)
+		default:
+			r.Err = fmt.Errorf("unsupported operation %s", event.Operation)
+		}
+		if r.Err == nil {
+			applied++
+		}
+		result.Results = append(result.Results, r)
+	}
+
+	if applied == 0 {
+		if result.DataVersion, err = s.storage.GetUserDataVersion(ctx, userID); err != nil {
+			return nil, err
 		}
+		return &result, nil
+	}
+	if result.DataVersion, err = tx.Commit(); err != nil {
+		return nil, err
 	}
+	s.watchers.notify(models.DataChange{
+		UserID:      userID,
+		DataVersion: result.DataVersion,
+		SessionID:   sessionID,
+	})
 
-	return tx.Commit()
+	return &result, nil
 }
@@ -0,0 +1,88 @@
+package gophkeeper
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+)
+
+// RestoreVault brings all the items of the user back to the state they had in the data version provided.
+// The restore is recorded as a single new data version, so the other devices of the user receive
+// it as ordinary changes and the restore itself can be undone the same way.
+// It returns the new data version and the number of items changed.
+// If the item history doesn't cover the data version, storage.ErrSnapshotNotRetained returns.
+func (s Service) RestoreVault(ctx context.Context, userID, sessionID uuid.UUID, dataVersion uint64) (uint64, int, error) {
+	tx, err := s.storage.NewUserTransaction(ctx, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	snapshot, err := tx.GetSnapshot(ctx, dataVersion)
+	if err != nil {
+		return 0, 0, err
+	}
+	current, err := tx.GetItems(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	targets := make(map[uuid.UUID]models.Item, len(snapshot))
+	for _, item := range snapshot {
+		targets[item.ID] = item
+	}
+
+	changed := 0
+	for _, item := range current {
+		target, ok := targets[item.ID]
+		delete(targets, item.ID)
+		switch {
+		case !ok || target.IsTombstone():
+			// the item is created after the data version or was deleted at that moment
+			if item.IsTombstone() {
+				continue
+			}
+			if _, err := tx.DeleteItem(ctx, item.ID, item.Version); err != nil {
+				return 0, 0, err
+			}
+		case !item.IsTombstone() && item.Meta == target.Meta && reflect.DeepEqual(item.Payload, target.Payload):
+			continue
+		default:
+			item.Payload = target.Payload
+			item.Meta = target.Meta
+			item.DeletedAt = nil
+			if _, err := tx.UpdateItem(ctx, item); err != nil {
+				return 0, 0, err
+			}
+		}
+		changed++
+	}
+	// the items that existed in the data version but are purged since then
+	for _, target := range targets {
+		if target.IsTombstone() {
+			continue
+		}
+		if _, err := tx.CreateItem(ctx, target); err != nil {
+			return 0, 0, err
+		}
+		changed++
+	}
+
+	if changed == 0 {
+		v, err := s.storage.GetUserDataVersion(ctx, userID)
+		return v, 0, err
+	}
+	newVersion, err := tx.Commit()
+	if err != nil {
+		return 0, 0, err
+	}
+	s.watchers.notify(models.DataChange{
+		UserID:      userID,
+		DataVersion: newVersion,
+		SessionID:   sessionID,
+	})
+
+	return newVersion, changed, nil
+}
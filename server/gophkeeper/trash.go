@@ -0,0 +1,94 @@
+package gophkeeper
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+)
+
+const (
+	defaultTrashPurgeInterval  = time.Hour
+	defaultTrashRetention      = 30 * 24 * time.Hour
+	defaultBlobCollectInterval = time.Hour
+	defaultBlobGracePeriod     = 24 * time.Hour
+)
+
+// ListTrash returns the deleted items of the user that are not purged yet.
+func (s Service) ListTrash(ctx context.Context, userID uuid.UUID) ([]models.Item, error) {
+	return s.storage.GetTrash(ctx, userID)
+}
+
+// RestoreItem restores the deleted item of the user and returns the restored item together with
+// the new data version. The watchers of the user are notified about the change.
+func (s Service) RestoreItem(ctx context.Context, userID, sessionID, itemID uuid.UUID) (models.Item, uint64, error) {
+	tx, err := s.storage.NewUserTransaction(ctx, userID)
+	if err != nil {
+		return models.Item{}, 0, err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	item, err := tx.RestoreItem(ctx, itemID)
+	if err != nil {
+		return models.Item{}, 0, err
+	}
+	dataVersion, err := tx.Commit()
+	if err != nil {
+		return models.Item{}, 0, err
+	}
+	s.watchers.notify(models.DataChange{
+		UserID:      userID,
+		DataVersion: dataVersion,
+		SessionID:   sessionID,
+	})
+
+	return item, dataVersion, nil
+}
+
+// PurgeItem permanently erases the deleted item of the user. The watchers of the user are notified
+// about the change, so the other clients remove the item.
+func (s Service) PurgeItem(ctx context.Context, userID, sessionID, itemID uuid.UUID) error {
+	dataVersion, err := s.storage.PurgeItem(ctx, userID, itemID)
+	if err != nil {
+		return err
+	}
+	s.watchers.notify(models.DataChange{
+		UserID:      userID,
+		DataVersion: dataVersion,
+		SessionID:   sessionID,
+	})
+
+	return nil
+}
+
+// RunTrashPurger periodically erases the items deleted more than the retention period ago.
+// It blocks until the context is canceled.
+func (s Service) RunTrashPurger(ctx context.Context, interval, retention time.Duration) {
+	if interval <= 0 {
+		interval = defaultTrashPurgeInterval
+	}
+	if retention <= 0 {
+		retention = defaultTrashRetention
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("trash purger is stopped")
+			return
+		case <-ticker.C:
+			n, err := s.storage.PurgeTrash(ctx, time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("trash purger: %s", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("trash purger: %d deleted item(s) permanently erased", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package gophkeeper
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+)
+
+// watchers keeps the subscriptions to the changes of the users' data.
+type watchers struct {
+	mu   sync.Mutex
+	subs map[uuid.UUID]map[chan models.DataChange]struct{}
+}
+
+func newWatchers() *watchers {
+	return &watchers{
+		subs: make(map[uuid.UUID]map[chan models.DataChange]struct{}),
+	}
+}
+
+// Watch subscribes to the changes of the user's data. The channel always holds only the latest
+// change, so a slow reader never blocks the publisher. The cancel function must be called
+// when the subscription is no longer needed.
+func (s Service) Watch(userID uuid.UUID) (<-chan models.DataChange, func()) {
+	return s.watchers.subscribe(userID)
+}
+
+func (w *watchers) subscribe(userID uuid.UUID) (<-chan models.DataChange, func()) {
+	ch := make(chan models.DataChange, 1)
+	w.mu.Lock()
+	if w.subs[userID] == nil {
+		w.subs[userID] = make(map[chan models.DataChange]struct{})
+	}
+	w.subs[userID][ch] = struct{}{}
+	w.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			delete(w.subs[userID], ch)
+			if len(w.subs[userID]) == 0 {
+				delete(w.subs, userID)
+			}
+		})
+	}
+	return ch, cancel
+}
+
+// notify sends the change to all the subscribers of the user.
+func (w *watchers) notify(change models.DataChange) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.subs[change.UserID] {
+		// drop the unread change, the new one supersedes it
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- change
+	}
+}
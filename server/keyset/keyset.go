@@ -0,0 +1,272 @@
+package keyset
+
+// Package keyset contains the set of Ed25519 keys used for signing and verifying JWTs.
+//
+// The keys are stored in a directory as PEM files (PKCS #8) named <kid>.pem. The key ID
+// starts with the UTC time of the key creation, so the newest key has the greatest ID.
+// The newest key is used for signing the tokens, all other (retired) keys are used only
+// for verifying the tokens signed before the key rotation. A retired key can be removed
+// when all the tokens signed by it are expired.
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	keyFileExt = ".pem"
+	// kidTimeFmt has fixed width, so the key IDs are sorted by the creation time.
+	kidTimeFmt = "20060102T150405.000000000Z"
+
+	defaultReloadInterval = time.Minute
+)
+
+var (
+	ErrNoKeys     = errors.New("no signing keys found")
+	ErrUnknownKID = errors.New("unknown key ID")
+	ErrNoKID      = errors.New("key ID is missing in the token header")
+)
+
+// KeySet is the set of keys loaded from the directory. It's safe for concurrent use.
+type KeySet struct {
+	dir string
+
+	sync.RWMutex
+	signingKID string
+	signingKey ed25519.PrivateKey
+	publicKeys map[string]ed25519.PublicKey
+}
+
+// Load reads all the keys from the directory provided.
+// If there are no keys in the directory, ErrNoKeys returns.
+func Load(dir string) (*KeySet, error) {
+	k := &KeySet{dir: dir}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload rereads the keys from the directory. If an error occurs, the current keys are kept.
+func (k *KeySet) Reload() error {
+	kids, err := listKIDs(k.dir)
+	if err != nil {
+		return fmt.Errorf("keyset: reload: %w", err)
+	}
+	if len(kids) == 0 {
+		return fmt.Errorf("keyset: reload: %w", ErrNoKeys)
+	}
+	publicKeys := make(map[string]ed25519.PublicKey, len(kids))
+	var signingKey ed25519.PrivateKey
+	for _, kid := range kids {
+		key, err := readKey(filepath.Join(k.dir, kid+keyFileExt))
+		if err != nil {
+			return fmt.Errorf("keyset: reload: %s: %w", kid, err)
+		}
+		publicKeys[kid] = key.Public().(ed25519.PublicKey)
+		signingKey = key // kids are sorted, the last one is the newest
+	}
+
+	k.Lock()
+	defer k.Unlock()
+	k.signingKID = kids[len(kids)-1]
+	k.signingKey = signingKey
+	k.publicKeys = publicKeys
+
+	return nil
+}
+
+// RunReloader periodically rereads the keys from the directory, so that the rolled key
+// is picked up without restarting the server. It blocks until the context is canceled.
+func (k *KeySet) RunReloader(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			prevKID := k.SigningKID()
+			if err := k.Reload(); err != nil {
+				log.Println(err)
+				continue
+			}
+			if kid := k.SigningKID(); kid != prevKID {
+				log.Printf("keyset: signing key is rolled: %s -> %s", prevKID, kid)
+			}
+		}
+	}
+}
+
+// SigningKID returns the ID of the current signing key.
+func (k *KeySet) SigningKID() string {
+	k.RLock()
+	defer k.RUnlock()
+	return k.signingKID
+}
+
+// Sign creates a new JWT with the claims provided and signs it with the current signing key.
+// The key ID is stored in the "kid" header of the token.
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	k.RLock()
+	defer k.RUnlock()
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = k.signingKID
+	return token.SignedString(k.signingKey)
+}
+
+// Keyfunc is jwt.Keyfunc that finds the public key for the token by its "kid" header.
+func (k *KeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodEd25519); !ok {
+		return nil, fmt.Errorf("keyset: unexpected signing method: %v", t.Header["alg"])
+	}
+	kid, ok := t.Header["kid"].(string)
+	if !ok {
+		return nil, ErrNoKID
+	}
+	k.RLock()
+	defer k.RUnlock()
+	key, ok := k.publicKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("keyset: %w: %s", ErrUnknownKID, kid)
+	}
+	return key, nil
+}
+
+// Generate creates a new key in the directory provided and returns its ID.
+// The new key becomes the signing key after the key set is reloaded.
+func Generate(dir string) (string, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("keyset: generate: %w", err)
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("keyset: generate: %w", err)
+	}
+	kid := time.Now().UTC().Format(kidTimeFmt) + "-" + hex.EncodeToString(suffix)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("keyset: generate: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("keyset: generate: %w", err)
+	}
+	// write to the temporary file first, so that the reloader never reads a partially written key
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("keyset: generate: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := pem.Encode(tmp, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("keyset: generate: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("keyset: generate: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, kid+keyFileExt)); err != nil {
+		return "", fmt.Errorf("keyset: generate: %w", err)
+	}
+
+	return kid, nil
+}
+
+// Prune removes the retired keys that were replaced by a newer key more than maxTokenLifetime ago,
+// so no valid token signed by these keys can exist. The signing key is never removed.
+// It returns the IDs of the removed keys.
+func Prune(dir string, maxTokenLifetime time.Duration) ([]string, error) {
+	kids, err := listKIDs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("keyset: prune: %w", err)
+	}
+	removed := make([]string, 0)
+	for i := 0; i < len(kids)-1; i++ {
+		retiredAt, err := kidTime(kids[i+1])
+		if err != nil {
+			return removed, fmt.Errorf("keyset: prune: %w", err)
+		}
+		if time.Since(retiredAt) <= maxTokenLifetime {
+			break
+		}
+		if err := os.Remove(filepath.Join(dir, kids[i]+keyFileExt)); err != nil {
+			return removed, fmt.Errorf("keyset: prune: %w", err)
+		}
+		removed = append(removed, kids[i])
+	}
+
+	return removed, nil
+}
+
+// List returns the IDs of all keys in the directory. The last one is the signing key.
+func List(dir string) ([]string, error) {
+	kids, err := listKIDs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("keyset: list: %w", err)
+	}
+	return kids, nil
+}
+
+// listKIDs returns sorted IDs of the keys stored in the directory.
+func listKIDs(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	kids := make([]string, 0, len(files))
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != keyFileExt {
+			continue
+		}
+		kids = append(kids, strings.TrimSuffix(name, keyFileExt))
+	}
+	sort.Strings(kids)
+
+	return kids, nil
+}
+
+// readKey reads Ed25519 private key from the PEM file.
+func readKey(fileName string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwt.ParseEdPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, jwt.ErrNotEdPrivateKey
+	}
+	return edKey, nil
+}
+
+// kidTime returns the creation time of the key encoded in its ID.
+func kidTime(kid string) (time.Time, error) {
+	ts, _, _ := strings.Cut(kid, "-")
+	return time.Parse(kidTimeFmt, ts)
+}
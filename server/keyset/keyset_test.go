@@ -0,0 +1,62 @@
+package keyset
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	_, err := Load(dir)
+	assert.ErrorIs(t, err, ErrNoKeys)
+
+	oldKID, err := Generate(dir)
+	require.NoError(t, err)
+	keys, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, oldKID, keys.SigningKID())
+	oldToken, err := keys.Sign(jwt.StandardClaims{Id: "old"})
+	require.NoError(t, err)
+
+	// roll the key: new tokens are signed by the new key, old tokens are still valid
+	newKID, err := Generate(dir)
+	require.NoError(t, err)
+	require.NoError(t, keys.Reload())
+	assert.Equal(t, newKID, keys.SigningKID())
+	newToken, err := keys.Sign(jwt.StandardClaims{Id: "new"})
+	require.NoError(t, err)
+
+	for _, tc := range []struct{ token, kid, id string }{
+		{oldToken, oldKID, "old"},
+		{newToken, newKID, "new"},
+	} {
+		claims := &jwt.StandardClaims{}
+		parsed, err := jwt.ParseWithClaims(tc.token, claims, keys.Keyfunc)
+		require.NoError(t, err)
+		assert.Equal(t, tc.kid, parsed.Header["kid"])
+		assert.Equal(t, tc.id, claims.Id)
+	}
+
+	// the retired key is kept until its tokens expire
+	removed, err := Prune(dir, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+	removed, err = Prune(dir, -time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []string{oldKID}, removed)
+	require.NoError(t, keys.Reload())
+	_, err = jwt.ParseWithClaims(oldToken, &jwt.StandardClaims{}, keys.Keyfunc)
+	var ve *jwt.ValidationError
+	require.ErrorAs(t, err, &ve)
+	assert.ErrorIs(t, ve.Inner, ErrUnknownKID)
+
+	// the tokens signed by other algorithms are rejected
+	hsToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = jwt.ParseWithClaims(hsToken, &jwt.StandardClaims{}, keys.Keyfunc)
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package mailer
+
+// Package mailer contains the mailers used for sending the service emails to the users.
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Mailer sends the plain text email to the recipient.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// SMTP sends the emails through the SMTP server.
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTP creates a new SMTP mailer. If the username is empty, the messages are sent without authentication.
+func NewSMTP(host string, port int, username, password, from string) SMTP {
+	m := SMTP{
+		addr: host + ":" + strconv.Itoa(port),
+		from: from,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send implements Mailer interface.
+func (m SMTP) Send(ctx context.Context, to, subject, body string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{to}, message(m.from, to, subject, body)); err != nil {
+		return fmt.Errorf("mailer: smtp: %w", err)
+	}
+	return nil
+}
+
+// File stores the emails in the directory as .eml files instead of sending them.
+// It's intended for local testing.
+type File struct {
+	dir  string
+	from string
+}
+
+// NewFile creates a new file mailer.
+func NewFile(dir, from string) File {
+	return File{
+		dir:  dir,
+		from: from,
+	}
+}
+
+// Send implements Mailer interface.
+func (m File) Send(ctx context.Context, to, subject, body string) error {
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return fmt.Errorf("mailer: file: %w", err)
+	}
+	name := time.Now().UTC().Format("20060102T150405") + "-" + uuid.New().String() + ".eml"
+	if err := os.WriteFile(filepath.Join(m.dir, name), message(m.from, to, subject, body), 0600); err != nil {
+		return fmt.Errorf("mailer: file: %w", err)
+	}
+	return nil
+}
+
+// Log writes the emails to the standard logger instead of sending them.
+// It's intended for local testing.
+type Log struct{}
+
+// Send implements Mailer interface.
+func (Log) Send(ctx context.Context, to, subject, body string) error {
+	log.Printf("mailer: email to %s\nSubject: %s\n\n%s", to, subject, body)
+	return nil
+}
+
+// message formats the email as RFC 5322 message.
+func message(from, to, subject, body string) []byte {
+	var sb strings.Builder
+	sb.WriteString("From: " + from + "\r\n")
+	sb.WriteString("To: " + to + "\r\n")
+	sb.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	sb.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(sb.String())
+}
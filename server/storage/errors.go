@@ -1,8 +1,30 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/vanamelnik/gophkeeper/models"
+)
 
 var (
 	ErrAlreadyExists = errors.New("entry already exists")
 	ErrNotFound      = errors.New("entry not found")
+	// ErrChangeLogCompacted means that the change log doesn't cover the data version requested.
+	ErrChangeLogCompacted = errors.New("change log is compacted")
+	// ErrSnapshotNotRetained means that the item history doesn't cover the data version requested.
+	ErrSnapshotNotRetained = errors.New("snapshot of the data version is not retained")
+	// ErrBlobOffsetMismatch means that the blob chunk doesn't continue the content received so far.
+	ErrBlobOffsetMismatch = errors.New("blob chunk offset doesn't match the received size")
+	// ErrBlobNotUploaded means that the blob item refers to the content that isn't uploaded completely.
+	ErrBlobNotUploaded = errors.New("blob content is not uploaded")
 )
+
+// ErrVersionConflict is returned when the item is changed on the server since the version
+// the update is based on. Item is the current server version of the item.
+type ErrVersionConflict struct {
+	Item models.Item
+}
+
+func (e ErrVersionConflict) Error() string {
+	return "item version conflict"
+}
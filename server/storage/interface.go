@@ -4,6 +4,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/vanamelnik/gophkeeper/models"
@@ -15,17 +16,65 @@ type (
 		// CreateUser creates a new record with user's data in the database.
 		// It returns models.User object with generated user ID and CreatedAt field.
 		// If user with such email is already exists, the erroro ErrAlreadyExists returns.
-		CreateUser(ctx context.Context, email, passwordHash string) (models.User, error)
+		CreateUser(ctx context.Context, user models.User) (models.User, error)
 
-		// TODO:
-		// // UpdateUser updates information of the user with ID provided.
-		// // All fields must be filled.
-		// UpdateUser(ctx context.Context, user models.User) error
-		// // DeleteUser removes the user provided from the database.
-		// DeleteUser(ctx context.Context, userID uuid.UUID) error
+		// UpdateUser updates information of the user with ID provided.
+		// All fields must be filled. If the user is not found or deleted, ErrNotFound returns.
+		UpdateUser(ctx context.Context, user models.User) error
+
+		// DeleteUser marks the user provided as deleted and ends all the user's sessions.
+		// The user's data is kept in the database until PurgeDeletedUsers is called.
+		DeleteUser(ctx context.Context, userID uuid.UUID) error
+		// UndeleteUser removes the deletion mark of the user provided.
+		// If the user is not deleted, ErrNotFound returns.
+		UndeleteUser(ctx context.Context, userID uuid.UUID) error
+		// PurgeDeletedUsers permanently erases the users deleted before the time provided
+		// along with all their sessions and items. It returns the number of erased users.
+		PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
 
 		// GetUserByEmail finds the user with given email.
 		GetUserByEmail(ctx context.Context, email string) (models.User, error)
+		// GetDeletedUserByEmail finds the user with given email marked as deleted.
+		GetDeletedUserByEmail(ctx context.Context, email string) (models.User, error)
+		// GetDeletedUserByID finds the user with given ID marked as deleted.
+		GetDeletedUserByID(ctx context.Context, userID uuid.UUID) (models.User, error)
+		// GetUserByID finds the user with given ID.
+		GetUserByID(ctx context.Context, userID uuid.UUID) (models.User, error)
+		// CreateSRPHandshake stores the server state of SRP-6a login. The expired handshakes are removed.
+		CreateSRPHandshake(ctx context.Context, h models.SRPHandshake) error
+		// TakeSRPHandshake removes the handshake with given ID and returns it, so the handshake
+		// can be used only once. If there is no such handshake, ErrNotFound returns.
+		TakeSRPHandshake(ctx context.Context, id uuid.UUID) (models.SRPHandshake, error)
+		// CreateUserToken stores the user token. All unused tokens of the user with the same purpose are removed.
+		CreateUserToken(ctx context.Context, t models.UserToken) error
+		// UseUserToken marks the unused and unexpired token with given hash and purpose as used and returns it.
+		// If there is no such token, ErrNotFound returns.
+		UseUserToken(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (models.UserToken, error)
+		// GetUserToken returns the unused and unexpired token with given hash and purpose without marking it as used.
+		// If there is no such token, ErrNotFound returns.
+		GetUserToken(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (models.UserToken, error)
+		// ResetPassword marks the unused and unexpired token as used and sets the SRP verifier of the token's user
+		// by the single statement, so the token is spent only together with the verifier. The email of the user
+		// is marked as verified. If the token is already used or expired, ErrNotFound returns.
+		ResetPassword(ctx context.Context, tokenID uuid.UUID, salt, verifier []byte) error
+		// ReplaceRecoveryCodes removes all recovery codes of the user and stores the new ones.
+		ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+		// GetUnusedRecoveryCodes returns all recovery codes of the user that are not used yet.
+		GetUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]models.RecoveryCode, error)
+		// UseTOTPStep stores the time step of the TOTP code accepted for the user. If the step
+		// of the last accepted code is the same or later, ErrNotFound returns: the code is already used.
+		UseTOTPStep(ctx context.Context, userID uuid.UUID, step uint64) error
+		// CreateMFAChallenge stores the server state of the MFA challenge. The expired challenges are removed.
+		CreateMFAChallenge(ctx context.Context, c models.MFAChallengeState) error
+		// CountMFAChallengeAttempt atomically counts the attempt to answer the challenge. If there is
+		// no such challenge, it's expired or maxAttempts are already made, ErrNotFound returns.
+		CountMFAChallengeAttempt(ctx context.Context, id uuid.UUID, maxAttempts int) (models.MFAChallengeState, error)
+		// DeleteMFAChallenge removes the challenge, so it can't be used anymore.
+		DeleteMFAChallenge(ctx context.Context, id uuid.UUID) error
+		// UseRecoveryCode marks the recovery code as used.
+		// If the code is not found or already used, ErrNotFound returns.
+		UseRecoveryCode(ctx context.Context, codeID uuid.UUID) error
+
 		// GetUserDataVersion returns current user data version.
 		GetUserDataVersion(ctx context.Context, userID uuid.UUID) (uint64, error)
 
@@ -42,31 +91,142 @@ type (
 		// If there are no active sessions, an empy list is returned and a nil error.
 		GetActiveUserSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
 
-		// TODO:
-		// // LogoutAll marks all session of the user provided as logged out.
-		// LogoutAll(ctx context.Context, userID uuid.UUID) error
+		// LogoutAll marks all session of the user provided as logged out.
+		LogoutAll(ctx context.Context, userID uuid.UUID) error
+
+		// CreateSecurityEvent stores the record about the security event.
+		// ID and CreatedAt fields are generated by the storage.
+		CreateSecurityEvent(ctx context.Context, event models.SecurityEvent) error
+
+		// GetLoginAttempts returns the failed attempts counter for the key provided.
+		// If there were no failures, ErrNotFound returns.
+		GetLoginAttempts(ctx context.Context, key string) (models.LoginAttempts, error)
+		// ReserveLoginAttempt atomically reserves the attempt for the key before the credentials are verified.
+		// The attempt isn't reserved (false returns) if the key is blocked or if other attempts are in progress
+		// and together with the failures they reach maxFailures. The failures older than resetBefore and
+		// the reservations older than staleBefore aren't counted.
+		ReserveLoginAttempt(ctx context.Context, key string, maxFailures int, resetBefore, staleBefore time.Time) (bool, error)
+		// ReleaseLoginAttempt releases the attempt reserved for the key without registering the failure.
+		ReleaseLoginAttempt(ctx context.Context, key string) error
+		// RegisterLoginFailure increments the failed attempts counter for the key, releases the attempt
+		// reserved for it and returns the updated counter. The counter is restarted if the last failure
+		// is older than resetBefore.
+		RegisterLoginFailure(ctx context.Context, key string, resetBefore time.Time) (models.LoginAttempts, error)
+		// BlockLoginAttempts sets the time before which the attempts for the key are rejected.
+		BlockLoginAttempts(ctx context.Context, key string, blockedUntil time.Time) error
+		// ResetLoginAttempts removes the failed attempts counter for the key.
+		ResetLoginAttempts(ctx context.Context, key string) error
+		// CountRequest increments the requests counter for the key and returns the updated counter.
+		// The counter is restarted in a new window if its window started before windowStartBefore.
+		CountRequest(ctx context.Context, key string, windowStartBefore time.Time) (models.RequestCounter, error)
 
 		// NewUserTransaction starts a new transaction that implements the specified user's events.
 		NewUserTransaction(ctx context.Context, userID uuid.UUID) (UserTransaction, error)
 
-		// GetUserData returns all user's items: passwords, blobs, texts and cards.
+		// GetUserData returns all user's items: passwords, blobs, texts, cards, OTP and SSH keys.
 		GetUserData(ctx context.Context, userID uuid.UUID) (*models.UserData, error)
+		// GetChangesSince returns the user's items changed after the data version provided.
+		// If the change log no longer covers that version, ErrChangeLogCompacted returns.
+		GetChangesSince(ctx context.Context, userID uuid.UUID, dataVersion uint64) (*models.UserData, error)
+		// GetTrash returns the deleted items of the user with their payload.
+		GetTrash(ctx context.Context, userID uuid.UUID) ([]models.Item, error)
+		// PurgeItem permanently erases the deleted item of the user together with its history and
+		// returns the new data version. The purge is recorded in the change log, so GetChangesSince
+		// returns the tombstone of the item until the log is compacted. The snapshots taken before
+		// the item was deleted are no longer retained. If the user has no such deleted item, ErrNotFound returns.
+		PurgeItem(ctx context.Context, userID, itemID uuid.UUID) (uint64, error)
+		// PurgeTrash permanently erases the items of all users deleted before the time provided
+		// together with their history. The purges are recorded as by PurgeItem.
+		// It returns the number of erased items.
+		PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error)
+		// GetItemVersions returns the versions of the user's item kept in the history, the latest first.
+		// If there is no history of the item, ErrNotFound returns.
+		GetItemVersions(ctx context.Context, userID, itemID uuid.UUID) ([]models.ItemVersion, error)
+		// GetItemVersion returns the version of the user's item from the history.
+		// If there is no such version, ErrNotFound returns.
+		GetItemVersion(ctx context.Context, userID, itemID uuid.UUID, version uint64) (models.Item, error)
+		// PruneItemHistory removes from the history of all items the versions beyond keepVersions
+		// latest ones and the versions archived before the time provided. The latest version
+		// of each item is always kept. The oldest data version that can be reconstructed
+		// by GetSnapshot is moved accordingly. It returns the number of removed versions.
+		PruneItemHistory(ctx context.Context, keepVersions int, archivedBefore time.Time) (int64, error)
+		// CompactChangeLog removes the change log records of all users except the latest keepVersions
+		// data versions. It returns the number of removed records.
+		CompactChangeLog(ctx context.Context, keepVersions uint64) (int64, error)
+
+		// GetBlobUpload returns the state of the user's blob content with the hash provided.
+		// If the upload isn't started, ErrNotFound returns.
+		GetBlobUpload(ctx context.Context, userID uuid.UUID, hash string) (models.BlobUpload, error)
+		// CreateBlobUpload starts the upload of the user's blob content. If the upload of the content
+		// is already started, nothing is changed. The current state of the upload returns.
+		CreateBlobUpload(ctx context.Context, userID uuid.UUID, hash string, size int64) (models.BlobUpload, error)
+		// AppendBlobChunk stores the next chunk of the blob content being uploaded. If the offset isn't equal
+		// to the number of bytes received or the chunk exceeds the size of the content, ErrBlobOffsetMismatch returns.
+		AppendBlobChunk(ctx context.Context, userID uuid.UUID, hash string, offset int64, data []byte) error
+		// CompleteBlobUpload marks the blob content as completely uploaded.
+		CompleteBlobUpload(ctx context.Context, userID uuid.UUID, hash string) error
+		// GetStagedBlobs returns the completely uploaded blob contents whose chunks are still kept in the database.
+		GetStagedBlobs(ctx context.Context) ([]models.BlobUpload, error)
+		// DeleteBlobChunks removes the chunks of the blob content when it's moved to the blob store.
+		DeleteBlobChunks(ctx context.Context, userID uuid.UUID, hash string) error
+		// CollectBlobs removes the user blob contents that are not referred to by any item or item version
+		// since the time provided, and the uploads not finished since then. Their hashes are queued
+		// as the blob garbage. It returns the number of the contents removed.
+		CollectBlobs(ctx context.Context, unreferencedBefore time.Time) (int64, error)
+		// GetBlobGarbage returns the hashes of the blob contents that may be not used by anyone.
+		GetBlobGarbage(ctx context.Context) ([]string, error)
+		// ReleaseBlobGarbage removes the hash from the blob garbage. If no user has the content with the hash,
+		// release is called before, while the new uploads of the content wait. It reports whether
+		// the content is released.
+		ReleaseBlobGarbage(ctx context.Context, hash string, release func() error) (bool, error)
+		// DeleteBlobUpload removes the blob content with all its chunks.
+		DeleteBlobUpload(ctx context.Context, userID uuid.UUID, hash string) error
+		// ReadBlobChunk returns the stored piece of the blob content that starts at the offset.
+		// The piece may be shorter than the rest of the content; nil returns at the end of the content.
+		ReadBlobChunk(ctx context.Context, userID uuid.UUID, hash string, offset int64) ([]byte, error)
 	}
 
 	// UserTransaction is an interface that wraps methods that performs user events committing.
 	// Each transaction must be closed by calling Commit or Rollback method.
 	UserTransaction interface {
-		// CreateItem adds a new record in the database.
-		CreateItem(ctx context.Context, item models.Item) error
+		// CreateItem adds a new record in the database and returns the version of the item.
+		// If the item with such ID already exists, ErrAlreadyExists returns.
+		// If the blob item refers to the content that isn't uploaded, ErrBlobNotUploaded returns.
+		// A failed operation is rolled back alone, the transaction can be used further.
+		CreateItem(ctx context.Context, item models.Item) (uint64, error)
+
+		// UpdateItem updates the record in the database and returns the new version of the item.
+		// The update is applied only if the stored version of the item is equal to item.Version,
+		// otherwise ErrVersionConflict with the stored item returns.
+		// If the user has no such item, ErrNotFound returns. The blob content is checked as in CreateItem.
+		// A failed operation is rolled back alone, the transaction can be used further.
+		UpdateItem(ctx context.Context, item models.Item) (uint64, error)
+
+		// DeleteItem turns the item into a tombstone by setting the deletion time and returns
+		// the new version of the item. The payload of the tombstone is not wiped: it's kept in the trash
+		// until PurgeItem or PurgeTrash erases the item together with its history, but it's never
+		// returned by GetUserData and GetChangesSince.
+		// The version semantics and the errors are the same as of UpdateItem.
+		DeleteItem(ctx context.Context, itemID uuid.UUID, version uint64) (uint64, error)
+
+		// RestoreItem removes the deletion mark of the item, increments its version
+		// and returns the restored item. If the user has no such deleted item, ErrNotFound returns.
+		RestoreItem(ctx context.Context, itemID uuid.UUID) (models.Item, error)
+
+		// GetItems returns all the user's items including the tombstones with their payload.
+		GetItems(ctx context.Context) ([]models.Item, error)
 
-		// UpdateItem updates the record in the database.
-		UpdateItem(ctx context.Context, item models.Item) error
+		// GetSnapshot reconstructs from the item history the user's items as they were
+		// in the data version provided. If the history doesn't cover that version,
+		// ErrSnapshotNotRetained returns.
+		GetSnapshot(ctx context.Context, dataVersion uint64) ([]models.Item, error)
 
 		// Rollback cancels the transaction if it's not closed yet.
 		Rollback() error
 
-		// Commit closes the transaction and commits all changes.
-		// Also it increments DataVersion field of the user.
-		Commit() error
+		// Commit closes the transaction, commits all changes and returns the new data version.
+		// Also it increments DataVersion field of the user and records the changed items
+		// in the user's change log.
+		Commit() (uint64, error)
 	}
 )
@@ -0,0 +1,295 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+// GetBlobUpload implements storage.Storage interface.
+func (s Storage) GetBlobUpload(ctx context.Context, userID uuid.UUID, hash string) (models.BlobUpload, error) {
+	upload := models.BlobUpload{UserID: userID, Hash: hash}
+	err := s.db.QueryRowContext(ctx,
+		`SELECT size, received, complete FROM blob_contents WHERE user_id=$1 AND sha256=$2;`,
+		userID, hash,
+	).Scan(&upload.Size, &upload.Received, &upload.Complete)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.BlobUpload{}, storage.ErrNotFound
+		}
+		return models.BlobUpload{}, err
+	}
+	return upload, nil
+}
+
+// CreateBlobUpload implements storage.Storage interface.
+// The upload waits while the content with the same hash is being released by ReleaseBlobGarbage.
+func (s Storage) CreateBlobUpload(ctx context.Context, userID uuid.UUID, hash string, size int64) (models.BlobUpload, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.BlobUpload{}, err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	if err := lockBlob(ctx, tx, hash); err != nil {
+		return models.BlobUpload{}, err
+	}
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO blob_contents (user_id, sha256, size, received, complete, created_at, touched_at)
+		VALUES ($1, $2, $3, 0, false, $4, $4) ON CONFLICT DO NOTHING;`,
+		userID, hash, size, now,
+	); err != nil {
+		return models.BlobUpload{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.BlobUpload{}, err
+	}
+	return s.GetBlobUpload(ctx, userID, hash)
+}
+
+// AppendBlobChunk implements storage.Storage interface.
+func (s Storage) AppendBlobChunk(ctx context.Context, userID uuid.UUID, hash string, offset int64, data []byte) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE blob_contents SET received=received+$1, touched_at=now()
+		WHERE user_id=$2 AND sha256=$3 AND received=$4 AND NOT complete AND received+$1 <= size;`,
+		len(data), userID, hash, offset)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		if _, err := s.GetBlobUpload(ctx, userID, hash); err != nil {
+			return err
+		}
+		return storage.ErrBlobOffsetMismatch
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO blob_chunks (user_id, sha256, chunk_offset, data) VALUES ($1, $2, $3, $4);`,
+		userID, hash, offset, data); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CompleteBlobUpload implements storage.Storage interface.
+func (s Storage) CompleteBlobUpload(ctx context.Context, userID uuid.UUID, hash string) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE blob_contents SET complete=true, touched_at=now() WHERE user_id=$1 AND sha256=$2 AND received=size;`,
+		userID, hash)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// GetStagedBlobs implements storage.Storage interface.
+func (s Storage) GetStagedBlobs(ctx context.Context) ([]models.BlobUpload, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT user_id, sha256, size, received FROM blob_contents c
+		WHERE complete AND EXISTS (SELECT 1 FROM blob_chunks WHERE user_id=c.user_id AND sha256=c.sha256);`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	uploads := make([]models.BlobUpload, 0)
+	for rows.Next() {
+		upload := models.BlobUpload{Complete: true}
+		if err := rows.Scan(&upload.UserID, &upload.Hash, &upload.Size, &upload.Received); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, rows.Err()
+}
+
+// DeleteBlobChunks implements storage.Storage interface.
+func (s Storage) DeleteBlobChunks(ctx context.Context, userID uuid.UUID, hash string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM blob_chunks WHERE user_id=$1 AND sha256=$2;`, userID, hash)
+	return err
+}
+
+// DeleteBlobUpload implements storage.Storage interface.
+func (s Storage) DeleteBlobUpload(ctx context.Context, userID uuid.UUID, hash string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	for _, table := range []string{"blob_chunks", "blob_contents"} {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM `+table+` WHERE user_id=$1 AND sha256=$2;`,
+			userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ReadBlobChunk implements storage.Storage interface.
+func (s Storage) ReadBlobChunk(ctx context.Context, userID uuid.UUID, hash string, offset int64) ([]byte, error) {
+	var (
+		chunkOffset int64
+		data        []byte
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT chunk_offset, data FROM blob_chunks WHERE user_id=$1 AND sha256=$2 AND chunk_offset <= $3
+		ORDER BY chunk_offset DESC LIMIT 1;`,
+		userID, hash, offset,
+	).Scan(&chunkOffset, &data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if offset-chunkOffset >= int64(len(data)) {
+		return nil, nil
+	}
+	return data[offset-chunkOffset:], nil
+}
+
+// CollectBlobs implements storage.Storage interface.
+func (s Storage) CollectBlobs(ctx context.Context, unreferencedBefore time.Time) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`DELETE FROM blob_contents WHERE ref_count <= 0 AND touched_at < $1 RETURNING user_id, sha256;`,
+		unreferencedBefore)
+	if err != nil {
+		return 0, err
+	}
+	type content struct {
+		userID uuid.UUID
+		hash   string
+	}
+	collected := make([]content, 0)
+	for rows.Next() {
+		var c content
+		if err := rows.Scan(&c.userID, &c.hash); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		collected = append(collected, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, c := range collected {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM blob_chunks WHERE user_id=$1 AND sha256=$2;`,
+			c.userID, c.hash); err != nil {
+			return 0, err
+		}
+		if err := queueBlobGarbage(ctx, tx, `SELECT $1::text AS sha256`, c.hash); err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(collected)), tx.Commit()
+}
+
+// GetBlobGarbage implements storage.Storage interface.
+func (s Storage) GetBlobGarbage(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT sha256 FROM blob_garbage ORDER BY collected_at;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hashes := make([]string, 0)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// ReleaseBlobGarbage implements storage.Storage interface.
+func (s Storage) ReleaseBlobGarbage(ctx context.Context, hash string, release func() error) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	if err := lockBlob(ctx, tx, hash); err != nil {
+		return false, err
+	}
+	var inUse bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM blob_contents WHERE sha256=$1);`,
+		hash).Scan(&inUse); err != nil {
+		return false, err
+	}
+	if !inUse {
+		if err := release(); err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM blob_garbage WHERE sha256=$1;`, hash); err != nil {
+		return false, err
+	}
+
+	return !inUse, tx.Commit()
+}
+
+// lockBlob takes the transaction lock of the blob content hash, so the content can't be uploaded
+// and released at the same time.
+func lockBlob(ctx context.Context, tx *sql.Tx, hash string) error {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1));`, hash)
+	return err
+}
+
+// queueBlobGarbage queues the hashes selected by the query (it must return sha256 column)
+// for the check whether their content is still used by anyone.
+func queueBlobGarbage(ctx context.Context, tx *sql.Tx, hashes string, args ...interface{}) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO blob_garbage (sha256, collected_at) SELECT sha256, now() FROM (`+hashes+`) h
+		ON CONFLICT DO NOTHING;`,
+		args...)
+	return err
+}
+
+// adjustBlobRefs returns the statement that changes the reference counters of the user blob contents
+// referred to by the rows selected by the refs query (it must return user_id and sha256 columns).
+// The sign is "+" for the new references and "-" for the removed ones.
+func adjustBlobRefs(sign, refs string) string {
+	return `UPDATE blob_contents c SET ref_count = c.ref_count ` + sign + ` r.n, touched_at = now()
+	FROM (SELECT user_id, sha256, count(*) AS n FROM (` + refs + `) refs GROUP BY user_id, sha256) r
+	WHERE c.user_id = r.user_id AND c.sha256 = r.sha256`
+}
@@ -0,0 +1,305 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+// Kinds of the items stored in the item_versions table.
+const (
+	kindText     = "TEXT"
+	kindPassword = "PASSWORD"
+	kindCard     = "CARD"
+	kindBlob     = "BLOB"
+	kindOTP      = "OTP"
+	kindSSHKey   = "SSH_KEY"
+)
+
+// archivedItems are the tables of the items with the SQL expressions building their payload stored as JSON.
+var archivedItems = []struct{ table, kind, payload string }{
+	{"texts", kindText, `json_build_object('text', text_string)`},
+	{"passwords", kindPassword, `json_build_object('password', password)`},
+	{"cards", kindCard,
+		`json_build_object('number', card_number, 'name', cardholder_name, 'date', expiration_date, 'cvc', cvc)`},
+	{"otps", kindOTP, `json_build_object('secret', encode(secret, 'base64'), 'algorithm', algorithm,
+		'digits', digits, 'period', period, 'issuer', issuer, 'account', account)`},
+	{"ssh_keys", kindSSHKey, `json_build_object('private_key', encode(private_key, 'base64'),
+		'public_key', public_key, 'comment', comment, 'passphrase', passphrase)`},
+	{"blobs", kindBlob, `json_build_object('size', size, 'sha256', sha256)`},
+}
+
+// archiveQueries copy the current state of the items changed in the data version ($2)
+// of the user ($1) into the item_versions table.
+var archiveQueries = archiveQueriesWhere(`$2::integer`,
+	`user_id=$1 AND id IN (SELECT item_id FROM item_changes WHERE user_id=$1 AND data_version=$2::integer)`)
+
+// unversionedQueries copy the current state of the items that have no archived versions, i.e. the items
+// created before the item history was introduced, into the item_versions table. The versions are recorded
+// in the current data version of their users.
+var unversionedQueries = archiveQueriesWhere(`(SELECT data_version FROM users WHERE users.id = user_id)`,
+	`id NOT IN (SELECT item_id FROM item_versions)`)
+
+// archiveQueriesWhere returns the queries archiving the items matching the condition into the data version
+// provided. The archived versions of the blobs refer to the blob content as well.
+func archiveQueriesWhere(dataVersion, where string) []string {
+	queries := make([]string, 0, len(archivedItems))
+	for _, a := range archivedItems {
+		query := `INSERT INTO item_versions
+	(user_id, item_id, version, data_version, kind, payload, meta, created_at, deleted_at, archived_at)
+	SELECT user_id, id, version, ` + dataVersion + `, '` + a.kind + `', ` + a.payload + `, meta, created_at, deleted_at, now()
+	FROM ` + a.table + `
+	WHERE ` + where + `
+	ON CONFLICT DO NOTHING`
+		if a.kind == kindBlob {
+			query = `WITH archived AS (` + query + ` RETURNING user_id, payload->>'sha256' AS sha256) ` +
+				adjustBlobRefs("+", `SELECT user_id, sha256 FROM archived`)
+		}
+		queries = append(queries, query)
+	}
+	return queries
+}
+
+type (
+	textPayload struct {
+		Text string `json:"text"`
+	}
+	passwordPayload struct {
+		Password string `json:"password"`
+	}
+	cardPayload struct {
+		Number string `json:"number"`
+		Name   string `json:"name"`
+		Date   string `json:"date"`
+		CVC    uint32 `json:"cvc"`
+	}
+	blobPayload struct {
+		Size   int64  `json:"size"`
+		SHA256 string `json:"sha256"`
+	}
+	otpPayload struct {
+		// Secret is base64 encoded by postgres, json decodes it into []byte.
+		Secret    []byte `json:"secret"`
+		Algorithm string `json:"algorithm"`
+		Digits    uint32 `json:"digits"`
+		Period    uint32 `json:"period"`
+		Issuer    string `json:"issuer"`
+		Account   string `json:"account"`
+	}
+	sshKeyPayload struct {
+		PrivateKey []byte `json:"private_key"`
+		PublicKey  string `json:"public_key"`
+		Comment    string `json:"comment"`
+		Passphrase string `json:"passphrase"`
+	}
+)
+
+// decodePayload converts the payload stored in the item_versions table into the item payload.
+func decodePayload(kind string, raw []byte) (interface{}, error) {
+	switch kind {
+	case kindText:
+		var p textPayload
+		err := json.Unmarshal(raw, &p)
+		return models.TextData{Text: p.Text}, err
+	case kindPassword:
+		var p passwordPayload
+		err := json.Unmarshal(raw, &p)
+		return models.PasswordData{Password: p.Password}, err
+	case kindCard:
+		var p cardPayload
+		err := json.Unmarshal(raw, &p)
+		return models.CardData{Number: p.Number, CardholderName: p.Name, Date: p.Date, CVC: p.CVC}, err
+	case kindBlob:
+		var p blobPayload
+		err := json.Unmarshal(raw, &p)
+		return models.BinaryData{Size: p.Size, Hash: p.SHA256}, err
+	case kindOTP:
+		var p otpPayload
+		err := json.Unmarshal(raw, &p)
+		return models.OTPData{Secret: p.Secret, Algorithm: p.Algorithm, Digits: p.Digits, Period: p.Period,
+			Issuer: p.Issuer, Account: p.Account}, err
+	case kindSSHKey:
+		var p sshKeyPayload
+		err := json.Unmarshal(raw, &p)
+		return models.SSHKeyData{PrivateKey: p.PrivateKey, PublicKey: p.PublicKey, Comment: p.Comment,
+			Passphrase: p.Passphrase}, err
+	}
+	return nil, fmt.Errorf("unknown item kind %q", kind)
+}
+
+// archiveChanges stores the new versions of the items changed in the data version provided.
+func (t *UserTransaction) archiveChanges(dataVersion uint64) error {
+	for _, query := range archiveQueries {
+		if _, err := t.tx.Exec(query, t.userID, dataVersion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveUnversionedItems archives the items created before the item history was introduced.
+// The earlier states of these items can't be reconstructed, so history_start of their users
+// is moved to the current data version.
+func (s Storage) archiveUnversionedItems(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	owners := make([]string, 0, len(archivedItems))
+	for _, a := range archivedItems {
+		owners = append(owners, `SELECT user_id FROM `+a.table+` WHERE id NOT IN (SELECT item_id FROM item_versions)`)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET history_start = data_version
+		WHERE history_start < data_version AND id IN (`+strings.Join(owners, ` UNION `)+`);`); err != nil {
+		return err
+	}
+	for _, query := range unversionedQueries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetItemVersions implements storage.Storage interface.
+func (s Storage) GetItemVersions(ctx context.Context, userID, itemID uuid.UUID) ([]models.ItemVersion, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT version, data_version, archived_at, deleted_at IS NOT NULL FROM item_versions
+		WHERE user_id=$1 AND item_id=$2 ORDER BY version DESC;`,
+		userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	versions := make([]models.ItemVersion, 0)
+	for rows.Next() {
+		v := models.ItemVersion{ItemID: itemID}
+		if err := rows.Scan(&v.Version, &v.DataVersion, &v.ArchivedAt, &v.Deleted); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, storage.ErrNotFound
+	}
+	return versions, nil
+}
+
+// GetItemVersion implements storage.Storage interface.
+func (s Storage) GetItemVersion(ctx context.Context, userID, itemID uuid.UUID, version uint64) (models.Item, error) {
+	var (
+		item    = models.Item{ID: itemID, Version: version}
+		kind    string
+		payload []byte
+		meta    sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT kind, payload, meta, created_at, deleted_at FROM item_versions
+		WHERE user_id=$1 AND item_id=$2 AND version=$3;`,
+		userID, itemID, version).Scan(&kind, &payload, &meta, &item.CreatedAt, &item.DeletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Item{}, storage.ErrNotFound
+		}
+		return models.Item{}, err
+	}
+	item.Meta = models.JSONMetadata(meta.String)
+	if item.Payload, err = decodePayload(kind, payload); err != nil {
+		return models.Item{}, err
+	}
+	return item, nil
+}
+
+// PruneItemHistory implements storage.Storage interface.
+// The references of the pruned blob versions to the blob content are discounted.
+// The state of the item between the pruned version and the next one can't be reconstructed anymore,
+// so history_start of the user is moved to the data version of the next item version.
+func (s Storage) PruneItemHistory(ctx context.Context, keepVersions int, archivedBefore time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		`WITH pruned AS (
+			SELECT v.user_id, v.item_id, v.version FROM item_versions v
+			JOIN (SELECT item_id, max(version) AS latest FROM item_versions GROUP BY item_id) l
+			ON v.item_id = l.item_id
+			WHERE v.version < l.latest AND (v.version <= l.latest - $1 OR v.archived_at < $2)
+		), bounds AS (
+			SELECT p.user_id, max((
+				SELECT min(n.data_version) FROM item_versions n WHERE n.item_id = p.item_id AND n.version > p.version
+			)) AS covered_from
+			FROM pruned p GROUP BY p.user_id
+		), moved AS (
+			UPDATE users u SET history_start = GREATEST(u.history_start, b.covered_from)
+			FROM bounds b WHERE u.id = b.user_id
+		), released AS (
+			`+adjustBlobRefs("-", `SELECT v.user_id, v.payload->>'sha256' AS sha256 FROM item_versions v
+				JOIN pruned p ON v.item_id = p.item_id AND v.version = p.version WHERE v.kind = 'BLOB'`)+`
+		)
+		DELETE FROM item_versions v USING pruned p WHERE v.item_id = p.item_id AND v.version = p.version;`,
+		keepVersions, archivedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetItems implements storage.UserTransaction interface.
+func (t *UserTransaction) GetItems(ctx context.Context) ([]models.Item, error) {
+	return getItems(ctx, t.tx, `user_id=$1`, t.userID)
+}
+
+// GetSnapshot implements storage.UserTransaction interface.
+func (t *UserTransaction) GetSnapshot(ctx context.Context, dataVersion uint64) ([]models.Item, error) {
+	var current, historyStart uint64
+	if err := t.tx.QueryRowContext(ctx, `SELECT data_version, history_start FROM users WHERE id=$1;`,
+		t.userID).Scan(&current, &historyStart); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrNotFound
+		}
+		return nil, err
+	}
+	if dataVersion < historyStart || dataVersion > current {
+		return nil, storage.ErrSnapshotNotRetained
+	}
+
+	rows, err := t.tx.QueryContext(ctx,
+		`SELECT DISTINCT ON (item_id) item_id, version, kind, payload, meta, created_at, deleted_at
+		FROM item_versions WHERE user_id=$1 AND data_version <= $2
+		ORDER BY item_id, version DESC;`,
+		t.userID, dataVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]models.Item, 0)
+	for rows.Next() {
+		var (
+			item    models.Item
+			kind    string
+			payload []byte
+			meta    sql.NullString
+		)
+		if err := rows.Scan(&item.ID, &item.Version, &kind, &payload, &meta, &item.CreatedAt, &item.DeletedAt); err != nil {
+			return nil, err
+		}
+		item.Meta = models.JSONMetadata(meta.String)
+		if item.Payload, err = decodePayload(kind, payload); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,220 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/gophkeeper"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+// testDSNEnv is the environment variable with the connection string of the test database.
+// The tests don't reset the database, each of them works with its own user.
+const testDSNEnv = "TEST_DATABASE_DSN"
+
+func newTestStorage(t *testing.T) Storage {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	s, err := NewStorage(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func newTestUser(t *testing.T, s Storage) uuid.UUID {
+	user, err := s.CreateUser(context.Background(), models.User{Email: uuid.New().String() + "@example.com"})
+	require.NoError(t, err)
+	return user.ID
+}
+
+// commit applies the operation in the user transaction and returns the new data version.
+func commit(t *testing.T, s Storage, userID uuid.UUID, op func(tx storage.UserTransaction) error) uint64 {
+	tx, err := s.NewUserTransaction(context.Background(), userID)
+	require.NoError(t, err)
+	// nolint: errcheck
+	defer tx.Rollback()
+	require.NoError(t, op(tx))
+	dataVersion, err := tx.Commit()
+	require.NoError(t, err)
+	return dataVersion
+}
+
+func createText(t *testing.T, s Storage, userID uuid.UUID, text string) (models.Item, uint64) {
+	now := time.Now()
+	item := models.Item{ID: uuid.New(), CreatedAt: &now, Payload: models.TextData{Text: text}}
+	dataVersion := commit(t, s, userID, func(tx storage.UserTransaction) error {
+		var err error
+		item.Version, err = tx.CreateItem(context.Background(), item)
+		return err
+	})
+	return item, dataVersion
+}
+
+func updateText(t *testing.T, s Storage, userID uuid.UUID, item models.Item, text string) (models.Item, uint64) {
+	item.Payload = models.TextData{Text: text}
+	dataVersion := commit(t, s, userID, func(tx storage.UserTransaction) error {
+		var err error
+		item.Version, err = tx.UpdateItem(context.Background(), item)
+		return err
+	})
+	return item, dataVersion
+}
+
+// snapshot returns the texts of the user's items at the data version provided.
+func snapshot(t *testing.T, s Storage, userID uuid.UUID, dataVersion uint64) (map[uuid.UUID]string, error) {
+	tx, err := s.NewUserTransaction(context.Background(), userID)
+	require.NoError(t, err)
+	// nolint: errcheck
+	defer tx.Rollback()
+	items, err := tx.GetSnapshot(context.Background(), dataVersion)
+	if err != nil {
+		return nil, err
+	}
+	texts := make(map[uuid.UUID]string)
+	for _, item := range items {
+		if !item.IsTombstone() {
+			texts[item.ID] = item.Payload.(models.TextData).Text
+		}
+	}
+	return texts, nil
+}
+
+func TestGetSnapshot(t *testing.T) {
+	s := newTestStorage(t)
+	userID := newTestUser(t, s)
+
+	a, v1 := createText(t, s, userID, "a1")
+	_, v2 := updateText(t, s, userID, a, "a2")
+	b, v3 := createText(t, s, userID, "b1")
+
+	texts, err := snapshot(t, s, userID, 0)
+	require.NoError(t, err)
+	assert.Empty(t, texts)
+
+	texts, err = snapshot(t, s, userID, v1)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]string{a.ID: "a1"}, texts)
+
+	texts, err = snapshot(t, s, userID, v2)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]string{a.ID: "a2"}, texts)
+
+	texts, err = snapshot(t, s, userID, v3)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]string{a.ID: "a2", b.ID: "b1"}, texts)
+
+	_, err = snapshot(t, s, userID, v3+1)
+	assert.ErrorIs(t, err, storage.ErrSnapshotNotRetained, "the future data version is not retained")
+}
+
+func TestPruneItemHistory(t *testing.T) {
+	s := newTestStorage(t)
+	userID := newTestUser(t, s)
+
+	a, v1 := createText(t, s, userID, "a1")
+	a, v2 := updateText(t, s, userID, a, "a2")
+	_, v3 := updateText(t, s, userID, a, "a3")
+	b, v4 := createText(t, s, userID, "b1")
+
+	// only the latest version of each item is kept
+	_, err := s.PruneItemHistory(context.Background(), 1, time.Time{})
+	require.NoError(t, err)
+
+	versions, err := s.GetItemVersions(context.Background(), userID, a.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, v3, versions[0].DataVersion)
+
+	// the state of the item before its latest version can't be reconstructed
+	for _, dataVersion := range []uint64{0, v1, v2} {
+		_, err = snapshot(t, s, userID, dataVersion)
+		assert.ErrorIs(t, err, storage.ErrSnapshotNotRetained, "data version %d", dataVersion)
+	}
+	texts, err := snapshot(t, s, userID, v3)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]string{a.ID: "a3"}, texts)
+	texts, err = snapshot(t, s, userID, v4)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]string{a.ID: "a3", b.ID: "b1"}, texts)
+
+	// the single version of the item is never pruned
+	_, err = s.PruneItemHistory(context.Background(), 1, time.Now())
+	require.NoError(t, err)
+	texts, err = snapshot(t, s, userID, v4)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]string{a.ID: "a3", b.ID: "b1"}, texts)
+}
+
+func TestPurgeItem(t *testing.T) {
+	s := newTestStorage(t)
+	userID := newTestUser(t, s)
+
+	a, _ := createText(t, s, userID, "a1")
+	b, v2 := createText(t, s, userID, "b1")
+	v3 := commit(t, s, userID, func(tx storage.UserTransaction) error {
+		var err error
+		a.Version, err = tx.DeleteItem(context.Background(), a.ID, a.Version)
+		return err
+	})
+
+	v4, err := s.PurgeItem(context.Background(), userID, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, v3+1, v4, "the purge gets a new data version")
+	_, err = s.PurgeItem(context.Background(), userID, a.ID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// the item existed before its deletion, so the earlier snapshots are no longer retained
+	_, err = snapshot(t, s, userID, v2)
+	assert.ErrorIs(t, err, storage.ErrSnapshotNotRetained)
+	texts, err := snapshot(t, s, userID, v3)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]string{b.ID: "b1"}, texts)
+
+	// the client that hasn't synced the deletion receives the tombstone
+	changes, err := s.GetChangesSince(context.Background(), userID, v2)
+	require.NoError(t, err)
+	assert.Equal(t, v4, changes.Version)
+	require.Len(t, changes.Items, 1)
+	assert.Equal(t, a.ID, changes.Items[0].ID)
+	assert.Equal(t, a.Version, changes.Items[0].Version)
+	assert.True(t, changes.Items[0].IsTombstone())
+}
+
+func TestRestoreUnversionedItem(t *testing.T) {
+	s := newTestStorage(t)
+	userID := newTestUser(t, s)
+
+	// the item is created before the item history was introduced
+	a, v1 := createText(t, s, userID, "a1")
+	_, err := s.db.Exec(`DELETE FROM item_versions WHERE item_id=$1;`, a.ID)
+	require.NoError(t, err)
+	_, err = s.db.Exec(`UPDATE users SET history_start=0 WHERE id=$1;`, userID)
+	require.NoError(t, err)
+
+	require.NoError(t, s.archiveUnversionedItems(context.Background()))
+	versions, err := s.GetItemVersions(context.Background(), userID, a.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, v1, versions[0].DataVersion)
+	_, err = snapshot(t, s, userID, 0)
+	assert.ErrorIs(t, err, storage.ErrSnapshotNotRetained, "the history starts at the upgrade")
+
+	createText(t, s, userID, "b1")
+	keeper := gophkeeper.NewService(s)
+	defer keeper.Close()
+	_, changed, err := keeper.RestoreVault(context.Background(), userID, uuid.New(), v1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed, "only the item created after the data version is deleted")
+
+	texts, err := snapshot(t, s, userID, v1+2)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]string{a.ID: "a1"}, texts)
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+// GetLoginAttempts implements storage.Storage interface.
+func (s Storage) GetLoginAttempts(ctx context.Context, key string) (models.LoginAttempts, error) {
+	a := models.LoginAttempts{Key: key}
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT failures, last_failure_at, blocked_until, pending FROM login_attempts WHERE key=$1;`,
+		key,
+	).Scan(&a.Failures, &a.LastFailureAt, &a.BlockedUntil, &a.Pending)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.LoginAttempts{}, storage.ErrNotFound
+		}
+		return models.LoginAttempts{}, err
+	}
+	return a, nil
+}
+
+// ReserveLoginAttempt implements storage.Storage interface. The check and the reservation are made
+// by the single statement, so the concurrent attempts can't pass the check together.
+func (s Storage) ReserveLoginAttempt(ctx context.Context, key string, maxFailures int, resetBefore, staleBefore time.Time) (bool, error) {
+	var pending int
+	err := s.db.QueryRowContext(
+		ctx,
+		`INSERT INTO login_attempts AS a (key, pending, reserved_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET pending = CASE WHEN a.reserved_at < $4 THEN 1 ELSE a.pending + 1 END,
+			reserved_at = $2
+		WHERE (a.blocked_until IS NULL OR a.blocked_until <= $2)
+			AND (a.pending = 0 OR a.reserved_at < $4
+				OR CASE WHEN a.last_failure_at < $3 THEN 0 ELSE a.failures END + a.pending < $5)
+		RETURNING pending;`,
+		key,
+		time.Now(),
+		resetBefore,
+		staleBefore,
+		maxFailures,
+	).Scan(&pending)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseLoginAttempt implements storage.Storage interface.
+func (s Storage) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE login_attempts SET pending = GREATEST(pending - 1, 0) WHERE key=$1;`, key)
+	return err
+}
+
+// RegisterLoginFailure implements storage.Storage interface.
+func (s Storage) RegisterLoginFailure(ctx context.Context, key string, resetBefore time.Time) (models.LoginAttempts, error) {
+	a := models.LoginAttempts{Key: key}
+	err := s.db.QueryRowContext(
+		ctx,
+		`INSERT INTO login_attempts AS a (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE WHEN a.last_failure_at < $3 THEN 1 ELSE a.failures + 1 END,
+			last_failure_at = $2,
+			pending = GREATEST(a.pending - 1, 0)
+		RETURNING failures, last_failure_at, blocked_until, pending;`,
+		key,
+		time.Now(),
+		resetBefore,
+	).Scan(&a.Failures, &a.LastFailureAt, &a.BlockedUntil, &a.Pending)
+	if err != nil {
+		return models.LoginAttempts{}, err
+	}
+	return a, nil
+}
+
+// BlockLoginAttempts implements storage.Storage interface.
+func (s Storage) BlockLoginAttempts(ctx context.Context, key string, blockedUntil time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE login_attempts SET blocked_until=$1 WHERE key=$2;`, blockedUntil, key)
+	return err
+}
+
+// ResetLoginAttempts implements storage.Storage interface.
+func (s Storage) ResetLoginAttempts(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE key=$1;`, key)
+	return err
+}
+
+// CountRequest implements storage.Storage interface.
+func (s Storage) CountRequest(ctx context.Context, key string, windowStartBefore time.Time) (models.RequestCounter, error) {
+	c := models.RequestCounter{Key: key}
+	err := s.db.QueryRowContext(
+		ctx,
+		`INSERT INTO request_counters AS c (key, count, window_start)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET count = CASE WHEN c.window_start < $3 THEN 1 ELSE c.count + 1 END,
+			window_start = CASE WHEN c.window_start < $3 THEN $2 ELSE c.window_start END
+		RETURNING count, window_start;`,
+		key,
+		time.Now(),
+		windowStartBefore,
+	).Scan(&c.Count, &c.WindowStart)
+	if err != nil {
+		return models.RequestCounter{}, err
+	}
+	return c, nil
+}
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+// ReplaceRecoveryCodes implements storage.Storage interface.
+func (s Storage) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id=$1;`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO recovery_codes (id, user_id, code_hash) VALUES ($1, $2, $3);`,
+			id, userID, hash,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUnusedRecoveryCodes implements storage.Storage interface.
+func (s Storage) GetUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]models.RecoveryCode, error) {
+	codes := make([]models.RecoveryCode, 0)
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, code_hash FROM recovery_codes WHERE user_id=$1 AND used_at IS NULL;`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		code := models.RecoveryCode{UserID: userID}
+		if err := rows.Scan(&code.ID, &code.CodeHash); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, rows.Err()
+}
+
+// UseRecoveryCode implements storage.Storage interface.
+func (s Storage) UseRecoveryCode(ctx context.Context, codeID uuid.UUID) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE recovery_codes SET used_at=$1 WHERE id=$2 AND used_at IS NULL;`,
+		time.Now(), codeID,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+// UseTOTPStep implements storage.Storage interface.
+func (s Storage) UseTOTPStep(ctx context.Context, userID uuid.UUID, step uint64) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET totp_last_step=$1 WHERE id=$2 AND totp_last_step < $1;`,
+		int64(step), userID,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+// CreateMFAChallenge implements storage.Storage interface.
+func (s Storage) CreateMFAChallenge(ctx context.Context, c models.MFAChallengeState) error {
+	// the challenges that were never answered are removed here
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE expires_at<$1;`, time.Now()); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO mfa_challenges (id, user_id, expires_at) VALUES ($1, $2, $3);`,
+		c.ID, c.UserID, c.ExpiresAt,
+	)
+	return err
+}
+
+// CountMFAChallengeAttempt implements storage.Storage interface.
+func (s Storage) CountMFAChallengeAttempt(ctx context.Context, id uuid.UUID, maxAttempts int) (models.MFAChallengeState, error) {
+	c := models.MFAChallengeState{ID: id}
+	err := s.db.QueryRowContext(
+		ctx,
+		`UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE id=$1 AND attempts < $2 AND expires_at > $3
+		RETURNING user_id, attempts, expires_at;`,
+		id, maxAttempts, time.Now(),
+	).Scan(&c.UserID, &c.Attempts, &c.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.MFAChallengeState{}, storage.ErrNotFound
+		}
+		return models.MFAChallengeState{}, err
+	}
+	return c, nil
+}
+
+// DeleteMFAChallenge implements storage.Storage interface.
+func (s Storage) DeleteMFAChallenge(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE id=$1;`, id)
+	return err
+}
@@ -24,6 +24,8 @@ type (
 	UserTransaction struct {
 		tx     *sql.Tx
 		userID uuid.UUID
+		// changed are the IDs of the items touched by the transaction.
+		changed []uuid.UUID
 	}
 
 	PostgresOption func(s Storage) error
@@ -53,6 +55,9 @@ func NewStorage(connStr string, opts ...PostgresOption) (Storage, error) {
 	if _, err := db.Exec(queryCreate); err != nil {
 		return Storage{}, fmt.Errorf("newStorage: %w", err)
 	}
+	if err := s.archiveUnversionedItems(context.Background()); err != nil {
+		return Storage{}, fmt.Errorf("newStorage: %w", err)
+	}
 
 	return s, nil
 }
@@ -7,6 +7,96 @@ CREATE TABLE IF NOT EXISTS users (
     deleted_at timestamp
 );
 
+-- The columns added after the tables were introduced. CREATE TABLE IF NOT EXISTS doesn't change
+-- the existing tables, so the new columns are added by ALTER TABLE in order to upgrade the existing database.
+ALTER TABLE users ALTER COLUMN password_hash SET DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled boolean NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step bigint NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS srp_salt bytea;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS srp_verifier bytea;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified boolean NOT NULL DEFAULT false;
+-- The change log is empty for the users created before it was introduced, so their change log starts
+-- at the current data version: the clients synced earlier get the full state instead of no changes.
+-- The column is added and filled in once, since change_log_start is moved by the compaction later.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+        WHERE table_schema=current_schema() AND table_name='users' AND column_name='change_log_start') THEN
+        ALTER TABLE users ADD COLUMN change_log_start integer NOT NULL DEFAULT 0;
+        UPDATE users SET change_log_start = data_version;
+    END IF;
+END $$;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS history_start integer NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS item_changes (
+    user_id uuid NOT NULL,
+    data_version integer NOT NULL,
+    item_id uuid NOT NULL,
+    PRIMARY KEY (user_id, data_version, item_id),
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+-- the tombstones of the purged items are kept until the change log is compacted
+CREATE TABLE IF NOT EXISTS purged_items (
+    item_id uuid UNIQUE NOT NULL PRIMARY KEY,
+    user_id uuid NOT NULL,
+    version integer NOT NULL,
+    data_version integer,
+    deleted_at timestamp,
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS item_versions (
+    item_id uuid NOT NULL,
+    version integer NOT NULL,
+    user_id uuid NOT NULL,
+    data_version integer NOT NULL,
+    kind text NOT NULL,
+    payload jsonb NOT NULL,
+    meta text,
+    created_at timestamp,
+    deleted_at timestamp,
+    archived_at timestamp NOT NULL,
+    PRIMARY KEY (item_id, version),
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id uuid UNIQUE NOT NULL PRIMARY KEY,
+    user_id uuid NOT NULL,
+    purpose text NOT NULL,
+    token_hash text UNIQUE NOT NULL,
+    expires_at timestamp NOT NULL,
+    used_at timestamp,
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id uuid UNIQUE NOT NULL PRIMARY KEY,
+    user_id uuid NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    expires_at timestamp NOT NULL,
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS srp_handshakes (
+    id uuid UNIQUE NOT NULL PRIMARY KEY,
+    user_id uuid NOT NULL,
+    client_public bytea NOT NULL,
+    server_secret bytea NOT NULL,
+    expires_at timestamp NOT NULL,
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id uuid UNIQUE NOT NULL PRIMARY KEY,
+    user_id uuid NOT NULL,
+    code_hash text NOT NULL,
+    used_at timestamp,
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
 CREATE TABLE IF NOT EXISTS sessions (
     id uuid UNIQUE NOT NULL PRIMARY KEY,
     user_id uuid NOT NULL,
@@ -16,6 +106,38 @@ CREATE TABLE IF NOT EXISTS sessions (
     CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
 );
 
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS client_name text;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS client_version text;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS remote_addr text;
+
+CREATE TABLE IF NOT EXISTS security_events (
+    id uuid UNIQUE NOT NULL PRIMARY KEY,
+    user_id uuid NOT NULL,
+    session_id uuid,
+    kind text NOT NULL,
+    remote_addr text,
+    created_at timestamp,
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key text UNIQUE NOT NULL PRIMARY KEY,
+    failures integer NOT NULL DEFAULT 0,
+    last_failure_at timestamp,
+    blocked_until timestamp
+);
+
+-- the attempts in progress are reserved before the credentials are verified
+ALTER TABLE login_attempts ADD COLUMN IF NOT EXISTS pending integer NOT NULL DEFAULT 0;
+ALTER TABLE login_attempts ADD COLUMN IF NOT EXISTS reserved_at timestamp;
+
+-- the requests limited by rate (not by failures) are counted in the fixed time windows
+CREATE TABLE IF NOT EXISTS request_counters (
+    key text UNIQUE NOT NULL PRIMARY KEY,
+    count integer NOT NULL DEFAULT 0,
+    window_start timestamp NOT NULL
+);
+
 CREATE TABLE IF NOT EXISTS passwords (
     id uuid UNIQUE NOT NULL,
     user_id uuid NOT NULL,
@@ -58,4 +180,94 @@ CREATE TABLE IF NOT EXISTS cards (
     created_at timestamp,
     deleted_at timestamp,
     CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
-);
\ No newline at end of file
+);
+CREATE TABLE IF NOT EXISTS otps (
+    id uuid UNIQUE NOT NULL,
+    user_id uuid NOT NULL,
+    secret bytea,
+    algorithm text,
+    digits integer,
+    period integer,
+    issuer text,
+    account text,
+    version integer NOT NULL DEFAULT 0,
+    meta TEXT,
+    created_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE TABLE IF NOT EXISTS ssh_keys (
+    id uuid UNIQUE NOT NULL,
+    user_id uuid NOT NULL,
+    private_key bytea,
+    public_key text,
+    comment text,
+    passphrase text,
+    version integer NOT NULL DEFAULT 0,
+    meta TEXT,
+    created_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE TABLE IF NOT EXISTS blob_contents (
+    user_id uuid NOT NULL,
+    sha256 text NOT NULL,
+    size bigint NOT NULL,
+    received bigint NOT NULL DEFAULT 0,
+    complete boolean NOT NULL DEFAULT false,
+    created_at timestamp,
+    PRIMARY KEY (user_id, sha256),
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE TABLE IF NOT EXISTS blob_chunks (
+    user_id uuid NOT NULL,
+    sha256 text NOT NULL,
+    chunk_offset bigint NOT NULL,
+    data bytea NOT NULL,
+    PRIMARY KEY (user_id, sha256, chunk_offset),
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS blob_garbage (
+    sha256 text UNIQUE NOT NULL PRIMARY KEY,
+    collected_at timestamp
+);
+
+ALTER TABLE blobs ADD COLUMN IF NOT EXISTS size bigint NOT NULL DEFAULT 0;
+ALTER TABLE blobs ADD COLUMN IF NOT EXISTS sha256 text NOT NULL DEFAULT '';
+ALTER TABLE blob_contents ADD COLUMN IF NOT EXISTS ref_count integer NOT NULL DEFAULT 0;
+ALTER TABLE blob_contents ADD COLUMN IF NOT EXISTS touched_at timestamp;
+
+-- The blobs were stored inline before the chunked upload: their content is moved to the blob chunks
+-- and the items keep only the reference to it. The item history is converted the same way.
+INSERT INTO blob_contents (user_id, sha256, size, received, complete, created_at, touched_at)
+SELECT user_id, encode(sha256(blob), 'hex'), length(blob), length(blob), true, now(), now()
+FROM blobs WHERE blob IS NOT NULL
+ON CONFLICT DO NOTHING;
+INSERT INTO blob_chunks (user_id, sha256, chunk_offset, data)
+SELECT user_id, encode(sha256(blob), 'hex'), 0, blob
+FROM blobs WHERE blob IS NOT NULL AND length(blob) > 0
+ON CONFLICT DO NOTHING;
+UPDATE blobs SET size=length(blob), sha256=encode(sha256(blob), 'hex'), blob=NULL WHERE blob IS NOT NULL;
+
+INSERT INTO blob_contents (user_id, sha256, size, received, complete, created_at, touched_at)
+SELECT user_id, encode(sha256(content), 'hex'), length(content), length(content), true, now(), now()
+FROM (SELECT user_id, decode(payload->>'data', 'base64') AS content FROM item_versions
+    WHERE kind='BLOB' AND payload->>'data' IS NOT NULL) legacy
+ON CONFLICT DO NOTHING;
+INSERT INTO blob_chunks (user_id, sha256, chunk_offset, data)
+SELECT user_id, encode(sha256(content), 'hex'), 0, content
+FROM (SELECT user_id, decode(payload->>'data', 'base64') AS content FROM item_versions
+    WHERE kind='BLOB' AND payload->>'data' IS NOT NULL) legacy
+WHERE length(content) > 0
+ON CONFLICT DO NOTHING;
+UPDATE item_versions SET payload=jsonb_build_object(
+    'size', length(decode(payload->>'data', 'base64')),
+    'sha256', encode(sha256(decode(payload->>'data', 'base64')), 'hex'))
+WHERE kind='BLOB' AND payload->>'data' IS NOT NULL;
+
+-- The reference counters of the blob contents are recounted at start, so the contents converted above
+-- and the ones uploaded before the counting was introduced are never collected while referred to.
+UPDATE blob_contents c SET ref_count =
+    (SELECT count(*) FROM blobs b WHERE b.user_id=c.user_id AND b.sha256=c.sha256) +
+    (SELECT count(*) FROM item_versions v WHERE v.user_id=c.user_id AND v.kind='BLOB' AND v.payload->>'sha256'=c.sha256);
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/vanamelnik/gophkeeper/models"
@@ -15,12 +16,15 @@ func (s Storage) CreateSession(ctx context.Context, session models.Session) erro
 	_, err := s.db.ExecContext(
 		ctx,
 		`INSERT INTO sessions
-		(id, user_id, refresh_token, login_at)
-		VALUES ($1, $2, $3, $4);`,
+		(id, user_id, refresh_token, login_at, client_name, client_version, remote_addr)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);`,
 		session.ID,
 		session.UserID,
 		session.RefreshToken,
 		session.LoginAt,
+		session.Client.Name,
+		session.Client.Version,
+		session.Client.RemoteAddr,
 	)
 	if err != nil {
 		return err
@@ -54,11 +58,12 @@ func (s Storage) GetSessionByID(ctx context.Context, sessionID uuid.UUID) (model
 	session := models.Session{ID: sessionID}
 	err := s.db.QueryRowContext(
 		ctx,
-		`SELECT user_id, refresh_token, login_at, logout_at
+		`SELECT user_id, refresh_token, login_at, logout_at, client_name, client_version, remote_addr
 		FROM sessions
 		WHERE id=$1 AND logout_at IS NULL`,
 		sessionID,
-	).Scan(&session.UserID, &session.RefreshToken, &session.LoginAt, &session.LogoutAt)
+	).Scan(&session.UserID, &session.RefreshToken, &session.LoginAt, &session.LogoutAt,
+		&session.Client.Name, &session.Client.Version, &session.Client.RemoteAddr)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.Session{}, storage.ErrNotFound
@@ -75,7 +80,7 @@ func (s Storage) GetActiveUserSessions(ctx context.Context, userID uuid.UUID) ([
 	sessions := make([]models.Session, 0)
 	rows, err := s.db.QueryContext(
 		ctx,
-		`SELECT (id, refresh_token, login_at, logout_at)
+		`SELECT id, refresh_token, login_at, logout_at, client_name, client_version, remote_addr
 		FROM sessions
 		WHERE user_id = $1 AND logout_at IS NULL`,
 		userID,
@@ -86,7 +91,8 @@ func (s Storage) GetActiveUserSessions(ctx context.Context, userID uuid.UUID) ([
 	defer rows.Close()
 	for rows.Next() {
 		session := models.Session{UserID: userID}
-		if err := rows.Scan(&session.ID, &session.RefreshToken, &session.LoginAt, &session.LogoutAt); err != nil {
+		if err := rows.Scan(&session.ID, &session.RefreshToken, &session.LoginAt, &session.LogoutAt,
+			&session.Client.Name, &session.Client.Version, &session.Client.RemoteAddr); err != nil {
 			return nil, err
 		}
 		sessions = append(sessions, session)
@@ -94,3 +100,39 @@ func (s Storage) GetActiveUserSessions(ctx context.Context, userID uuid.UUID) ([
 
 	return sessions, nil
 }
+
+// LogoutAll implements storage.Storage interface.
+func (s Storage) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE sessions
+		SET logout_at=$1
+		WHERE user_id=$2 AND logout_at IS NULL;`,
+		time.Now(),
+		userID,
+	)
+
+	return err
+}
+
+// CreateSecurityEvent implements storage.Storage interface.
+func (s Storage) CreateSecurityEvent(ctx context.Context, event models.SecurityEvent) error {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(
+		ctx,
+		`INSERT INTO security_events
+		(id, user_id, session_id, kind, remote_addr, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6);`,
+		id,
+		event.UserID,
+		event.SessionID,
+		event.Kind,
+		event.RemoteAddr,
+		time.Now(),
+	)
+
+	return err
+}
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+// CreateSRPHandshake implements storage.Storage interface.
+func (s Storage) CreateSRPHandshake(ctx context.Context, h models.SRPHandshake) error {
+	// the handshakes that were never finished are removed here
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM srp_handshakes WHERE expires_at<$1;`, time.Now()); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO srp_handshakes (id, user_id, client_public, server_secret, expires_at)
+		VALUES ($1, $2, $3, $4, $5);`,
+		h.ID,
+		h.UserID,
+		h.ClientPublic,
+		h.ServerSecret,
+		h.ExpiresAt,
+	)
+	return err
+}
+
+// TakeSRPHandshake implements storage.Storage interface.
+func (s Storage) TakeSRPHandshake(ctx context.Context, id uuid.UUID) (models.SRPHandshake, error) {
+	h := models.SRPHandshake{ID: id}
+	err := s.db.QueryRowContext(
+		ctx,
+		`DELETE FROM srp_handshakes WHERE id=$1
+		RETURNING user_id, client_public, server_secret, expires_at;`,
+		id,
+	).Scan(&h.UserID, &h.ClientPublic, &h.ServerSecret, &h.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.SRPHandshake{}, storage.ErrNotFound
+		}
+		return models.SRPHandshake{}, err
+	}
+	return h, nil
+}
@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+// GetTrash implements storage.Storage interface.
+func (s Storage) GetTrash(ctx context.Context, userID uuid.UUID) ([]models.Item, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	items, err := getItems(ctx, tx, `user_id=$1 AND deleted_at IS NOT NULL`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return items, tx.Commit()
+}
+
+// PurgeItem implements storage.Storage interface.
+func (s Storage) PurgeItem(ctx context.Context, userID, itemID uuid.UUID) (uint64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	// the purged blob and its history don't refer to the blob content anymore
+	if _, err := tx.ExecContext(ctx, adjustBlobRefs("-",
+		`SELECT user_id, sha256 FROM blobs WHERE id=$1 AND user_id=$2 AND deleted_at IS NOT NULL
+		UNION ALL
+		SELECT user_id, payload->>'sha256' FROM item_versions WHERE item_id=$1 AND user_id=$2 AND kind='BLOB'`)+`;`,
+		itemID, userID); err != nil {
+		return 0, err
+	}
+
+	purged, err := purgeItems(ctx, tx, `id=$1 AND user_id=$2 AND deleted_at IS NOT NULL`, itemID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if purged == 0 {
+		return 0, storage.ErrNotFound
+	}
+	if err := recordPurges(ctx, tx); err != nil {
+		return 0, err
+	}
+	var dataVersion uint64
+	if err := tx.QueryRowContext(ctx, `SELECT data_version FROM users WHERE id=$1;`,
+		userID).Scan(&dataVersion); err != nil {
+		return 0, err
+	}
+
+	return dataVersion, tx.Commit()
+}
+
+// PurgeTrash implements storage.Storage interface.
+func (s Storage) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, adjustBlobRefs("-",
+		`SELECT user_id, sha256 FROM blobs WHERE deleted_at < $1
+		UNION ALL
+		SELECT v.user_id, v.payload->>'sha256' FROM item_versions v JOIN blobs b ON v.item_id = b.id
+		WHERE b.deleted_at < $1 AND v.kind='BLOB'`)+`;`,
+		deletedBefore); err != nil {
+		return 0, err
+	}
+
+	purged, err := purgeItems(ctx, tx, `deleted_at < $1`, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	if err := recordPurges(ctx, tx); err != nil {
+		return 0, err
+	}
+
+	return purged, tx.Commit()
+}
+
+// purgeItems erases the items of all types that match the condition. The purged items are kept
+// in the purged_items table without the payload until the purge is recorded by recordPurges.
+func purgeItems(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) (int64, error) {
+	var purged int64
+	for _, table := range itemTables {
+		res, err := tx.ExecContext(ctx,
+			`WITH purged AS (DELETE FROM `+table+` WHERE `+where+` RETURNING user_id, id, version, deleted_at)
+			INSERT INTO purged_items (user_id, item_id, version, deleted_at)
+			SELECT user_id, id, version, deleted_at FROM purged;`,
+			args...)
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		purged += n
+	}
+	return purged, nil
+}
+
+// recordPurges erases the history of the items purged in the transaction and records the purge
+// in the change log under the new data version of each user, so the clients that haven't synced
+// the deletion receive the tombstones. The snapshots taken before the deletion of the purged items
+// can't be reconstructed anymore, so history_start of the user is moved to the data version of the deletion.
+func recordPurges(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users u SET history_start = GREATEST(u.history_start, h.deleted_in)
+		FROM (
+			SELECT v.user_id, max(v.data_version) AS deleted_in FROM item_versions v
+			JOIN purged_items p ON v.item_id = p.item_id AND p.data_version IS NULL
+			GROUP BY v.user_id
+		) h
+		WHERE u.id = h.user_id;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM item_versions v USING purged_items p WHERE v.item_id = p.item_id AND p.data_version IS NULL;`); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx,
+		`WITH bumped AS (
+			UPDATE users SET data_version = data_version + 1
+			WHERE id IN (SELECT user_id FROM purged_items WHERE data_version IS NULL)
+			RETURNING id, data_version
+		), recorded AS (
+			UPDATE purged_items p SET data_version = b.data_version
+			FROM bumped b WHERE p.user_id = b.id AND p.data_version IS NULL
+			RETURNING p.user_id, p.data_version, p.item_id
+		)
+		INSERT INTO item_changes (user_id, data_version, item_id)
+		SELECT user_id, data_version, item_id FROM recorded
+		ON CONFLICT DO NOTHING;`)
+	return err
+}
+
+// getPurgedItems retrieves the tombstones of the purged items that match the condition.
+func getPurgedItems(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) ([]models.Item, error) {
+	items := make([]models.Item, 0)
+	rows, err := tx.QueryContext(ctx,
+		`SELECT item_id, version, deleted_at FROM purged_items WHERE `+where+`;`,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		item := models.Item{}
+		if err := rows.Scan(&item.ID, &item.Version, &item.DeletedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
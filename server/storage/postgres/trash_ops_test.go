@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+func TestPurgeItemErasesPayload(t *testing.T) {
+	s := newTestStorage(t)
+	userID := newTestUser(t, s)
+
+	a, _ := createText(t, s, userID, "secret")
+	a, _ = updateText(t, s, userID, a, "secret2")
+	commit(t, s, userID, func(tx storage.UserTransaction) error {
+		var err error
+		a.Version, err = tx.DeleteItem(context.Background(), a.ID, a.Version)
+		return err
+	})
+
+	// the deleted item keeps its payload in the trash
+	trash, err := s.GetTrash(context.Background(), userID)
+	require.NoError(t, err)
+	require.Len(t, trash, 1)
+	assert.Equal(t, models.TextData{Text: "secret2"}, trash[0].Payload)
+
+	_, err = s.PurgeItem(context.Background(), userID, a.ID)
+	require.NoError(t, err)
+
+	trash, err = s.GetTrash(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Empty(t, trash)
+	// neither the item nor its archived versions keep the payload
+	var n int
+	require.NoError(t, s.db.QueryRow(`SELECT count(*) FROM texts WHERE id=$1;`, a.ID).Scan(&n))
+	assert.Zero(t, n)
+	require.NoError(t, s.db.QueryRow(`SELECT count(*) FROM item_versions WHERE item_id=$1;`, a.ID).Scan(&n))
+	assert.Zero(t, n)
+}
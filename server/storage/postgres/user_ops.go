@@ -7,12 +7,16 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
 	"github.com/vanamelnik/gophkeeper/models"
 	"github.com/vanamelnik/gophkeeper/server/storage"
 )
 
+// uniqueViolationCode is the postgres error code of unique constraint violation.
+const uniqueViolationCode = "23505"
+
 // CreateUser implements storage.Storage interface.
-func (s Storage) CreateUser(ctx context.Context, email, passwordHash string) (models.User, error) {
+func (s Storage) CreateUser(ctx context.Context, user models.User) (models.User, error) {
 	id, err := uuid.NewRandom()
 	if err != nil {
 		return models.User{}, err
@@ -21,32 +25,57 @@ func (s Storage) CreateUser(ctx context.Context, email, passwordHash string) (mo
 	_, err = s.db.ExecContext(
 		ctx,
 		`INSERT INTO users
-		(id, email, password_hash, data_version, created_at)
-		VALUES ($1, $2, $3, $4, $5);`,
+		(id, email, password_hash, srp_salt, srp_verifier, data_version, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);`,
 		id,
-		email,
-		passwordHash,
+		user.Email,
+		user.PasswordHash,
+		user.SRPSalt,
+		user.SRPVerifier,
 		0,
 		now,
 	)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return models.User{}, storage.ErrAlreadyExists
+		}
 		return models.User{}, err
 	}
 
-	return models.User{
-		ID:           id,
-		Email:        email,
-		PasswordHash: passwordHash,
-		CreatedAt:    &now,
-		DeletedAt:    nil,
-	}, nil
+	user.ID = id
+	user.CreatedAt = &now
+	user.DeletedAt = nil
+	return user, nil
 }
 
 // GetUserByEmail implements storage.Storage interface.
 func (s Storage) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
 	u := models.User{Email: email}
-	err := s.db.QueryRowContext(ctx, `SELECT (id, password_hash, created_at) FROM users WHERE email=$1 AND deleted_at IS NULL;`, email).
-		Scan(&u.ID, &u.PasswordHash, &u.CreatedAt)
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT id, password_hash, created_at, totp_secret, totp_enabled, srp_salt, srp_verifier, email_verified
+		FROM users WHERE email=$1 AND deleted_at IS NULL;`,
+		email,
+	).Scan(&u.ID, &u.PasswordHash, &u.CreatedAt, &u.TOTPSecret, &u.TOTPEnabled, &u.SRPSalt, &u.SRPVerifier, &u.EmailVerified)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, storage.ErrNotFound
+		}
+		return models.User{}, err
+	}
+	return u, nil
+}
+
+// GetUserByID implements storage.Storage interface.
+func (s Storage) GetUserByID(ctx context.Context, userID uuid.UUID) (models.User, error) {
+	u := models.User{ID: userID}
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT email, password_hash, created_at, totp_secret, totp_enabled, srp_salt, srp_verifier, email_verified
+		FROM users WHERE id=$1 AND deleted_at IS NULL;`,
+		userID,
+	).Scan(&u.Email, &u.PasswordHash, &u.CreatedAt, &u.TOTPSecret, &u.TOTPEnabled, &u.SRPSalt, &u.SRPVerifier, &u.EmailVerified)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, storage.ErrNotFound
@@ -56,6 +85,37 @@ func (s Storage) GetUserByEmail(ctx context.Context, email string) (models.User,
 	return u, nil
 }
 
+// UpdateUser implements storage.Storage interface.
+func (s Storage) UpdateUser(ctx context.Context, user models.User) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users
+		SET email=$1, password_hash=$2, totp_secret=$3, totp_enabled=$4, srp_salt=$5, srp_verifier=$6,
+		email_verified=$7
+		WHERE id=$8 AND deleted_at IS NULL;`,
+		user.Email,
+		user.PasswordHash,
+		user.TOTPSecret,
+		user.TOTPEnabled,
+		user.SRPSalt,
+		user.SRPVerifier,
+		user.EmailVerified,
+		user.ID,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
 // GetUserDataVersion implements storage.Storage interface.
 func (s Storage) GetUserDataVersion(ctx context.Context, userID uuid.UUID) (uint64, error) {
 	var dataVersion uint64
@@ -68,3 +128,121 @@ func (s Storage) GetUserDataVersion(ctx context.Context, userID uuid.UUID) (uint
 	}
 	return dataVersion, nil
 }
+
+// GetDeletedUserByEmail implements storage.Storage interface.
+func (s Storage) GetDeletedUserByEmail(ctx context.Context, email string) (models.User, error) {
+	u := models.User{Email: email}
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT id, password_hash, created_at, deleted_at, totp_secret, totp_enabled, srp_salt, srp_verifier
+		FROM users WHERE email=$1 AND deleted_at IS NOT NULL;`,
+		email,
+	).Scan(&u.ID, &u.PasswordHash, &u.CreatedAt, &u.DeletedAt, &u.TOTPSecret, &u.TOTPEnabled, &u.SRPSalt, &u.SRPVerifier)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, storage.ErrNotFound
+		}
+		return models.User{}, err
+	}
+	return u, nil
+}
+
+// GetDeletedUserByID implements storage.Storage interface.
+func (s Storage) GetDeletedUserByID(ctx context.Context, userID uuid.UUID) (models.User, error) {
+	u := models.User{ID: userID}
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT email, password_hash, created_at, deleted_at, totp_secret, totp_enabled, srp_salt, srp_verifier
+		FROM users WHERE id=$1 AND deleted_at IS NOT NULL;`,
+		userID,
+	).Scan(&u.Email, &u.PasswordHash, &u.CreatedAt, &u.DeletedAt, &u.TOTPSecret, &u.TOTPEnabled, &u.SRPSalt, &u.SRPVerifier)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, storage.ErrNotFound
+		}
+		return models.User{}, err
+	}
+	return u, nil
+}
+
+// DeleteUser implements storage.Storage interface.
+func (s Storage) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	now := time.Now()
+	res, err := tx.ExecContext(ctx, `UPDATE users SET deleted_at=$1 WHERE id=$2 AND deleted_at IS NULL;`, now, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE sessions SET logout_at=$1 WHERE user_id=$2 AND logout_at IS NULL;`,
+		now, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UndeleteUser implements storage.Storage interface.
+func (s Storage) UndeleteUser(ctx context.Context, userID uuid.UUID) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE users SET deleted_at=NULL WHERE id=$1 AND deleted_at IS NOT NULL;`, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+// PurgeDeletedUsers implements storage.Storage interface.
+func (s Storage) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	// the blob contents of the users may be shared with other users, so they are only queued for the check
+	if err := queueBlobGarbage(ctx, tx,
+		`SELECT sha256 FROM blob_contents WHERE user_id IN (SELECT id FROM users WHERE deleted_at < $1)`,
+		deletedBefore); err != nil {
+		return 0, err
+	}
+	// the user's rows must be erased from all the tables referencing the users table
+	for _, table := range []string{"passwords", "blobs", "texts", "cards", "otps", "ssh_keys", "sessions", "security_events", "recovery_codes", "mfa_challenges", "srp_handshakes", "user_tokens", "item_changes", "item_versions", "purged_items", "blob_chunks", "blob_contents"} {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM `+table+` WHERE user_id IN (SELECT id FROM users WHERE deleted_at < $1);`,
+			deletedBefore); err != nil {
+			return 0, err
+		}
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM users WHERE deleted_at < $1;`, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+// CreateUserToken implements storage.Storage interface.
+func (s Storage) CreateUserToken(ctx context.Context, t models.UserToken) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM user_tokens WHERE user_id=$1 AND purpose=$2 AND used_at IS NULL;`,
+		t.UserID,
+		t.Purpose,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO user_tokens (id, user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5);`,
+		t.ID,
+		t.UserID,
+		t.Purpose,
+		t.TokenHash,
+		t.ExpiresAt,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseUserToken implements storage.Storage interface.
+func (s Storage) UseUserToken(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (models.UserToken, error) {
+	now := time.Now()
+	t := models.UserToken{
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		UsedAt:    &now,
+	}
+	err := s.db.QueryRowContext(
+		ctx,
+		`UPDATE user_tokens SET used_at=$1
+		WHERE token_hash=$2 AND purpose=$3 AND used_at IS NULL AND expires_at>$1
+		RETURNING id, user_id, expires_at;`,
+		now,
+		tokenHash,
+		purpose,
+	).Scan(&t.ID, &t.UserID, &t.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.UserToken{}, storage.ErrNotFound
+		}
+		return models.UserToken{}, err
+	}
+	return t, nil
+}
+
+// GetUserToken implements storage.Storage interface.
+func (s Storage) GetUserToken(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (models.UserToken, error) {
+	t := models.UserToken{
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+	}
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT id, user_id, expires_at FROM user_tokens
+		WHERE token_hash=$1 AND purpose=$2 AND used_at IS NULL AND expires_at>$3;`,
+		tokenHash,
+		purpose,
+		time.Now(),
+	).Scan(&t.ID, &t.UserID, &t.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.UserToken{}, storage.ErrNotFound
+		}
+		return models.UserToken{}, err
+	}
+	return t, nil
+}
+
+// ResetPassword implements storage.Storage interface.
+func (s Storage) ResetPassword(ctx context.Context, tokenID uuid.UUID, salt, verifier []byte) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`WITH used AS (
+			UPDATE user_tokens SET used_at=$1
+			WHERE id=$2 AND used_at IS NULL AND expires_at>$1
+			RETURNING user_id
+		)
+		UPDATE users u SET srp_salt=$3, srp_verifier=$4, password_hash='', email_verified=true
+		FROM used WHERE u.id = used.user_id AND u.deleted_at IS NULL;`,
+		time.Now(),
+		tokenID,
+		salt,
+		verifier,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
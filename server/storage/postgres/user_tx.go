@@ -4,41 +4,167 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
 	"github.com/vanamelnik/gophkeeper/models"
 	"github.com/vanamelnik/gophkeeper/server/storage"
 )
 
 // CreateItem implements storage.UserTransaction interface.
-func (t *UserTransaction) CreateItem(ctx context.Context, item models.Item) error {
-	switch data := item.Payload.(type) {
-	case models.TextData:
-		return t.createText(ctx, item, data)
-	case models.BinaryData:
-		return t.createBlob(ctx, item, data)
-	case models.PasswordData:
-		return t.createPassword(ctx, item, data)
-	case models.CardData:
-		return t.createCard(ctx, item, data)
+func (t *UserTransaction) CreateItem(ctx context.Context, item models.Item) (uint64, error) {
+	err := t.withSavepoint(ctx, func() error {
+		switch data := item.Payload.(type) {
+		case models.TextData:
+			return t.createText(ctx, item, data)
+		case models.BinaryData:
+			return t.createBlob(ctx, item, data)
+		case models.PasswordData:
+			return t.createPassword(ctx, item, data)
+		case models.CardData:
+			return t.createCard(ctx, item, data)
+		case models.OTPData:
+			return t.createOTP(ctx, item, data)
+		case models.SSHKeyData:
+			return t.createSSHKey(ctx, item, data)
+		}
+		return errors.New("unreachable error: wrong item payload type")
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return 0, storage.ErrAlreadyExists
+		}
+		return 0, err
 	}
+	t.changed = append(t.changed, item.ID)
 
-	return errors.New("unreachable error: wrong item payload type")
+	return 1, nil
 }
 
 // UpdateItem implements storage.UserTransaction interface.
-func (t *UserTransaction) UpdateItem(ctx context.Context, item models.Item) error {
-	switch data := item.Payload.(type) {
-	case models.TextData:
-		return t.updateText(ctx, item, data)
-	case models.BinaryData:
-		return t.updateBlob(ctx, item, data)
-	case models.PasswordData:
-		return t.updatePassword(ctx, item, data)
-	case models.CardData:
-		return t.updateCard(ctx, item, data)
+func (t *UserTransaction) UpdateItem(ctx context.Context, item models.Item) (uint64, error) {
+	err := t.withSavepoint(ctx, func() error {
+		switch data := item.Payload.(type) {
+		case models.TextData:
+			return t.updateText(ctx, item, data)
+		case models.BinaryData:
+			return t.updateBlob(ctx, item, data)
+		case models.PasswordData:
+			return t.updatePassword(ctx, item, data)
+		case models.CardData:
+			return t.updateCard(ctx, item, data)
+		case models.OTPData:
+			return t.updateOTP(ctx, item, data)
+		case models.SSHKeyData:
+			return t.updateSSHKey(ctx, item, data)
+		}
+		return errors.New("unreachable error: wrong item payload type")
+	})
+	if err != nil {
+		return 0, err
+	}
+	t.changed = append(t.changed, item.ID)
+
+	return item.Version + 1, nil
+}
+
+// itemTables are the tables of the items of all types.
+var itemTables = []string{"texts", "passwords", "cards", "blobs", "otps", "ssh_keys"}
+
+// DeleteItem implements storage.UserTransaction interface.
+func (t *UserTransaction) DeleteItem(ctx context.Context, itemID uuid.UUID, version uint64) (uint64, error) {
+	err := t.withSavepoint(ctx, func() error {
+		now := time.Now()
+		// the type of the item is unknown, so each table is tried until the item is found;
+		// the payload stays for the trash and is erased by the purge
+		for _, table := range itemTables {
+			res, err := t.tx.ExecContext(
+				ctx,
+				`UPDATE `+table+` SET version=$1, deleted_at=$2 WHERE id=$3 AND user_id=$4 AND version=$5;`,
+				version+1, // This increments the item version!
+				now,
+				itemID,
+				t.userID,
+				version,
+			)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				return nil
+			}
+		}
+		return t.notUpdatedError(ctx, itemID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	t.changed = append(t.changed, itemID)
+
+	return version + 1, nil
+}
+
+// RestoreItem implements storage.UserTransaction interface.
+func (t *UserTransaction) RestoreItem(ctx context.Context, itemID uuid.UUID) (models.Item, error) {
+	var item models.Item
+	err := t.withSavepoint(ctx, func() error {
+		for _, table := range itemTables {
+			res, err := t.tx.ExecContext(
+				ctx,
+				`UPDATE `+table+` SET version=version+1, deleted_at=NULL
+				WHERE id=$1 AND user_id=$2 AND deleted_at IS NOT NULL;`,
+				itemID,
+				t.userID,
+			)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				continue
+			}
+			items, err := getItems(ctx, t.tx, `id=$1 AND user_id=$2`, itemID, t.userID)
+			if err != nil {
+				return err
+			}
+			if len(items) == 0 {
+				return storage.ErrNotFound
+			}
+			item = items[0]
+			return nil
+		}
+		return storage.ErrNotFound
+	})
+	if err != nil {
+		return models.Item{}, err
 	}
+	t.changed = append(t.changed, itemID)
+
+	return item, nil
+}
 
-	return errors.New("unreachable error: wrong item payload type")
+// withSavepoint runs the operation so that its failure doesn't abort the whole transaction.
+func (t *UserTransaction) withSavepoint(ctx context.Context, op func() error) error {
+	if _, err := t.tx.ExecContext(ctx, `SAVEPOINT item_op;`); err != nil {
+		return err
+	}
+	if err := op(); err != nil {
+		if _, rbErr := t.tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT item_op;`); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+	_, err := t.tx.ExecContext(ctx, `RELEASE SAVEPOINT item_op;`)
+	return err
 }
 
 // Rollback implements storage.UserTransaction interface.
@@ -47,16 +173,31 @@ func (t *UserTransaction) Rollback() error {
 }
 
 // Commit implements storage.UserTransaction interface.
-func (t *UserTransaction) Commit() error {
-	_, err := t.tx.Exec(`UPDATE users SET data_version = data_version+1 WHERE id=$1;`, t.userID)
+// The items touched by the transaction are recorded in the change log under the new data version
+// and their new versions are stored in the item history.
+func (t *UserTransaction) Commit() (uint64, error) {
+	var version uint64
+	err := t.tx.QueryRow(`UPDATE users SET data_version = data_version+1 WHERE id=$1 RETURNING data_version;`,
+		t.userID).Scan(&version)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return storage.ErrNotFound
+			return 0, storage.ErrNotFound
+		}
+		return 0, err
+	}
+	for _, itemID := range t.changed {
+		if _, err := t.tx.Exec(
+			`INSERT INTO item_changes (user_id, data_version, item_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING;`,
+			t.userID, version, itemID,
+		); err != nil {
+			return 0, err
 		}
-		return err
+	}
+	if err := t.archiveChanges(version); err != nil {
+		return 0, err
 	}
 
-	return t.tx.Commit()
+	return version, t.tx.Commit()
 }
 
 // createText adds a new text item into the texts table. Item version is set to 1.
@@ -89,13 +230,47 @@ func (t *UserTransaction) createCard(ctx context.Context, item models.Item, data
 	return nil
 }
 
+// createOTP adds a new one-time password key into the otps table. Item version is set to 1.
+func (t *UserTransaction) createOTP(ctx context.Context, item models.Item, data models.OTPData) error {
+	_, err := t.tx.ExecContext(
+		ctx,
+		`INSERT INTO otps (id, user_id, version, meta, created_at, secret, algorithm, digits, period, issuer, account)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);`,
+		item.ID, t.userID, 1, item.Meta, item.CreatedAt,
+		data.Secret, data.Algorithm, data.Digits, data.Period, data.Issuer, data.Account,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// createSSHKey adds a new SSH key into the ssh_keys table. Item version is set to 1.
+func (t *UserTransaction) createSSHKey(ctx context.Context, item models.Item, data models.SSHKeyData) error {
+	_, err := t.tx.ExecContext(
+		ctx,
+		`INSERT INTO ssh_keys (id, user_id, version, meta, created_at, private_key, public_key, comment, passphrase)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`,
+		item.ID, t.userID, 1, item.Meta, item.CreatedAt, data.PrivateKey, data.PublicKey, data.Comment, data.Passphrase,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // createBlob adds a new blob item into the blobs table. Item version is set to 1.
 func (t *UserTransaction) createBlob(ctx context.Context, item models.Item, data models.BinaryData) error {
+	if err := t.retainBlob(ctx, data.Hash); err != nil {
+		return err
+	}
 	_, err := t.tx.ExecContext(
 		ctx,
-		`INSERT INTO blobs (id, user_id, version, meta, created_at, blob)
-		VALUES ($1, $2, $3, $4, $5, $6);`,
-		item.ID, t.userID, 1, item.Meta, item.CreatedAt, data.Binary,
+		`INSERT INTO blobs (id, user_id, version, meta, created_at, size, sha256)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);`,
+		item.ID, t.userID, 1, item.Meta, item.CreatedAt, data.Size, data.Hash,
 	)
 	if err != nil {
 		return err
@@ -119,55 +294,47 @@ func (t *UserTransaction) createPassword(ctx context.Context, item models.Item,
 	return nil
 }
 
-// updateText updates an existing text item in the texts table.
+// updateText updates an existing text item in the texts table if its version is not changed.
 func (t *UserTransaction) updateText(ctx context.Context, item models.Item, data models.TextData) error {
-	_, err := t.tx.ExecContext(
+	res, err := t.tx.ExecContext(
 		ctx,
-		`UPDATE texts SET version=$1, meta=$2, deleted_at=$3, text_string=$4 WHERE id=$5;`,
+		`UPDATE texts SET version=$1, meta=$2, deleted_at=$3, text_string=$4
+		WHERE id=$5 AND user_id=$6 AND version=$7;`,
 		item.Version+1, // This increments the item version!
 		item.Meta,
 		item.DeletedAt,
 		data.Text,
 		item.ID,
+		t.userID,
+		item.Version,
 	)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return storage.ErrNotFound
-		}
-		return err
-	}
-
-	return nil
+	return t.checkUpdated(ctx, item.ID, res, err)
 }
 
-// updatePassword updates an existing password item in the passwords table.
+// updatePassword updates an existing password item in the passwords table if its version is not changed.
 func (t *UserTransaction) updatePassword(ctx context.Context, item models.Item, data models.PasswordData) error {
-	_, err := t.tx.ExecContext(
+	res, err := t.tx.ExecContext(
 		ctx,
-		`UPDATE passwords SET version=$1, meta=$2, deleted_at=$3, password=$4 WHERE id=$5;`,
+		`UPDATE passwords SET version=$1, meta=$2, deleted_at=$3, password=$4
+		WHERE id=$5 AND user_id=$6 AND version=$7;`,
 		item.Version+1, // This increments the item version!
 		item.Meta,
 		item.DeletedAt,
 		data.Password,
 		item.ID,
+		t.userID,
+		item.Version,
 	)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return storage.ErrNotFound
-		}
-		return err
-	}
-
-	return nil
+	return t.checkUpdated(ctx, item.ID, res, err)
 }
 
-// updateCard updates an existing card item in the cards table.
+// updateCard updates an existing card item in the cards table if its version is not changed.
 func (t *UserTransaction) updateCard(ctx context.Context, item models.Item, data models.CardData) error {
-	_, err := t.tx.ExecContext(
+	res, err := t.tx.ExecContext(
 		ctx,
 		`UPDATE cards
 		SET version=$1, meta=$2, deleted_at=$3, card_number=$4, cardholder_name=$5, expiration_date=$6, cvc=$7
-		WHERE id=$8;`,
+		WHERE id=$8 AND user_id=$9 AND version=$10;`,
 		item.Version+1, // This increments the item version!
 		item.Meta,
 		item.DeletedAt,
@@ -176,34 +343,146 @@ func (t *UserTransaction) updateCard(ctx context.Context, item models.Item, data
 		data.Date,
 		data.CVC,
 		item.ID,
+		t.userID,
+		item.Version,
 	)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return storage.ErrNotFound
-		}
-		return err
-	}
+	return t.checkUpdated(ctx, item.ID, res, err)
+}
 
-	return nil
+// updateOTP updates an existing one-time password key in the otps table if its version is not changed.
+func (t *UserTransaction) updateOTP(ctx context.Context, item models.Item, data models.OTPData) error {
+	res, err := t.tx.ExecContext(
+		ctx,
+		`UPDATE otps
+		SET version=$1, meta=$2, deleted_at=$3, secret=$4, algorithm=$5, digits=$6, period=$7, issuer=$8, account=$9
+		WHERE id=$10 AND user_id=$11 AND version=$12;`,
+		item.Version+1, // This increments the item version!
+		item.Meta,
+		item.DeletedAt,
+		data.Secret,
+		data.Algorithm,
+		data.Digits,
+		data.Period,
+		data.Issuer,
+		data.Account,
+		item.ID,
+		t.userID,
+		item.Version,
+	)
+	return t.checkUpdated(ctx, item.ID, res, err)
 }
 
-// updateBlob updates an existing blob item in the blobs table.
-func (t *UserTransaction) updateBlob(ctx context.Context, item models.Item, data models.BinaryData) error {
-	_, err := t.tx.ExecContext(
+// updateSSHKey updates an existing SSH key in the ssh_keys table if its version is not changed.
+func (t *UserTransaction) updateSSHKey(ctx context.Context, item models.Item, data models.SSHKeyData) error {
+	res, err := t.tx.ExecContext(
 		ctx,
-		`UPDATE blobs SET version=$1, meta=$2, deleted_at=$3, blob=$4 WHERE id=$5;`,
+		`UPDATE ssh_keys
+		SET version=$1, meta=$2, deleted_at=$3, private_key=$4, public_key=$5, comment=$6, passphrase=$7
+		WHERE id=$8 AND user_id=$9 AND version=$10;`,
 		item.Version+1, // This increments the item version!
 		item.Meta,
 		item.DeletedAt,
-		data.Binary,
+		data.PrivateKey,
+		data.PublicKey,
+		data.Comment,
+		data.Passphrase,
 		item.ID,
+		t.userID,
+		item.Version,
 	)
+	return t.checkUpdated(ctx, item.ID, res, err)
+}
+
+// updateBlob updates an existing blob item in the blobs table if its version is not changed.
+// If the item refers to another content now, the references of both contents are counted.
+func (t *UserTransaction) updateBlob(ctx context.Context, item models.Item, data models.BinaryData) error {
+	var oldHash string
+	err := t.tx.QueryRowContext(ctx, `SELECT sha256 FROM blobs WHERE id=$1 AND user_id=$2 AND version=$3;`,
+		item.ID, t.userID, item.Version).Scan(&oldHash)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return storage.ErrNotFound
+			return t.notUpdatedError(ctx, item.ID)
+		}
+		return err
+	}
+	if oldHash != data.Hash {
+		if err := t.retainBlob(ctx, data.Hash); err != nil {
+			return err
 		}
+	}
+	res, err := t.tx.ExecContext(
+		ctx,
+		`UPDATE blobs SET version=$1, meta=$2, deleted_at=$3, size=$4, sha256=$5
+		WHERE id=$6 AND user_id=$7 AND version=$8;`,
+		item.Version+1, // This increments the item version!
+		item.Meta,
+		item.DeletedAt,
+		data.Size,
+		data.Hash,
+		item.ID,
+		t.userID,
+		item.Version,
+	)
+	if err := t.checkUpdated(ctx, item.ID, res, err); err != nil {
 		return err
 	}
+	if oldHash != data.Hash {
+		return t.releaseBlob(ctx, oldHash)
+	}
+	return nil
+}
 
+// retainBlob counts the new reference to the user's blob content with the hash provided.
+// If the content isn't completely uploaded, storage.ErrBlobNotUploaded returns.
+func (t *UserTransaction) retainBlob(ctx context.Context, hash string) error {
+	res, err := t.tx.ExecContext(ctx,
+		`UPDATE blob_contents SET ref_count=ref_count+1, touched_at=now() WHERE user_id=$1 AND sha256=$2 AND complete;`,
+		t.userID, hash)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrBlobNotUploaded
+	}
 	return nil
 }
+
+// releaseBlob discounts the reference to the user's blob content with the hash provided.
+func (t *UserTransaction) releaseBlob(ctx context.Context, hash string) error {
+	_, err := t.tx.ExecContext(ctx,
+		`UPDATE blob_contents SET ref_count=ref_count-1, touched_at=now() WHERE user_id=$1 AND sha256=$2;`,
+		t.userID, hash)
+	return err
+}
+
+// checkUpdated checks that the item is updated. If no row is updated, notUpdatedError returns.
+func (t *UserTransaction) checkUpdated(ctx context.Context, itemID uuid.UUID, res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	return t.notUpdatedError(ctx, itemID)
+}
+
+// notUpdatedError fetches the current item of the user that is failed to update:
+// if it exists, storage.ErrVersionConflict with the item returns, otherwise storage.ErrNotFound.
+func (t *UserTransaction) notUpdatedError(ctx context.Context, itemID uuid.UUID) error {
+	items, err := getItems(ctx, t.tx, `id=$1 AND user_id=$2`, itemID, t.userID)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return storage.ErrNotFound
+	}
+	return storage.ErrVersionConflict{Item: stripTombstone(items[0])}
+}
@@ -33,39 +33,155 @@ func (s Storage) GetUserData(ctx context.Context, userID uuid.UUID) (*models.Use
 	}
 
 	// get user data of all types
-	texts, err := s.getTexts(ctx, tx, userID)
+	items, err := getItems(ctx, tx, `user_id=$1`, userID)
 	if err != nil {
 		return nil, err
 	}
-	passwords, err := s.getPasswords(ctx, tx, userID)
+	userData.Items = stripTombstones(items)
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &userData, nil
+}
+
+// GetChangesSince implements storage.Storage interface.
+func (s Storage) GetChangesSince(ctx context.Context, userID uuid.UUID, dataVersion uint64) (*models.UserData, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+		ReadOnly:  true,
+	})
 	if err != nil {
 		return nil, err
 	}
-	cards, err := s.getCards(ctx, tx, userID)
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	userData := models.UserData{}
+	var logStart uint64
+	if err := tx.QueryRowContext(ctx,
+		`SELECT data_version, change_log_start FROM users WHERE id=$1 AND deleted_at IS NULL;`,
+		userID).Scan(&userData.Version, &logStart); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrNotFound
+		}
+		return nil, err
+	}
+	if dataVersion < logStart || dataVersion > userData.Version {
+		return nil, storage.ErrChangeLogCompacted
+	}
+
+	items, err := getItems(ctx, tx,
+		`user_id=$1 AND id IN (SELECT item_id FROM item_changes WHERE user_id=$1 AND data_version > $2)`,
+		userID, dataVersion)
 	if err != nil {
 		return nil, err
 	}
-	blobs, err := s.getBlobs(ctx, tx, userID)
+	// the purged items are sent as tombstones, so the clients that haven't synced the deletion remove them
+	purged, err := getPurgedItems(ctx, tx,
+		`user_id=$1 AND data_version > $2`,
+		userID, dataVersion)
 	if err != nil {
 		return nil, err
 	}
-	userData.Items = append(userData.Items, texts...)
-	userData.Items = append(userData.Items, passwords...)
-	userData.Items = append(userData.Items, blobs...)
-	userData.Items = append(userData.Items, cards...)
+	userData.Items = append(stripTombstones(items), purged...)
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 	return &userData, nil
 }
 
-// getTexts retrieves from the database all texts of the user provided.
-func (s Storage) getTexts(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]models.Item, error) {
+// CompactChangeLog implements storage.Storage interface.
+func (s Storage) CompactChangeLog(ctx context.Context, keepVersions uint64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET change_log_start = data_version - $1 WHERE data_version - $1 > change_log_start;`,
+		keepVersions); err != nil {
+		return 0, err
+	}
+	// the tombstones of the purged items are needed only by the clients synced after the start of the log
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM purged_items p USING users u WHERE p.user_id = u.id AND p.data_version <= u.change_log_start;`); err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx,
+		`DELETE FROM item_changes c USING users u WHERE c.user_id = u.id AND c.data_version <= u.change_log_start;`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
+
+// stripTombstones erases the payload and the metadata of the deleted items. The data of the deleted
+// items is kept in the trash and is sent only by GetTrash.
+func stripTombstones(items []models.Item) []models.Item {
+	for i := range items {
+		items[i] = stripTombstone(items[i])
+	}
+	return items
+}
+
+func stripTombstone(item models.Item) models.Item {
+	if item.IsTombstone() {
+		item.Payload = nil
+		item.Meta = ""
+	}
+	return item
+}
+
+// getItems retrieves from the database the items of all types that match the condition.
+func getItems(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) ([]models.Item, error) {
+	texts, err := getTexts(ctx, tx, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	passwords, err := getPasswords(ctx, tx, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	cards, err := getCards(ctx, tx, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := getBlobs(ctx, tx, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	otps, err := getOTPs(ctx, tx, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	sshKeys, err := getSSHKeys(ctx, tx, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]models.Item, 0, len(texts)+len(passwords)+len(blobs)+len(cards)+len(otps)+len(sshKeys))
+	items = append(items, texts...)
+	items = append(items, passwords...)
+	items = append(items, blobs...)
+	items = append(items, cards...)
+	items = append(items, otps...)
+	items = append(items, sshKeys...)
+	return items, nil
+}
+
+// getTexts retrieves from the database the texts that match the condition.
+func getTexts(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) ([]models.Item, error) {
 	items := make([]models.Item, 0)
 	rows, err := tx.QueryContext(
 		ctx,
-		`SELECT id, text_string, meta, created_at, deleted_at, version FROM texts WHERE user_id=$1;`,
-		userID,
+		`SELECT id, text_string, meta, created_at, deleted_at, version FROM texts WHERE `+where+`;`,
+		args...,
 	)
 	if err != nil {
 		return nil, err
@@ -83,13 +199,13 @@ func (s Storage) getTexts(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]
 	return items, nil
 }
 
-// getPasswords retrieves from the database all passwords of the user provided.
-func (s Storage) getPasswords(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]models.Item, error) {
+// getPasswords retrieves from the database the passwords that match the condition.
+func getPasswords(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) ([]models.Item, error) {
 	items := make([]models.Item, 0)
 	rows, err := tx.QueryContext(
 		ctx,
-		`SELECT id, password, meta, created_at, deleted_at, version FROM passwords WHERE user_id=$1;`,
-		userID,
+		`SELECT id, password, meta, created_at, deleted_at, version FROM passwords WHERE `+where+`;`,
+		args...,
 	)
 	if err != nil {
 		return nil, err
@@ -107,14 +223,14 @@ func (s Storage) getPasswords(ctx context.Context, tx *sql.Tx, userID uuid.UUID)
 	return items, nil
 }
 
-// getCards retrieves from the database all credit cards of the user provided.
-func (s Storage) getCards(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]models.Item, error) {
+// getCards retrieves from the database the credit cards that match the condition.
+func getCards(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) ([]models.Item, error) {
 	items := make([]models.Item, 0)
 	rows, err := tx.QueryContext(
 		ctx,
 		`SELECT id, card_number, cardholder_name, expiration_date, cvc, meta, created_at, deleted_at, version
-		FROM cards WHERE user_id=$1;`,
-		userID,
+		FROM cards WHERE `+where+`;`,
+		args...,
 	)
 	if err != nil {
 		return nil, err
@@ -133,13 +249,13 @@ func (s Storage) getCards(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]
 	return items, nil
 }
 
-// getBlobs retrieves from the database all blobs of the user provided.
-func (s Storage) getBlobs(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]models.Item, error) {
+// getBlobs retrieves from the database the blobs that match the condition.
+func getBlobs(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) ([]models.Item, error) {
 	items := make([]models.Item, 0)
 	rows, err := tx.QueryContext(
 		ctx,
-		`SELECT id, blob, meta, created_at, deleted_at, version FROM blobs WHERE user_id=$1;`,
-		userID,
+		`SELECT id, size, sha256, meta, created_at, deleted_at, version FROM blobs WHERE `+where+`;`,
+		args...,
 	)
 	if err != nil {
 		return nil, err
@@ -148,7 +264,59 @@ func (s Storage) getBlobs(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]
 	for rows.Next() {
 		data := models.BinaryData{}
 		item := models.Item{}
-		if err := rows.Scan(&item.ID, &data.Binary, &item.Meta, &item.CreatedAt, &item.DeletedAt, &item.Version); err != nil {
+		if err := rows.Scan(&item.ID, &data.Size, &data.Hash, &item.Meta, &item.CreatedAt, &item.DeletedAt, &item.Version); err != nil {
+			return nil, err
+		}
+		item.Payload = data
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// getOTPs retrieves from the database the one-time password keys that match the condition.
+func getOTPs(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) ([]models.Item, error) {
+	items := make([]models.Item, 0)
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT id, secret, algorithm, digits, period, issuer, account, meta, created_at, deleted_at, version
+		FROM otps WHERE `+where+`;`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		data := models.OTPData{}
+		item := models.Item{}
+		if err := rows.Scan(&item.ID, &data.Secret, &data.Algorithm, &data.Digits, &data.Period, &data.Issuer, &data.Account,
+			&item.Meta, &item.CreatedAt, &item.DeletedAt, &item.Version); err != nil {
+			return nil, err
+		}
+		item.Payload = data
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// getSSHKeys retrieves from the database the SSH keys that match the condition.
+func getSSHKeys(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) ([]models.Item, error) {
+	items := make([]models.Item, 0)
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT id, private_key, public_key, comment, passphrase, meta, created_at, deleted_at, version
+		FROM ssh_keys WHERE `+where+`;`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		data := models.SSHKeyData{}
+		item := models.Item{}
+		if err := rows.Scan(&item.ID, &data.PrivateKey, &data.PublicKey, &data.Comment, &data.Passphrase,
+			&item.Meta, &item.CreatedAt, &item.DeletedAt, &item.Version); err != nil {
 			return nil, err
 		}
 		item.Payload = data
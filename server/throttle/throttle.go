@@ -0,0 +1,261 @@
+package throttle
+
+// Package throttle protects authentication methods from brute-force attacks.
+// It counts failed attempts per key (email, remote IP address etc.) and rejects the attempts
+// for the key with exponentially growing delay. After too many failures the key is locked out.
+// The requests limited regardless of their result (e.g. the ones sending emails) are counted by Allow
+// in fixed time windows instead. The counters are kept in the storage, so they survive the server restart.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+const (
+	defaultMaxFailures     = 10
+	defaultBaseDelay       = time.Second
+	defaultMaxDelay        = 5 * time.Minute
+	defaultLockoutDuration = time.Hour
+	defaultFailureWindow   = 24 * time.Hour
+
+	// reservationTimeout is the time after which the attempt reserved and never completed
+	// (e.g. because of the server crash) is no longer counted.
+	reservationTimeout = time.Minute
+)
+
+// ErrTooManyAttempts is returned when the attempts for the key are temporarily blocked.
+type ErrTooManyAttempts struct {
+	RetryAfter time.Duration
+}
+
+func (err ErrTooManyAttempts) Error() string {
+	return fmt.Sprintf("too many failed attempts, try again in %v", err.RetryAfter.Round(time.Second))
+}
+
+type (
+	// Limiter counts failed attempts and blocks the keys with too many failures.
+	Limiter struct {
+		storage storage.Storage
+
+		// maxFailures is the number of failures after which the key is locked out for lockoutDuration.
+		maxFailures     int
+		lockoutDuration time.Duration
+		// Before the lockout, n-th failure blocks the key for baseDelay * 2^(n-1), but not more than maxDelay.
+		baseDelay time.Duration
+		maxDelay  time.Duration
+		// failureWindow is the time after the last failure when the counter is restarted.
+		failureWindow time.Duration
+	}
+
+	// Option is a functional option for the limiter.
+	Option func(l *Limiter)
+)
+
+// NewLimiter creates a new limiter with the default policy modified by the options provided.
+func NewLimiter(s storage.Storage, opts ...Option) Limiter {
+	l := Limiter{
+		storage:         s,
+		maxFailures:     defaultMaxFailures,
+		lockoutDuration: defaultLockoutDuration,
+		baseDelay:       defaultBaseDelay,
+		maxDelay:        defaultMaxDelay,
+		failureWindow:   defaultFailureWindow,
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+	return l
+}
+
+// WithLockout sets the number of failures after which the key is locked out and the lockout duration.
+func WithLockout(maxFailures int, lockoutDuration time.Duration) Option {
+	return func(l *Limiter) {
+		if maxFailures > 0 {
+			l.maxFailures = maxFailures
+		}
+		if lockoutDuration > 0 {
+			l.lockoutDuration = lockoutDuration
+		}
+	}
+}
+
+// WithBackoff sets the parameters of the exponential backoff.
+func WithBackoff(baseDelay, maxDelay time.Duration) Option {
+	return func(l *Limiter) {
+		if baseDelay > 0 {
+			l.baseDelay = baseDelay
+		}
+		if maxDelay > 0 {
+			l.maxDelay = maxDelay
+		}
+	}
+}
+
+// WithFailureWindow sets the time after the last failure when the counter is restarted.
+func WithFailureWindow(d time.Duration) Option {
+	return func(l *Limiter) {
+		if d > 0 {
+			l.failureWindow = d
+		}
+	}
+}
+
+// Rate is the maximal number of the requests for a key in the time window.
+type Rate struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Allow counts the request for each of the keys provided and returns ErrTooManyAttempts if any of them
+// exceeds the rate. Unlike the failures, all the requests are counted, and the counter is restarted
+// when the window is over, so nobody can lock the key out for longer than the window.
+func (l Limiter) Allow(ctx context.Context, rate Rate, keys ...string) error {
+	now := time.Now()
+	for _, key := range keys {
+		c, err := l.storage.CountRequest(ctx, key, now.Add(-rate.Window))
+		if err != nil {
+			return fmt.Errorf("throttle: allow: %w", err)
+		}
+		if c.Count > rate.Limit {
+			return ErrTooManyAttempts{RetryAfter: time.Until(c.WindowStart.Add(rate.Window))}
+		}
+	}
+	return nil
+}
+
+// Reserve atomically reserves the attempt for each of the keys provided before the credentials
+// are verified, so the concurrent attempts can't bypass the backoff and the lockout. ErrTooManyAttempts
+// returns if any of the keys is blocked. Each reserved attempt must be completed by Fail, Release or Reset.
+func (l Limiter) Reserve(ctx context.Context, keys ...string) error {
+	now := time.Now()
+	for i, key := range keys {
+		ok, err := l.storage.ReserveLoginAttempt(ctx, key, l.maxFailures, now.Add(-l.failureWindow), now.Add(-reservationTimeout))
+		if err == nil && !ok {
+			err = l.tooManyAttempts(ctx, key)
+		}
+		if err != nil {
+			if releaseErr := l.Release(ctx, keys[:i]...); releaseErr != nil {
+				return fmt.Errorf("throttle: reserve: %w", releaseErr)
+			}
+			var tooMany ErrTooManyAttempts
+			if errors.As(err, &tooMany) {
+				return err
+			}
+			return fmt.Errorf("throttle: reserve: %w", err)
+		}
+	}
+	return nil
+}
+
+// Release releases the attempts reserved for the keys provided without registering the failure.
+func (l Limiter) Release(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := l.storage.ReleaseLoginAttempt(ctx, key); err != nil {
+			return fmt.Errorf("throttle: release: %w", err)
+		}
+	}
+	return nil
+}
+
+// Fail registers the failed attempt reserved for each of the keys provided and blocks the keys
+// according to the number of their failures.
+func (l Limiter) Fail(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		attempts, err := l.storage.RegisterLoginFailure(ctx, key, time.Now().Add(-l.failureWindow))
+		if err != nil {
+			return fmt.Errorf("throttle: fail: %w", err)
+		}
+		if err := l.storage.BlockLoginAttempts(ctx, key, time.Now().Add(l.delay(attempts.Failures))); err != nil {
+			return fmt.Errorf("throttle: fail: %w", err)
+		}
+	}
+	return nil
+}
+
+// Reset removes the failure counters and the reservations of the keys provided.
+// It's called after successful authentication.
+func (l Limiter) Reset(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := l.storage.ResetLoginAttempts(ctx, key); err != nil {
+			return fmt.Errorf("throttle: reset: %w", err)
+		}
+	}
+	return nil
+}
+
+// tooManyAttempts returns ErrTooManyAttempts with the time after which the attempt for the key may be retried.
+// If the key isn't blocked, the attempt is rejected because of the attempts in progress, so it may be
+// retried after the minimal delay.
+func (l Limiter) tooManyAttempts(ctx context.Context, key string) error {
+	attempts, err := l.storage.GetLoginAttempts(ctx, key)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	retryAfter := l.baseDelay
+	if attempts.BlockedUntil != nil {
+		if d := time.Until(*attempts.BlockedUntil); d > retryAfter {
+			retryAfter = d
+		}
+	}
+	return ErrTooManyAttempts{RetryAfter: retryAfter}
+}
+
+// delay returns the time for which the key with given number of failures is blocked.
+func (l Limiter) delay(failures int) time.Duration {
+	if failures >= l.maxFailures {
+		return l.lockoutDuration
+	}
+	d := l.baseDelay
+	for i := 1; i < failures; i++ {
+		d *= 2
+		if d >= l.maxDelay {
+			return l.maxDelay
+		}
+	}
+	return d
+}
+
+// EmailKey returns the counter key for the email.
+func EmailKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPKey returns the counter key for the remote address of the client.
+// The port is stripped, so that all connections from the same host share the counter.
+func IPKey(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return "ip:" + host
+}
+
+// SignUpKey returns the counter key for the registrations from the remote address.
+func SignUpKey(remoteAddr string) string {
+	return "signup:" + IPKey(remoteAddr)
+}
+
+// MFAKey returns the counter key for the second factor checks of the user.
+// The codes are counted per user, since the challenge can be obtained again after a successful password check.
+func MFAKey(userID uuid.UUID) string {
+	return "mfa:user:" + userID.String()
+}
+
+// PasswordKey returns the counter key for the password confirmations of the signed-in user
+// (password change, account deletion etc.).
+func PasswordKey(userID uuid.UUID) string {
+	return "password:user:" + userID.String()
+}
+
+// PasswordResetKey returns the rate counter key of the password reset requests for the key provided
+// (see EmailKey and IPKey). The requests are counted to prevent flooding the user's mailbox.
+func PasswordResetKey(key string) string {
+	return "reset:" + key
+}
@@ -9,46 +9,83 @@ import (
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/storage"
 )
 
 const jwtIssuer = "GophKeeper"
 
-// newAccessToken creates a new token for the user provided and signs it with the secret key.
-func (s Service) newAccessToken(userID uuid.UUID) (models.AccessToken, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
-		Audience:  "",
-		ExpiresAt: time.Now().Add(s.accessTokenDuration).Unix(),
-		Id:        userID.String(),
-		IssuedAt:  time.Now().Unix(),
-		Issuer:    jwtIssuer,
-		NotBefore: time.Now().Unix(),
+// accessTokenClaims are the claims of the access token. The ID of the token is the user ID.
+type accessTokenClaims struct {
+	jwt.StandardClaims
+	// SessionID is the ID of the session the token is issued for.
+	SessionID string `json:"sid,omitempty"`
+}
+
+// newAccessToken creates a new token for the user session provided and signs it with the current signing key.
+func (s Service) newAccessToken(userID, sessionID uuid.UUID) (models.AccessToken, error) {
+	ss, err := s.keys.Sign(accessTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Audience:  "",
+			ExpiresAt: time.Now().Add(s.accessTokenDuration).Unix(),
+			Id:        userID.String(),
+			IssuedAt:  time.Now().Unix(),
+			Issuer:    jwtIssuer,
+			NotBefore: time.Now().Unix(),
+		},
+		SessionID: sessionID.String(),
 	})
-	ss, err := token.SignedString([]byte(s.secret))
 	if err != nil {
 		return "", fmt.Errorf("users: NewAccessToken: %w", err)
 	}
 	return models.AccessToken(ss), nil
 }
 
-// Authenticate checks if the given access token is valid and, if so, returns the user ID.
-func (s Service) Authenticate(ctx context.Context, accessToken models.AccessToken) (uuid.UUID, error) {
-	t, err := jwt.ParseWithClaims(string(accessToken), &jwt.StandardClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(s.secret), nil
-	})
+// Authenticate checks if the given access token is valid and its session is not logged out and,
+// if so, returns the user ID and the session ID. The session is checked on every call,
+// so the logout or the revocation of the session takes effect at once.
+// The tokens issued without the session can't be revoked, so they are rejected.
+func (s Service) Authenticate(ctx context.Context, accessToken models.AccessToken) (uuid.UUID, uuid.UUID, error) {
+	t, err := jwt.ParseWithClaims(string(accessToken), &accessTokenClaims{}, s.keys.Keyfunc)
 	if err != nil {
-		var ve jwt.ValidationError
+		var ve *jwt.ValidationError
 		if errors.As(err, &ve) && ve.Errors&jwt.ValidationErrorExpired != 0 {
-			return uuid.Nil, fmt.Errorf("users: authenticate: %w", ErrAccessTokenExpired)
+			return uuid.Nil, uuid.Nil, fmt.Errorf("users: authenticate: %w", ErrAccessTokenExpired)
 		}
-		return uuid.Nil, fmt.Errorf("users: authenticate: %w", err)
+		return uuid.Nil, uuid.Nil, fmt.Errorf("users: authenticate: %w", err)
 	}
-	claims, ok := t.Claims.(*jwt.StandardClaims)
-	if !ok {
-		return uuid.Nil, fmt.Errorf("users: authenticate: %w", ErrIncorrectAccessToken)
+	claims, ok := t.Claims.(*accessTokenClaims)
+	if !ok || claims.Audience != "" { // tokens with audience (e.g. MFA challenge) are not access tokens
+		return uuid.Nil, uuid.Nil, fmt.Errorf("users: authenticate: %w", ErrIncorrectAccessToken)
 	}
 	id, err := uuid.Parse(claims.Id)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("users: authenticate: %w: userID=%s", ErrIncorrectUserID, claims.Id)
+		return uuid.Nil, uuid.Nil, fmt.Errorf("users: authenticate: %w: userID=%s", ErrIncorrectUserID, claims.Id)
+	}
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("users: authenticate: %w", ErrIncorrectAccessToken)
+	}
+	session, err := s.storage.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return uuid.Nil, uuid.Nil, fmt.Errorf("users: authenticate: %w", ErrSessionLoggedOut)
+		}
+		return uuid.Nil, uuid.Nil, fmt.Errorf("users: authenticate: %w", err)
+	}
+	if session.UserID != id {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("users: authenticate: %w", ErrIncorrectAccessToken)
+	}
+	return id, sessionID, nil
+}
+
+// IsSessionActive reports whether the session is not logged out.
+func (s Service) IsSessionActive(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	session, err := s.storage.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("users: isSessionActive: %w", err)
 	}
-	return id, nil
+	return session.LogoutAt == nil, nil
 }
@@ -0,0 +1,117 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/pkg/passhash"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+// DeleteUser checks the proof of the password of the user and marks the user as deleted.
+// All the user sessions are ended. The user's data is kept until the grace period expires.
+func (s Service) DeleteUser(ctx context.Context, userID uuid.UUID, proof models.SRPProof) error {
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("users: deleteUser: %w", err)
+	}
+	if _, err := s.verifyPasswordProof(ctx, user, proof); err != nil {
+		return fmt.Errorf("users: deleteUser: %w", err)
+	}
+	if err := s.storage.DeleteUser(ctx, userID); err != nil {
+		return fmt.Errorf("users: deleteUser: %w", err)
+	}
+
+	return nil
+}
+
+// UndeleteUser restores the user deleted less than the grace period ago and creates a new session.
+// The password is confirmed by the proof for the handshake started by StartSRPLogin with undelete set.
+// If the user has two-factor authentication enabled, the account is not restored yet:
+// ErrMFARequired with the challenge returns, and the account is restored by LoginMFA.
+func (s Service) UndeleteUser(ctx context.Context, email string, proof models.SRPProof, client models.ClientInfo) (models.AccessToken, models.RefreshToken, error) {
+	user, err := s.storage.GetDeletedUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) { // the handshake is the fake one
+			return "", "", fmt.Errorf("users: undeleteUser: %w", passhash.ErrMismatchedHashAndPassword)
+		}
+		return "", "", fmt.Errorf("users: undeleteUser: %w", err)
+	}
+	if _, err := s.verifyPasswordProof(ctx, user, proof); err != nil {
+		return "", "", fmt.Errorf("users: undeleteUser: %w", err)
+	}
+	if user.DeletedAt == nil || time.Since(*user.DeletedAt) > s.deletionGracePeriod {
+		return "", "", fmt.Errorf("users: undeleteUser: %w", ErrGracePeriodExpired)
+	}
+	if user.TOTPEnabled {
+		challenge, err := s.newMFAChallenge(ctx, user.ID, true)
+		if err != nil {
+			return "", "", fmt.Errorf("users: undeleteUser: %w", err)
+		}
+		return "", "", ErrMFARequired{Challenge: challenge}
+	}
+	if err := s.storage.UndeleteUser(ctx, user.ID); err != nil {
+		return "", "", fmt.Errorf("users: undeleteUser: %w", err)
+	}
+
+	return s.CreateSession(ctx, user.ID, client)
+}
+
+// undeleteMFA restores the deleted user with two-factor authentication enabled
+// after the second factor is checked.
+func (s Service) undeleteMFA(ctx context.Context, userID uuid.UUID, code string) error {
+	user, err := s.storage.GetDeletedUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt == nil || time.Since(*user.DeletedAt) > s.deletionGracePeriod {
+		return ErrGracePeriodExpired
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnabled
+	}
+	if err := s.checkSecondFactor(ctx, user, code); err != nil {
+		return err
+	}
+	return s.storage.UndeleteUser(ctx, user.ID)
+}
+
+// PurgeDeletedUsers permanently erases all the users whose grace period is expired.
+func (s Service) PurgeDeletedUsers(ctx context.Context) (int64, error) {
+	n, err := s.storage.PurgeDeletedUsers(ctx, time.Now().Add(-s.deletionGracePeriod))
+	if err != nil {
+		return 0, fmt.Errorf("users: purgeDeletedUsers: %w", err)
+	}
+	return n, nil
+}
+
+// RunPurger periodically erases the users deleted more than the grace period ago.
+// It blocks until the context is canceled.
+func (s Service) RunPurger(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultPurgeInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("users purger is stopped")
+			return
+		case <-ticker.C:
+			n, err := s.PurgeDeletedUsers(ctx)
+			if err != nil {
+				log.Printf("users purger: %s", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("users purger: %d deleted user(s) permanently erased", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package users
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+const (
+	emailVerificationTokenDuration = 48 * time.Hour
+	passwordResetTokenDuration     = time.Hour
+)
+
+var (
+	ErrInvalidEmail        = errors.New("invalid email address")
+	ErrEmailNotVerified    = errors.New("email is not verified")
+	ErrIncorrectEmailToken = errors.New("incorrect or expired token")
+)
+
+// validateEmail checks that the string is a bare email address.
+func validateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidEmail, err)
+	}
+	if addr.Address != email {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+// SendVerificationEmail sends the email verification token to the user.
+func (s Service) SendVerificationEmail(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("users: sendVerificationEmail: %w", err)
+	}
+	token, err := s.issueUserToken(ctx, user.ID, models.TokenPurposeVerifyEmail, emailVerificationTokenDuration)
+	if err != nil {
+		return fmt.Errorf("users: sendVerificationEmail: %w", err)
+	}
+	body := fmt.Sprintf("Welcome to GophKeeper!\n\n"+
+		"Enter the code below in your GophKeeper client to verify your email address:\n\n%s\n\n"+
+		"The code expires in %v.\n", token, emailVerificationTokenDuration)
+	if err := s.mailer.Send(ctx, user.Email, "GophKeeper: verify your email", body); err != nil {
+		return fmt.Errorf("users: sendVerificationEmail: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the email of the user the token is issued for as verified.
+func (s Service) VerifyEmail(ctx context.Context, token string) error {
+	userID, err := s.useUserToken(ctx, models.TokenPurposeVerifyEmail, token)
+	if err != nil {
+		return fmt.Errorf("users: verifyEmail: %w", err)
+	}
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("users: verifyEmail: %w", err)
+	}
+	user.EmailVerified = true
+	if err := s.storage.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("users: verifyEmail: %w", err)
+	}
+
+	return nil
+}
+
+// RequestPasswordReset sends the password reset token to the user with the email provided.
+// If there is no such user, nothing is sent and no error returns, so the existence
+// of the account is not disclosed.
+func (s Service) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.storage.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("users: requestPasswordReset: %w", err)
+	}
+	token, err := s.issueUserToken(ctx, user.ID, models.TokenPurposeResetPassword, passwordResetTokenDuration)
+	if err != nil {
+		return fmt.Errorf("users: requestPasswordReset: %w", err)
+	}
+	body := fmt.Sprintf("Somebody (hopefully you) requested the reset of your GophKeeper password.\n\n"+
+		"Enter the code below in your GophKeeper client to set a new password:\n\n%s\n\n"+
+		"The code expires in %v. If you didn't request the reset, just ignore this email.\n",
+		token, passwordResetTokenDuration)
+	if err := s.mailer.Send(ctx, user.Email, "GophKeeper: password reset", body); err != nil {
+		return fmt.Errorf("users: requestPasswordReset: %w", err)
+	}
+
+	return nil
+}
+
+// ResetPassword replaces the SRP verifier of the user the token is issued for and ends
+// all the user sessions. The email is considered verified, since the token was received by it.
+// The token is spent only together with the verifier, so the request with a wrong email
+// doesn't invalidate it.
+func (s Service) ResetPassword(ctx context.Context, token, email string, salt, verifier []byte) error {
+	if len(salt) == 0 || len(verifier) == 0 {
+		return fmt.Errorf("users: resetPassword: %w", ErrInvalidSRPVerifier)
+	}
+	t, err := s.getUserToken(ctx, models.TokenPurposeResetPassword, token)
+	if err != nil {
+		return fmt.Errorf("users: resetPassword: %w", err)
+	}
+	user, err := s.storage.GetUserByID(ctx, t.UserID)
+	if err != nil {
+		return fmt.Errorf("users: resetPassword: %w", err)
+	}
+	// the email is the SRP identity, the verifier computed for another one is useless
+	if user.Email != email {
+		return fmt.Errorf("users: resetPassword: %w", ErrIncorrectEmailToken)
+	}
+	if err := s.storage.ResetPassword(ctx, t.ID, salt, verifier); err != nil {
+		if errors.Is(err, storage.ErrNotFound) { // used concurrently
+			return fmt.Errorf("users: resetPassword: %w", ErrIncorrectEmailToken)
+		}
+		return fmt.Errorf("users: resetPassword: %w", err)
+	}
+	if err := s.storage.LogoutAll(ctx, user.ID); err != nil {
+		return fmt.Errorf("users: resetPassword: %w", err)
+	}
+
+	return nil
+}
+
+// CheckSyncAllowed returns ErrEmailNotVerified if the service requires verified email
+// for data synchronization and the user's email is not verified yet.
+func (s Service) CheckSyncAllowed(ctx context.Context, userID uuid.UUID) error {
+	if !s.requireVerifiedEmail {
+		return nil
+	}
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("users: checkSyncAllowed: %w", err)
+	}
+	if !user.EmailVerified {
+		return fmt.Errorf("users: checkSyncAllowed: %w", ErrEmailNotVerified)
+	}
+	return nil
+}
+
+// sendVerificationEmailAsync sends the verification email in background. The registration
+// must not fail because of the mailer, so the errors are only logged.
+func (s Service) sendVerificationEmailAsync(userID uuid.UUID) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := s.SendVerificationEmail(ctx, userID); err != nil {
+			log.Println(err)
+		}
+	}()
+}
+
+// issueUserToken creates a signed one-time token and stores its hash.
+func (s Service) issueUserToken(ctx context.Context, userID uuid.UUID, purpose models.TokenPurpose, d time.Duration) (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	expiresAt := time.Now().Add(d)
+	token, err := s.keys.Sign(jwt.StandardClaims{
+		// the audience prevents the token from being used as an access token or for another purpose
+		Audience:  tokenAudience(purpose),
+		ExpiresAt: expiresAt.Unix(),
+		Id:        id.String(),
+		IssuedAt:  time.Now().Unix(),
+		Issuer:    jwtIssuer,
+		Subject:   userID.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := s.storage.CreateUserToken(ctx, models.UserToken{
+		ID:        id,
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashUserToken(token),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// useUserToken verifies the token signature and marks the token as used. It returns the user ID.
+func (s Service) useUserToken(ctx context.Context, purpose models.TokenPurpose, token string) (uuid.UUID, error) {
+	tokenHash, err := s.verifyUserToken(purpose, token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	t, err := s.storage.UseUserToken(ctx, purpose, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return uuid.Nil, ErrIncorrectEmailToken
+		}
+		return uuid.Nil, err
+	}
+	return t.UserID, nil
+}
+
+// getUserToken verifies the token signature and returns the stored token without marking it as used.
+func (s Service) getUserToken(ctx context.Context, purpose models.TokenPurpose, token string) (models.UserToken, error) {
+	tokenHash, err := s.verifyUserToken(purpose, token)
+	if err != nil {
+		return models.UserToken{}, err
+	}
+	t, err := s.storage.GetUserToken(ctx, purpose, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return models.UserToken{}, ErrIncorrectEmailToken
+		}
+		return models.UserToken{}, err
+	}
+	return t, nil
+}
+
+// verifyUserToken verifies the token signature and purpose and returns the hash the token is stored by.
+func (s Service) verifyUserToken(purpose models.TokenPurpose, token string) (string, error) {
+	token = strings.TrimSpace(token)
+	claims := &jwt.StandardClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, s.keys.Keyfunc); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrIncorrectEmailToken, err)
+	}
+	if !claims.VerifyAudience(tokenAudience(purpose), true) {
+		return "", ErrIncorrectEmailToken
+	}
+	return hashUserToken(token), nil
+}
+
+func tokenAudience(purpose models.TokenPurpose) string {
+	return strings.ToLower(string(purpose))
+}
+
+// hashUserToken returns SHA-256 hash of the token. The tokens are long random values,
+// so a fast hash is enough.
+func hashUserToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,347 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/pkg/totp"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+const (
+	// mfaAudience is the audience of the MFA challenge token. It prevents the challenge
+	// from being used as an access token.
+	mfaAudience          = "mfa"
+	mfaChallengeDuration = 5 * time.Minute
+	// mfaChallengeAttempts is the number of codes that can be tried with one challenge.
+	mfaChallengeAttempts = 3
+
+	// totpSkew is the number of time steps before and after the current one that are accepted.
+	totpSkew = 1
+
+	recoveryCodesNumber = 10
+	recoveryCodeLength  = 10
+)
+
+var (
+	ErrMFAAlreadyEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled         = errors.New("two-factor authentication is not enabled")
+	ErrMFANotEnrolled        = errors.New("two-factor authentication enrollment is not started")
+	ErrInvalidOTPCode        = errors.New("invalid one-time code")
+	ErrIncorrectMFAChallenge = errors.New("incorrect MFA challenge")
+)
+
+// ErrMFARequired is returned by Login and UndeleteUser if the user has two-factor authentication enabled.
+// Challenge must be exchanged together with the one-time code for the token pair by LoginMFA.
+type ErrMFARequired struct {
+	Challenge models.MFAChallenge
+}
+
+func (err ErrMFARequired) Error() string {
+	return "two-factor authentication required"
+}
+
+// mfaChallengeClaims are the claims of the MFA challenge token. The ID of the token is the user ID.
+type mfaChallengeClaims struct {
+	jwt.StandardClaims
+	// ChallengeID is the ID of the challenge state kept by the storage.
+	ChallengeID string `json:"cid"`
+	// Undelete means that the challenge is issued by UndeleteUser, so the deleted account
+	// is restored when the second factor is confirmed.
+	Undelete bool `json:"undelete,omitempty"`
+}
+
+// recoveryCodeEncoding is used for generation of the human readable recovery codes.
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// recoveryCodeAlphabet is the alphabet of the recovery codes: lower case base32.
+const recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+// EnrollTOTP checks the proof of the password of the user and generates a new TOTP secret and a new set
+// of recovery codes for the user. Two-factor authentication is not enabled until the user confirms
+// the enrollment with a valid code by ConfirmTOTP. It returns otpauth:// URI and the recovery codes in clear.
+func (s Service) EnrollTOTP(ctx context.Context, userID uuid.UUID, proof models.SRPProof) (string, []string, error) {
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("users: enrollTOTP: %w", err)
+	}
+	if user.TOTPEnabled {
+		return "", nil, fmt.Errorf("users: enrollTOTP: %w", ErrMFAAlreadyEnabled)
+	}
+	if _, err := s.verifyPasswordProof(ctx, user, proof); err != nil {
+		return "", nil, fmt.Errorf("users: enrollTOTP: %w", err)
+	}
+	key, err := totp.NewKey(jwtIssuer, user.Email)
+	if err != nil {
+		return "", nil, fmt.Errorf("users: enrollTOTP: %w", err)
+	}
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return "", nil, fmt.Errorf("users: enrollTOTP: %w", err)
+	}
+	user.TOTPSecret = key.EncodedSecret()
+	if err := s.storage.UpdateUser(ctx, user); err != nil {
+		return "", nil, fmt.Errorf("users: enrollTOTP: %w", err)
+	}
+	if err := s.storage.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return "", nil, fmt.Errorf("users: enrollTOTP: %w", err)
+	}
+
+	return key.URI(), codes, nil
+}
+
+// ConfirmTOTP enables two-factor authentication if the code matches the enrolled secret.
+func (s Service) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("users: confirmTOTP: %w", err)
+	}
+	if user.TOTPEnabled {
+		return fmt.Errorf("users: confirmTOTP: %w", ErrMFAAlreadyEnabled)
+	}
+	if user.TOTPSecret == "" {
+		return fmt.Errorf("users: confirmTOTP: %w", ErrMFANotEnrolled)
+	}
+	if err := s.checkTOTP(ctx, user, code); err != nil {
+		return fmt.Errorf("users: confirmTOTP: %w", err)
+	}
+	user.TOTPEnabled = true
+	if err := s.storage.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("users: confirmTOTP: %w", err)
+	}
+
+	return nil
+}
+
+// DisableTOTP disables two-factor authentication. Both the password (by the proof for SRP handshake)
+// and the one-time code (or a recovery code) must be confirmed.
+func (s Service) DisableTOTP(ctx context.Context, userID uuid.UUID, proof models.SRPProof, code string) error {
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("users: disableTOTP: %w", err)
+	}
+	if !user.TOTPEnabled {
+		return fmt.Errorf("users: disableTOTP: %w", ErrMFANotEnabled)
+	}
+	if _, err := s.verifyPasswordProof(ctx, user, proof); err != nil {
+		return fmt.Errorf("users: disableTOTP: %w", err)
+	}
+	if err := s.checkSecondFactor(ctx, user, code); err != nil {
+		return fmt.Errorf("users: disableTOTP: %w", err)
+	}
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	if err := s.storage.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("users: disableTOTP: %w", err)
+	}
+	if err := s.storage.ReplaceRecoveryCodes(ctx, userID, nil); err != nil {
+		return fmt.Errorf("users: disableTOTP: %w", err)
+	}
+
+	return nil
+}
+
+// LoginMFA checks the MFA challenge issued by Login or UndeleteUser and the one-time code (or a recovery code).
+// If all is OK a new session is created. The account of the challenge issued by UndeleteUser is restored.
+// The challenge can be used for mfaChallengeAttempts codes and is removed after the successful one.
+func (s Service) LoginMFA(ctx context.Context, challenge models.MFAChallenge, code string, client models.ClientInfo) (models.AccessToken, models.RefreshToken, error) {
+	claims, err := s.parseMFAChallenge(challenge)
+	if err != nil {
+		return "", "", fmt.Errorf("users: loginMFA: %w", err)
+	}
+	challengeID, err := uuid.Parse(claims.ChallengeID)
+	if err != nil {
+		return "", "", fmt.Errorf("users: loginMFA: %w", ErrIncorrectMFAChallenge)
+	}
+	state, err := s.storage.CountMFAChallengeAttempt(ctx, challengeID, mfaChallengeAttempts)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) { // used, expired or out of attempts
+			return "", "", fmt.Errorf("users: loginMFA: %w", ErrIncorrectMFAChallenge)
+		}
+		return "", "", fmt.Errorf("users: loginMFA: %w", err)
+	}
+	if state.UserID.String() != claims.Id {
+		return "", "", fmt.Errorf("users: loginMFA: %w", ErrIncorrectMFAChallenge)
+	}
+	if claims.Undelete {
+		err = s.undeleteMFA(ctx, state.UserID, code)
+	} else {
+		err = s.confirmMFA(ctx, state.UserID, code)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("users: loginMFA: %w", err)
+	}
+	if err := s.storage.DeleteMFAChallenge(ctx, challengeID); err != nil {
+		return "", "", fmt.Errorf("users: loginMFA: %w", err)
+	}
+
+	return s.CreateSession(ctx, state.UserID, client)
+}
+
+// MFAChallengeUser verifies the challenge token and returns the ID of the user it's issued for.
+// It's used to throttle the attempts per user before the code is checked.
+func (s Service) MFAChallengeUser(challenge models.MFAChallenge) (uuid.UUID, error) {
+	claims, err := s.parseMFAChallenge(challenge)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("users: mfaChallengeUser: %w", err)
+	}
+	id, err := uuid.Parse(claims.Id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("users: mfaChallengeUser: %w", ErrIncorrectMFAChallenge)
+	}
+	return id, nil
+}
+
+// confirmMFA checks the second factor of the user who has logged in with the password.
+func (s Service) confirmMFA(ctx context.Context, userID uuid.UUID, code string) error {
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnabled
+	}
+	return s.checkSecondFactor(ctx, user, code)
+}
+
+// newMFAChallenge creates a signed short-lived challenge token for the user and stores its state,
+// which limits the number of attempts. The challenge for undelete restores the deleted account.
+func (s Service) newMFAChallenge(ctx context.Context, userID uuid.UUID, undelete bool) (models.MFAChallenge, error) {
+	state := models.MFAChallengeState{
+		ID:        uuid.New(),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(mfaChallengeDuration),
+	}
+	if err := s.storage.CreateMFAChallenge(ctx, state); err != nil {
+		return "", fmt.Errorf("users: newMFAChallenge: %w", err)
+	}
+	ss, err := s.keys.Sign(mfaChallengeClaims{
+		StandardClaims: jwt.StandardClaims{
+			Audience:  mfaAudience,
+			ExpiresAt: state.ExpiresAt.Unix(),
+			Id:        userID.String(),
+			IssuedAt:  time.Now().Unix(),
+			Issuer:    jwtIssuer,
+			NotBefore: time.Now().Unix(),
+		},
+		ChallengeID: state.ID.String(),
+		Undelete:    undelete,
+	})
+	if err != nil {
+		return "", fmt.Errorf("users: newMFAChallenge: %w", err)
+	}
+	return models.MFAChallenge(ss), nil
+}
+
+// parseMFAChallenge verifies the challenge token and returns its claims.
+func (s Service) parseMFAChallenge(challenge models.MFAChallenge) (*mfaChallengeClaims, error) {
+	claims := &mfaChallengeClaims{}
+	if _, err := jwt.ParseWithClaims(string(challenge), claims, s.keys.Keyfunc); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrIncorrectMFAChallenge, err)
+	}
+	if !claims.VerifyAudience(mfaAudience, true) {
+		return nil, ErrIncorrectMFAChallenge
+	}
+	return claims, nil
+}
+
+// checkSecondFactor checks the TOTP code of the user or, if the code has the format
+// of the recovery codes, checks it against the unused recovery codes. The matched recovery code
+// is marked as used. The recovery codes are hashed with bcrypt, so the TOTP codes are never
+// checked against them.
+func (s Service) checkSecondFactor(ctx context.Context, user models.User, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if !isRecoveryCode(normalized) {
+		return s.checkTOTP(ctx, user, code)
+	}
+	recoveryCodes, err := s.storage.GetUnusedRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, rc := range recoveryCodes {
+		if err := s.recoveryCodeHasher.Verify(normalized, rc.CodeHash); err != nil {
+			continue
+		}
+		if err := s.storage.UseRecoveryCode(ctx, rc.ID); err != nil {
+			if errors.Is(err, storage.ErrNotFound) { // used concurrently
+				return ErrInvalidOTPCode
+			}
+			return err
+		}
+		return nil
+	}
+
+	return ErrInvalidOTPCode
+}
+
+// checkTOTP checks the TOTP code against the secret of the user. Each code is accepted only once:
+// the time step of the accepted code is stored, and the codes of the same or earlier steps are rejected.
+func (s Service) checkTOTP(ctx context.Context, user models.User, code string) error {
+	secret, err := totp.DecodeSecret(user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+	step, ok := totp.Key{
+		Secret:    secret,
+		Algorithm: totp.AlgorithmSHA1,
+		Digits:    totp.DefaultDigits,
+		Period:    totp.DefaultPeriod,
+	}.Match(strings.TrimSpace(code), time.Now(), totpSkew)
+	if !ok {
+		return ErrInvalidOTPCode
+	}
+	if err := s.storage.UseTOTPStep(ctx, user.ID, step); err != nil {
+		if errors.Is(err, storage.ErrNotFound) { // the code is already used
+			return ErrInvalidOTPCode
+		}
+		return err
+	}
+	return nil
+}
+
+// newRecoveryCodes generates a set of recovery codes formatted as "xxxxx-xxxxx" and their hashes.
+func (s Service) newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesNumber)
+	hashes := make([]string, 0, recoveryCodesNumber)
+	for i := 0; i < recoveryCodesNumber; i++ {
+		b := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:recoveryCodeLength]
+		hash, err := s.recoveryCodeHasher.Hash(code)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, hash)
+	}
+	return codes, hashes, nil
+}
+
+// isRecoveryCode reports whether the normalized code has the format of the recovery codes.
+func isRecoveryCode(code string) bool {
+	if len(code) != recoveryCodeLength {
+		return false
+	}
+	for _, r := range code {
+		if !strings.ContainsRune(recoveryCodeAlphabet, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeRecoveryCode removes separators from the code entered by the user.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -10,9 +10,10 @@ import (
 )
 
 func (s Service) GetSessionID(rt models.RefreshToken) (uuid.UUID, error) {
-	t, _ := jwt.ParseWithClaims(string(rt), &jwt.StandardClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(s.secret), nil
-	})
+	t, _ := jwt.ParseWithClaims(string(rt), &jwt.StandardClaims{}, s.keys.Keyfunc)
+	if t == nil {
+		return uuid.Nil, ErrIncorrectRefreshToken
+	}
 	claims, ok := t.Claims.(*jwt.StandardClaims)
 	if !ok {
 		return uuid.Nil, ErrIncorrectRefreshToken
@@ -24,17 +25,32 @@ func (s Service) GetSessionID(rt models.RefreshToken) (uuid.UUID, error) {
 	return id, nil
 }
 
+// refreshTokenClaims are the claims of the refresh token.
+// StandardClaims.Id contains the session ID.
+type refreshTokenClaims struct {
+	jwt.StandardClaims
+	// TokenID makes each refresh token of the session unique, so that the superseded token
+	// never matches the current one even if both were issued within the same second.
+	TokenID string `json:"tid"`
+}
+
 // newRefreshToken creates a new signed refresh token.
 func (s Service) newRefreshToken(sessionID uuid.UUID) (models.RefreshToken, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
-		Audience:  "",
-		ExpiresAt: time.Now().Add(s.refreshTokenDuration).Unix(),
-		Id:        sessionID.String(),
-		IssuedAt:  time.Now().Unix(),
-		Issuer:    jwtIssuer,
-		NotBefore: time.Now().Unix(),
+	tokenID, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("users: NewRefreshToken: %w", err)
+	}
+	ss, err := s.keys.Sign(refreshTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Audience:  "",
+			ExpiresAt: time.Now().Add(s.refreshTokenDuration).Unix(),
+			Id:        sessionID.String(),
+			IssuedAt:  time.Now().Unix(),
+			Issuer:    jwtIssuer,
+			NotBefore: time.Now().Unix(),
+		},
+		TokenID: tokenID.String(),
 	})
-	ss, err := token.SignedString([]byte(s.secret))
 	if err != nil {
 		return "", fmt.Errorf("users: NewRefreshToken: %w", err)
 	}
@@ -0,0 +1,43 @@
+package users
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+// ListSessions returns all active sessions of the user.
+func (s Service) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	sessions, err := s.storage.GetActiveUserSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("users: listSessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// LogoutSession ends the session with ID provided. The session must belong to the user,
+// otherwise storage.ErrNotFound returns.
+func (s Service) LogoutSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	session, err := s.storage.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("users: logoutSession: %w", err)
+	}
+	if session.UserID != userID {
+		return fmt.Errorf("users: logoutSession: %w", storage.ErrNotFound)
+	}
+	if err := s.Logout(ctx, sessionID); err != nil {
+		return fmt.Errorf("users: logoutSession: %w", err)
+	}
+	return nil
+}
+
+// LogoutAll ends all sessions of the user.
+func (s Service) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	if err := s.storage.LogoutAll(ctx, userID); err != nil {
+		return fmt.Errorf("users: logoutAll: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package users
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanamelnik/gophkeeper/models"
+	"github.com/vanamelnik/gophkeeper/pkg/passhash"
+	"github.com/vanamelnik/gophkeeper/pkg/srp"
+	"github.com/vanamelnik/gophkeeper/server/storage"
+)
+
+const (
+	srpHandshakeDuration = 5 * time.Minute
+	// fakeSaltKeySize is the size of the random key of the fake SRP salts in bytes.
+	fakeSaltKeySize = 32
+)
+
+var ErrInvalidSRPVerifier = errors.New("invalid SRP salt or verifier")
+
+// CreateSRPUser stores the user with SRP-6a password verifier computed by the client and returns user ID.
+func (s Service) CreateSRPUser(ctx context.Context, email string, salt, verifier []byte) (uuid.UUID, error) {
+	if err := validateEmail(email); err != nil {
+		return uuid.Nil, fmt.Errorf("users: createSRPUser: %w", err)
+	}
+	user := models.User{Email: email}
+	if err := setVerifier(&user, salt, verifier); err != nil {
+		return uuid.Nil, fmt.Errorf("users: createSRPUser: %w", err)
+	}
+	user, err := s.storage.CreateUser(ctx, user)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("users: createSRPUser: %w", err)
+	}
+	s.sendVerificationEmailAsync(user.ID)
+
+	return user.ID, nil
+}
+
+// StartSRPLogin generates the server's ephemeral values for the client's public value provided
+// and stores the handshake. The handshake is finished either by FinishSRPLogin or by the operation
+// that requires the password confirmation. If undelete is set, the handshake is started for the deleted user
+// to be finished by UndeleteUser.
+// If there is no such user or the user has no verifier, the fake challenge is returned, so the registered
+// emails can't be told from the unknown ones: the handshake fails at the proof as with a wrong password.
+func (s Service) StartSRPLogin(ctx context.Context, email string, clientPublic []byte, undelete bool) (models.SRPChallenge, error) {
+	getUser := s.storage.GetUserByEmail
+	if undelete {
+		getUser = s.storage.GetDeletedUserByEmail
+	}
+	user, err := getUser(ctx, email)
+	if errors.Is(err, storage.ErrNotFound) || err == nil && len(user.SRPVerifier) == 0 {
+		challenge, err := s.fakeSRPChallenge(email)
+		if err != nil {
+			return models.SRPChallenge{}, fmt.Errorf("users: startSRPLogin: %w", err)
+		}
+		return challenge, nil
+	}
+	if err != nil {
+		return models.SRPChallenge{}, fmt.Errorf("users: startSRPLogin: %w", err)
+	}
+	server, err := srp.NewServer(user.Email, user.SRPSalt, user.SRPVerifier)
+	if err != nil {
+		return models.SRPChallenge{}, fmt.Errorf("users: startSRPLogin: %w", err)
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return models.SRPChallenge{}, fmt.Errorf("users: startSRPLogin: %w", err)
+	}
+	if err := s.storage.CreateSRPHandshake(ctx, models.SRPHandshake{
+		ID:           id,
+		UserID:       user.ID,
+		ClientPublic: clientPublic,
+		ServerSecret: server.Secret(),
+		ExpiresAt:    time.Now().Add(srpHandshakeDuration),
+	}); err != nil {
+		return models.SRPChallenge{}, fmt.Errorf("users: startSRPLogin: %w", err)
+	}
+
+	return models.SRPChallenge{
+		HandshakeID:  id,
+		Salt:         user.SRPSalt,
+		ServerPublic: server.PublicValue(),
+	}, nil
+}
+
+// fakeSRPChallenge returns the challenge for the email that has no verifier. The salt is derived from
+// the email by the server's secret, so it's the same for every handshake as the real one, and the server's
+// public value is computed for a random verifier. The handshake isn't stored.
+func (s Service) fakeSRPChallenge(email string) (models.SRPChallenge, error) {
+	mac := hmac.New(sha256.New, s.fakeSaltKey)
+	mac.Write([]byte(strings.ToLower(email)))
+	verifier := make([]byte, fakeSaltKeySize)
+	if _, err := rand.Read(verifier); err != nil {
+		return models.SRPChallenge{}, err
+	}
+	server, err := srp.NewServer(email, nil, verifier)
+	if err != nil {
+		return models.SRPChallenge{}, err
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return models.SRPChallenge{}, err
+	}
+	return models.SRPChallenge{
+		HandshakeID:  id,
+		Salt:         mac.Sum(nil)[:srp.SaltSize],
+		ServerPublic: server.PublicValue(),
+	}, nil
+}
+
+// FinishSRPLogin verifies the client's proof for the handshake started by StartSRPLogin.
+// If all is OK a new session is created and the server's proof is returned together with the tokens.
+// If the user has two-factor authentication enabled, the server's proof and ErrMFARequired return.
+func (s Service) FinishSRPLogin(ctx context.Context, email string, proof models.SRPProof,
+	client models.ClientInfo) ([]byte, models.AccessToken, models.RefreshToken, error) {
+	user, err := s.storage.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) { // the handshake is the fake one
+			return nil, "", "", fmt.Errorf("users: finishSRPLogin: %w", passhash.ErrMismatchedHashAndPassword)
+		}
+		return nil, "", "", fmt.Errorf("users: finishSRPLogin: %w", err)
+	}
+	serverProof, err := s.verifyPasswordProof(ctx, user, proof)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("users: finishSRPLogin: %w", err)
+	}
+	if user.TOTPEnabled {
+		challenge, err := s.newMFAChallenge(ctx, user.ID, false)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("users: finishSRPLogin: %w", err)
+		}
+		return serverProof, "", "", ErrMFARequired{Challenge: challenge}
+	}
+	accessToken, refreshToken, err := s.CreateSession(ctx, user.ID, client)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("users: finishSRPLogin: %w", err)
+	}
+
+	return serverProof, accessToken, refreshToken, nil
+}
+
+// ConfirmPassword checks the proof of the password of the user before a sensitive operation.
+// If the password doesn't match, passhash.ErrMismatchedHashAndPassword returns.
+func (s Service) ConfirmPassword(ctx context.Context, userID uuid.UUID, proof models.SRPProof) error {
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("users: confirmPassword: %w", err)
+	}
+	if _, err := s.verifyPasswordProof(ctx, user, proof); err != nil {
+		return fmt.Errorf("users: confirmPassword: %w", err)
+	}
+	return nil
+}
+
+// verifyPasswordProof finishes the handshake started by StartSRPLogin: it checks the client's proof
+// against the user's verifier and returns the server's proof. The handshake can be used only once.
+// If the password doesn't match, passhash.ErrMismatchedHashAndPassword returns. The same error returns
+// if the handshake is unknown, expired or started for another user, so the fake handshakes
+// (see StartSRPLogin) can't be told from the real ones.
+func (s Service) verifyPasswordProof(ctx context.Context, user models.User, proof models.SRPProof) ([]byte, error) {
+	if len(user.SRPVerifier) == 0 {
+		return nil, fmt.Errorf("%w: no verifier", passhash.ErrMismatchedHashAndPassword)
+	}
+	h, err := s.storage.TakeSRPHandshake(ctx, proof.HandshakeID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%w: unknown handshake", passhash.ErrMismatchedHashAndPassword)
+		}
+		return nil, err
+	}
+	if time.Now().After(h.ExpiresAt) {
+		return nil, fmt.Errorf("%w: handshake expired", passhash.ErrMismatchedHashAndPassword)
+	}
+	if h.UserID != user.ID {
+		return nil, fmt.Errorf("%w: handshake of another user", passhash.ErrMismatchedHashAndPassword)
+	}
+	server := srp.RestoreServer(user.Email, user.SRPSalt, user.SRPVerifier, h.ServerSecret)
+	serverProof, _, err := server.VerifyClientProof(h.ClientPublic, proof.ClientProof)
+	if err != nil {
+		if errors.Is(err, srp.ErrProofMismatch) {
+			return nil, passhash.ErrMismatchedHashAndPassword
+		}
+		return nil, err
+	}
+	return serverProof, nil
+}
+
+// setVerifier replaces the user's credentials with the SRP verifier computed by the client.
+func setVerifier(user *models.User, salt, verifier []byte) error {
+	if len(salt) == 0 || len(verifier) == 0 {
+		return ErrInvalidSRPVerifier
+	}
+	user.SRPSalt = salt
+	user.SRPVerifier = verifier
+	user.PasswordHash = ""
+	return nil
+}
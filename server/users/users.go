@@ -3,56 +3,133 @@ package users
 // Package users contains users service object.
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 	"github.com/vanamelnik/gophkeeper/models"
-	"github.com/vanamelnik/gophkeeper/pkg/bcrypt"
+	"github.com/vanamelnik/gophkeeper/pkg/passhash"
+	"github.com/vanamelnik/gophkeeper/server/keyset"
+	"github.com/vanamelnik/gophkeeper/server/mailer"
 	"github.com/vanamelnik/gophkeeper/server/storage"
 )
 
 var (
 	ErrAccessTokenExpired   = errors.New("access token expired")
 	ErrIncorrectAccessToken = errors.New("incorrect access token")
+	ErrSessionLoggedOut     = errors.New("the session is logged out")
 
 	ErrRefreshTokenExpired   = errors.New("refresh token expired")
 	ErrIncorrectRefreshToken = errors.New("incorrect refresh token")
+	ErrRefreshTokenReused    = errors.New("refresh token reuse detected, session revoked")
 
 	ErrIncorrectUserID = errors.New("incorrect user ID")
+
+	ErrGracePeriodExpired = errors.New("deletion grace period expired")
+)
+
+const (
+	defaultDeletionGracePeriod = 30 * 24 * time.Hour
+	defaultPurgeInterval       = time.Hour
 )
 
 // Service contains contains methods that provide operations with registered users and user sessions.
 type Service struct {
 	storage storage.Storage
-	// secret is the secret key for Access Token and Refresh Token signing.
-	secret               string
+	// keys is the set of keys for Access Token and Refresh Token signing and verifying.
+	keys                 *keyset.KeySet
 	accessTokenDuration  time.Duration
 	refreshTokenDuration time.Duration
+	// deletionGracePeriod is the time during which the deleted user can be restored.
+	deletionGracePeriod time.Duration
+	// revokeAllOnTokenReuse specifies whether all sessions of the user are ended
+	// when the reuse of a refresh token is detected.
+	revokeAllOnTokenReuse bool
+	// recoveryCodeHasher is used for hashing the recovery codes.
+	recoveryCodeHasher passhash.Hasher
+	// mailer sends email verification and password reset tokens.
+	mailer mailer.Mailer
+	// requireVerifiedEmail specifies whether the users with unverified email are allowed to sync the data.
+	requireVerifiedEmail bool
+	// fakeSaltKey is the secret the salts of the fake SRP challenges are derived with.
+	fakeSaltKey []byte
 }
 
-func NewService(storage storage.Storage, secret string, accessTokenDuration, refreshTokenDuration time.Duration) Service {
-	return Service{
+// Option is a functional option for the users service.
+type Option func(s *Service)
+
+func NewService(storage storage.Storage, keys *keyset.KeySet, accessTokenDuration, refreshTokenDuration time.Duration, opts ...Option) Service {
+	s := Service{
 		storage:              storage,
-		secret:               secret,
+		keys:                 keys,
 		accessTokenDuration:  accessTokenDuration,
 		refreshTokenDuration: refreshTokenDuration,
+		deletionGracePeriod:  defaultDeletionGracePeriod,
+		recoveryCodeHasher:   passhash.NewBcrypt(0),
+		mailer:               mailer.Log{},
+	}
+	for _, opt := range opts {
+		opt(&s)
 	}
+	if len(s.fakeSaltKey) == 0 {
+		s.fakeSaltKey = make([]byte, fakeSaltKeySize)
+		if _, err := rand.Read(s.fakeSaltKey); err != nil {
+			log.Printf("users: could not generate the key of the fake SRP salts: %s", err)
+		}
+	}
+	return s
 }
 
-// CreateUser stores user info in the database and returns user ID.
-func (s Service) CreateUser(ctx context.Context, email, pwHash string) (uuid.UUID, error) {
-	user, err := s.storage.CreateUser(ctx, email, pwHash)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("createUser: %w", err)
+// WithDeletionGracePeriod sets the time after which the data of the deleted user is permanently erased.
+func WithDeletionGracePeriod(d time.Duration) Option {
+	return func(s *Service) {
+		if d > 0 {
+			s.deletionGracePeriod = d
+		}
+	}
+}
+
+// WithRevokeAllOnTokenReuse makes the service end all sessions of the user (not only the
+// compromised one) when the reuse of a superseded refresh token is detected.
+func WithRevokeAllOnTokenReuse(revokeAll bool) Option {
+	return func(s *Service) {
+		s.revokeAllOnTokenReuse = revokeAll
+	}
+}
+
+// WithFakeSaltKey sets the secret the salts of the fake SRP challenges for the unknown emails
+// are derived with (see StartSRPLogin). If it's not set, a random key is generated, so the fake salts
+// change after the restart and the unknown emails can be told from the registered ones.
+func WithFakeSaltKey(key []byte) Option {
+	return func(s *Service) {
+		if len(key) > 0 {
+			s.fakeSaltKey = key
+		}
+	}
+}
+
+// WithMailer sets the mailer for email verification and password reset tokens.
+func WithMailer(m mailer.Mailer) Option {
+	return func(s *Service) {
+		if m != nil {
+			s.mailer = m
+		}
+	}
+}
+
+// WithRequireVerifiedEmail forbids the users with unverified email to sync the data.
+func WithRequireVerifiedEmail(require bool) Option {
+	return func(s *Service) {
+		s.requireVerifiedEmail = require
 	}
-	return user.ID, nil
 }
 
 // CreateSession creates a new user session and returns the access and refresh tokens.
-func (s Service) CreateSession(ctx context.Context, userID uuid.UUID) (models.AccessToken, models.RefreshToken, error) {
+func (s Service) CreateSession(ctx context.Context, userID uuid.UUID, client models.ClientInfo) (models.AccessToken, models.RefreshToken, error) {
 	sessionID, err := uuid.NewRandom()
 	if err != nil {
 		return "", "", fmt.Errorf("createSession: %w", err)
@@ -68,10 +145,11 @@ func (s Service) CreateSession(ctx context.Context, userID uuid.UUID) (models.Ac
 		RefreshToken: refreshToken,
 		LoginAt:      &now,
 		LogoutAt:     nil,
+		Client:       client,
 	}); err != nil {
 		return "", "", fmt.Errorf("createSession: %w", err)
 	}
-	accessToken, err := s.newAccessToken(userID)
+	accessToken, err := s.newAccessToken(userID, sessionID)
 	if err != nil {
 		return "", "", fmt.Errorf("createSession: %w", err)
 	}
@@ -81,12 +159,12 @@ func (s Service) CreateSession(ctx context.Context, userID uuid.UUID) (models.Ac
 
 // RefreshTheTokens checks whether the given refresh token is not expired. If the token is valid,
 // a new pair of tokens is generated and the new refresh token is stored in the db.
-func (s Service) RefreshTheTokens(ctx context.Context, refreshToken models.RefreshToken) (models.AccessToken, models.RefreshToken, error) {
-	t, err := jwt.ParseWithClaims(string(refreshToken), &jwt.StandardClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(s.secret), nil
-	})
+// If the token is signed by the service but is already superseded, it's considered stolen:
+// the session is revoked, the security event is recorded and ErrRefreshTokenReused returns.
+func (s Service) RefreshTheTokens(ctx context.Context, refreshToken models.RefreshToken, client models.ClientInfo) (models.AccessToken, models.RefreshToken, error) {
+	t, err := jwt.ParseWithClaims(string(refreshToken), &jwt.StandardClaims{}, s.keys.Keyfunc)
 	if err != nil {
-		var ve jwt.ValidationError
+		var ve *jwt.ValidationError
 		if errors.As(err, &ve) && ve.Errors&jwt.ValidationErrorExpired != 0 {
 			return "", "", fmt.Errorf("users: refreshTheTokens: %w", ErrRefreshTokenExpired)
 		}
@@ -106,10 +184,13 @@ func (s Service) RefreshTheTokens(ctx context.Context, refreshToken models.Refre
 		return "", "", fmt.Errorf("could not find session with id %s: %w", sessionID, err)
 	}
 	if session.RefreshToken != refreshToken {
-		return "", "", errors.New("internal error: refresh token is not correct")
+		if err := s.revokeCompromisedSession(ctx, session, client); err != nil {
+			return "", "", fmt.Errorf("users: refreshTheTokens: %w", err)
+		}
+		return "", "", fmt.Errorf("users: refreshTheTokens: %w", ErrRefreshTokenReused)
 	}
 
-	newAccessToken, err := s.newAccessToken(session.UserID)
+	newAccessToken, err := s.newAccessToken(session.UserID, sessionID)
 	if err != nil {
 		return "", "", err
 	}
@@ -127,17 +208,58 @@ func (s Service) RefreshTheTokens(ctx context.Context, refreshToken models.Refre
 	return newAccessToken, newRefreshToken, nil
 }
 
-// Login checks whether the user with the email provided exists and
-// the given credentials are valid. If all is OK a new session is created.
-func (s Service) Login(ctx context.Context, email, password string) (models.AccessToken, models.RefreshToken, error) {
-	user, err := s.storage.GetUserByEmail(ctx, email)
+// revokeCompromisedSession records the refresh token reuse event and ends the session
+// (or all sessions of the user if the service is configured so).
+func (s Service) revokeCompromisedSession(ctx context.Context, session models.Session, client models.ClientInfo) error {
+	if err := s.storage.CreateSecurityEvent(ctx, models.SecurityEvent{
+		UserID:     session.UserID,
+		SessionID:  session.ID,
+		Kind:       models.SecurityEventRefreshTokenReuse,
+		RemoteAddr: client.RemoteAddr,
+	}); err != nil {
+		return err
+	}
+	log.Printf("users: refresh token reuse detected: user %v, session %v, remote address %q",
+		session.UserID, session.ID, client.RemoteAddr)
+	if s.revokeAllOnTokenReuse {
+		return s.storage.LogoutAll(ctx, session.UserID)
+	}
+	return s.Logout(ctx, session.ID)
+}
+
+// ChangePassword checks the proof of the old password of the user, stores the SRP verifier of the new password
+// computed by the client and ends all active sessions of the user except the current one. The current session must be
+// taken from the verified access token; if it's uuid.Nil, all the sessions are ended.
+func (s Service) ChangePassword(ctx context.Context, userID, currentSessionID uuid.UUID, proof models.SRPProof, salt, verifier []byte) error {
+	user, err := s.storage.GetUserByID(ctx, userID)
 	if err != nil {
-		return "", "", err
+		return fmt.Errorf("users: changePassword: %w", err)
 	}
-	if err := bcrypt.CompareHashAndPassword(password, user.PasswordHash); err != nil {
-		return "", "", err
+	if _, err := s.verifyPasswordProof(ctx, user, proof); err != nil {
+		return fmt.Errorf("users: changePassword: %w", err)
 	}
-	return s.CreateSession(ctx, user.ID)
+	if err := setVerifier(&user, salt, verifier); err != nil {
+		return fmt.Errorf("users: changePassword: %w", err)
+	}
+	if err := s.storage.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("users: changePassword: %w", err)
+	}
+
+	// the old password could be compromised - end all other sessions of the user
+	sessions, err := s.storage.GetActiveUserSessions(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("users: changePassword: %w", err)
+	}
+	for _, session := range sessions {
+		if session.ID == currentSessionID {
+			continue
+		}
+		if err := s.Logout(ctx, session.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("users: changePassword: %w", err)
+		}
+	}
+
+	return nil
 }
 
 // Logout marks the user session in the db as logged out.